@@ -0,0 +1,301 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Command hb-shape shapes text using the harfbuzz package, and
+// prints the resulting glyphs.
+//
+// It mirrors the options and the output of the hb-shape tool of HarfBuzz,
+// so that outputs can be compared byte-for-byte:
+//
+//	hb-shape [OPTIONS] [FONT-FILE] [TEXT]
+//
+// Each line of the text is shaped separately.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/language"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "hb-shape:", err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	fontFile   string
+	faceIndex  int
+	fontSize   [2]int // 0 means upem
+	features   []harfbuzz.Feature
+	variations []font.Variation
+
+	props        harfbuzz.SegmentProperties
+	clusterLevel harfbuzz.ClusterLevel
+	bufferFlags  harfbuzz.ShappingOptions
+
+	text     string
+	unicodes string
+
+	format harfbuzz.SerializeFormat
+	flags  harfbuzz.SerializeFlags
+}
+
+func parseOptions(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("hb-shape", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.StringVar(&opts.fontFile, "font-file", "", "Set font file-name")
+	fs.IntVar(&opts.faceIndex, "face-index", 0, "Set face index (default: 0)")
+	fs.Func("font-size", "Font size (default: upem)", func(s string) error {
+		if s == "upem" {
+			opts.fontSize = [2]int{}
+			return nil
+		}
+		fields := strings.Fields(s)
+		if len(fields) == 0 || len(fields) > 2 {
+			return errors.New("font-size argument should be one or two space-separated numbers")
+		}
+		for i, field := range fields {
+			size, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return err
+			}
+			// as hb-shape (which uses no subpixel bits), the scale is truncated
+			opts.fontSize[i] = int(size)
+		}
+		if len(fields) == 1 {
+			opts.fontSize[1] = opts.fontSize[0]
+		}
+		return nil
+	})
+	fs.Func("features", "Comma-separated list of font features", func(s string) error {
+		for _, feature := range strings.Split(strings.Trim(s, `"`), ",") {
+			if feature = strings.TrimSpace(feature); feature == "" {
+				continue
+			}
+			f, err := harfbuzz.ParseFeature(feature)
+			if err != nil {
+				return err
+			}
+			opts.features = append(opts.features, f)
+		}
+		return nil
+	})
+	fs.Func("variations", "Comma-separated list of font variations", func(s string) error {
+		for _, variation := range strings.Split(strings.Trim(s, `"`), ",") {
+			if variation = strings.TrimSpace(variation); variation == "" {
+				continue
+			}
+			v, err := harfbuzz.ParseVariation(variation)
+			if err != nil {
+				return err
+			}
+			opts.variations = append(opts.variations, v)
+		}
+		return nil
+	})
+	fs.Func("script", "Set text script, as an ISO-15924 tag (default: auto)", func(s string) error {
+		var err error
+		opts.props.Script, err = language.ParseScript(s)
+		return err
+	})
+	fs.Func("language", "Set text language (default: $LANG)", func(s string) error {
+		opts.props.Language = language.NewLanguage(s)
+		return nil
+	})
+	fs.Func("direction", "Set text direction (default: auto)", func(s string) error {
+		if s == "" {
+			return errors.New("empty direction")
+		}
+		switch s[0] {
+		case 'l', 'L':
+			opts.props.Direction = harfbuzz.LeftToRight
+		case 'r', 'R':
+			opts.props.Direction = harfbuzz.RightToLeft
+		case 't', 'T':
+			opts.props.Direction = harfbuzz.TopToBottom
+		case 'b', 'B':
+			opts.props.Direction = harfbuzz.BottomToTop
+		default:
+			return fmt.Errorf("invalid direction %s", s)
+		}
+		return nil
+	})
+	fs.Func("cluster-level", "Cluster merging level (0/1/2, default: 0)", func(s string) error {
+		l, err := strconv.Atoi(s)
+		if err != nil || l < 0 || l > 2 {
+			return fmt.Errorf("invalid cluster-level option: %s", s)
+		}
+		opts.clusterLevel = harfbuzz.ClusterLevel(l)
+		return nil
+	})
+	bot := fs.Bool("bot", false, "Treat text as beginning-of-paragraph")
+	eot := fs.Bool("eot", false, "Treat text as end-of-paragraph")
+	unsafeToConcat := fs.Bool("unsafe-to-concat", false, "Produce unsafe-to-concat glyph flag")
+	safeToInsertTatweel := fs.Bool("safe-to-insert-tatweel", false, "Produce safe-to-insert-tatweel glyph flag")
+
+	fs.StringVar(&opts.text, "text", "", "Set input text")
+	fs.StringVar(&opts.unicodes, "unicodes", "", "Set input Unicode codepoints, as comma-separated hexadecimal numbers")
+
+	outputFormat := fs.String("output-format", "text", "Set output serialization format (text/json)")
+	noGlyphNames := fs.Bool("no-glyph-names", false, "Output glyph indices instead of names")
+	noPositions := fs.Bool("no-positions", false, "Do not output glyph positions")
+	noAdvances := fs.Bool("no-advances", false, "Do not output glyph advances")
+	noClusters := fs.Bool("no-clusters", false, "Do not output cluster indices")
+	showExtents := fs.Bool("show-extents", false, "Output glyph extents")
+	showFlags := fs.Bool("show-flags", false, "Output glyph flags")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+
+	// positional arguments: [FONT-FILE] [TEXT]
+	rest := fs.Args()
+	if opts.fontFile == "" && len(rest) != 0 {
+		opts.fontFile, rest = rest[0], rest[1:]
+	}
+	if opts.text == "" && opts.unicodes == "" && len(rest) != 0 {
+		opts.text, rest = rest[0], rest[1:]
+	}
+	if len(rest) != 0 {
+		return opts, fmt.Errorf("too many arguments: %v", rest)
+	}
+	if opts.fontFile == "" {
+		return opts, errors.New("no font file specified")
+	}
+
+	switch *outputFormat {
+	case "text":
+		opts.format = harfbuzz.SerializeFormatText
+	case "json":
+		opts.format = harfbuzz.SerializeFormatJSON
+	default:
+		return opts, fmt.Errorf("unsupported output format %s", *outputFormat)
+	}
+
+	for _, f := range [...]struct {
+		set  bool
+		flag harfbuzz.SerializeFlags
+	}{
+		{*noGlyphNames, harfbuzz.SerializeNoGlyphNames},
+		{*noPositions, harfbuzz.SerializeNoPositions},
+		{*noAdvances, harfbuzz.SerializeNoAdvances},
+		{*noClusters, harfbuzz.SerializeNoClusters},
+		{*showExtents, harfbuzz.SerializeGlyphExtents},
+		{*showFlags, harfbuzz.SerializeGlyphFlags},
+	} {
+		if f.set {
+			opts.flags |= f.flag
+		}
+	}
+
+	for _, f := range [...]struct {
+		set  bool
+		flag harfbuzz.ShappingOptions
+	}{
+		{*bot, harfbuzz.Bot},
+		{*eot, harfbuzz.Eot},
+		{*unsafeToConcat, harfbuzz.ProduceUnsafeToConcat},
+		{*safeToInsertTatweel, harfbuzz.ProduceSafeToInsertTatweel},
+	} {
+		if f.set {
+			opts.bufferFlags |= f.flag
+		}
+	}
+
+	return opts, nil
+}
+
+// parseUnicodes parses a list of codepoints like U+0041,U+0042 or 41 42
+func parseUnicodes(s string) ([]rune, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == ';' })
+	out := make([]rune, len(fields))
+	for i, field := range fields {
+		field = strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(field), "U+"), "0X")
+		r, err := strconv.ParseUint(field, 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unicode codepoint %s", fields[i])
+		}
+		out[i] = rune(r)
+	}
+	return out, nil
+}
+
+func loadFont(opts options) (*harfbuzz.Font, error) {
+	data, err := os.ReadFile(opts.fontFile)
+	if err != nil {
+		return nil, err
+	}
+	loaders, err := ot.NewLoaders(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if opts.faceIndex < 0 || opts.faceIndex >= len(loaders) {
+		return nil, fmt.Errorf("invalid face index %d (%d faces in file)", opts.faceIndex, len(loaders))
+	}
+	ft, err := font.NewFont(loaders[opts.faceIndex])
+	if err != nil {
+		return nil, err
+	}
+	face := font.NewFace(ft)
+	face.SetVariations(opts.variations)
+
+	out := harfbuzz.NewFont(face)
+	if opts.fontSize != [2]int{} {
+		out.XScale, out.YScale = int32(opts.fontSize[0]), int32(opts.fontSize[1])
+	}
+	return out, nil
+}
+
+func run(args []string, output, stderr io.Writer) error {
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		return err
+	}
+	ft, err := loadFont(opts)
+	if err != nil {
+		return err
+	}
+
+	var lines [][]rune
+	if opts.unicodes != "" {
+		text, err := parseUnicodes(opts.unicodes)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, text)
+	} else {
+		for _, line := range strings.Split(opts.text, "\n") {
+			lines = append(lines, []rune(line))
+		}
+	}
+
+	w := bufio.NewWriter(output)
+	buffer := harfbuzz.NewBuffer()
+	for _, line := range lines {
+		buffer.Clear()
+		buffer.AddRunes(line, 0, -1)
+		buffer.Props = opts.props
+		buffer.ClusterLevel = opts.clusterLevel
+		buffer.Flags = opts.bufferFlags
+		buffer.GuessSegmentProperties()
+		buffer.Shape(ft, opts.features)
+
+		fmt.Fprintln(w, buffer.Serialize(ft, opts.format, opts.flags))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+const robotoFile = "../../font/testdata/Roboto-Regular.ttf"
+
+func shape(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	err := run(args, &out, io.Discard)
+	tu.AssertNoErr(t, err)
+	return out.String()
+}
+
+func TestRun(t *testing.T) {
+	// this font has no glyph names
+	tu.Assert(t, shape(t, robotoFile, "ab") == "[gid69=0+1114|gid70=1+1149]\n")
+	tu.Assert(t, shape(t, "--font-file="+robotoFile, "--text=ab\nb") == "[gid69=0+1114|gid70=1+1149]\n[gid70=0+1149]\n")
+	tu.Assert(t, shape(t, "--no-positions", "--unicodes=U+0061,U+0062", robotoFile) == "[gid69=0|gid70=1]\n")
+	tu.Assert(t, shape(t, "--no-glyph-names", "--no-clusters", "--font-size=1024", robotoFile, "a") == "[69+557]\n")
+	// fractional sizes are truncated, as hb-shape does
+	tu.Assert(t, shape(t, "--no-glyph-names", "--no-clusters", "--font-size=100", robotoFile, "a") == "[69+54]\n")
+	tu.Assert(t, shape(t, "--no-glyph-names", "--no-clusters", "--font-size=100.5", robotoFile, "a") == "[69+54]\n")
+	tu.Assert(t, shape(t, "--no-glyph-names", "--no-clusters", "--font-size=100.9", robotoFile, "a") == "[69+54]\n")
+	// see the reference test in the harfbuzz package
+	tu.Assert(t, shape(t, "--direction=ttb", "--font-size=2000", robotoFile, "ab") == "[gid69=0@-544,-1700+0,-2343|gid70=1@-561,-1912+0,-2343]\n")
+	tu.Assert(t, shape(t, "--output-format=json", "--show-flags", "--show-extents", robotoFile, "a") ==
+		`[{"g":"gid69","cl":0,"dx":0,"dy":0,"ax":1114,"ay":0,"xb":109,"yb":1102,"w":893,"h":-1122}]`+"\n")
+
+	// features, script, language and cluster level
+	liga := shape(t, "--script=Latn", "--language=en", "--direction=ltr", "--cluster-level=1", robotoFile, "ffi")
+	noLiga := shape(t, "--features=-liga", robotoFile, "ffi")
+	tu.Assert(t, strings.Count(liga, "|") < strings.Count(noLiga, "|"))
+
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		{},
+		{"--font-file=notAFont.ttf", "a"},
+		{robotoFile, "a", "b"},
+		{"--face-index=2", robotoFile, "a"},
+		{"--direction=x", robotoFile, "a"},
+		{"--cluster-level=4", robotoFile, "a"},
+		{"--features=+", robotoFile, "a"},
+		{"--output-format=xml", robotoFile, "a"},
+		{"--unicodes=U+XX", robotoFile},
+		{"--font-size=a", robotoFile, "a"},
+	} {
+		tu.Assert(t, run(args, &out, io.Discard) != nil)
+	}
+}
@@ -1,31 +1,31 @@
 package font
 
-type glyphExtents struct {
-	valid   bool
-	extents GlyphExtents
-}
+import "sync/atomic"
 
-type extentsCache []glyphExtents
+// extentsCache is safe for concurrent use : each glyph
+// entry is atomically set once computed.
+type extentsCache []atomic.Pointer[GlyphExtents]
 
 func (ec extentsCache) get(gid GID) (GlyphExtents, bool) {
 	if int(gid) >= len(ec) {
 		return GlyphExtents{}, false
 	}
-	ge := ec[gid]
-	return ge.extents, ge.valid
+	if ge := ec[gid].Load(); ge != nil {
+		return *ge, true
+	}
+	return GlyphExtents{}, false
 }
 
 func (ec extentsCache) set(gid GID, extents GlyphExtents) {
 	if int(gid) >= len(ec) {
 		return
 	}
-	ec[gid].valid = true
-	ec[gid].extents = extents
+	ec[gid].Store(&extents)
 }
 
 func (ec extentsCache) reset() {
 	for i := range ec {
-		ec[i] = glyphExtents{}
+		ec[i].Store(nil)
 	}
 }
 
@@ -35,6 +35,7 @@ func (f *Face) GlyphExtents(glyph GID) (GlyphExtents, bool) {
 	}
 	e, ok := f.glyphExtentsRaw(glyph)
 	if ok {
+		f.applySynthesisExtents(&e)
 		f.extentsCache.set(glyph, e)
 	}
 	return e, ok
@@ -71,6 +71,15 @@ func (f *CFF) GlyphName(glyph opentype.GID) string {
 	return out
 }
 
+// GlyphCID returns the CID of the glyph, for CIDFonts (CID-keyed fonts).
+// For other fonts, or invalid glyphs, false is returned.
+func (f *CFF) GlyphCID(glyph opentype.GID) (uint16, bool) {
+	if f.fdSelect == nil || int(glyph) >= len(f.charset) {
+		return 0, false
+	}
+	return f.charset[glyph], true
+}
+
 // since SID = 0 means .notdef, we use a reserved value
 // to mean unset
 const unsetSID = uint16(0xFFFF)
@@ -1,6 +1,10 @@
 package font
 
-// Code generated by typesetting-utils/generators/cache/gen.go. DO NOT EDIT.
+import "sync/atomic"
+
+// This file was initially generated by typesetting-utils/generators/cache/gen.go,
+// and has since been edited by hand to support concurrent access :
+// it is not generated anymore.
 
 // cache21_19_8 implements a cache for integer pairs,
 // mapping a key in [0,1 << 21[ to a value in [0,1 << 19[
@@ -28,18 +32,20 @@ package font
 //
 // This cache works best with sparse keys (sharing the same high 13 bits),
 // since it handles 1 << 8 contiguous keys without collision.
+//
+// The array members are accessed atomically, so that the cache is safe for concurrent use.
 type cache21_19_8 [1 << 8]uint32
 
 // clear should be used as init function
 func (c *cache21_19_8) clear() {
 	for i := range c {
-		c[i] = ^uint32(0)
+		atomic.StoreUint32(&c[i], ^uint32(0))
 	}
 }
 
 func (c *cache21_19_8) get(key uint32) (uint32, bool) {
 	k := key & ((1 << 8) - 1)
-	v := c[k]
+	v := atomic.LoadUint32(&c[k])
 	if v == ^uint32(0) || (v>>19) != uint32(key>>8) {
 		return 0, false
 	}
@@ -57,7 +63,7 @@ func (c *cache21_19_8) set(key uint32, value uint32) {
 func (c *cache21_19_8) setUnchecked(key uint32, value uint32) {
 	k := key & ((1 << 8) - 1)
 	v := (uint32(key>>8) << 19) | value
-	c[k] = v
+	atomic.StoreUint32(&c[k], v)
 }
 
 // cache21_0_13 implements a cache for integer pairs,
@@ -86,18 +92,21 @@ func (c *cache21_19_8) setUnchecked(key uint32, value uint32) {
 //
 // This cache works best with sparse keys (sharing the same high 8 bits),
 // since it handles 1 << 13 contiguous keys without collision.
-type cache21_0_13 [1 << 13]uint8
+//
+// To be safe for concurrent use, the array members are packed by 4
+// in uint32 words, which are accessed atomically.
+type cache21_0_13 [1 << 13 / 4]uint32
 
 // clear should be used as init function
 func (c *cache21_0_13) clear() {
 	for i := range c {
-		c[i] = ^uint8(0)
+		atomic.StoreUint32(&c[i], ^uint32(0))
 	}
 }
 
 func (c *cache21_0_13) get(key uint32) bool {
 	k := key & ((1 << 13) - 1)
-	v := c[k]
+	v := uint8(atomic.LoadUint32(&c[k/4]) >> (8 * (k % 4)))
 	return v != ^uint8(0) && v == uint8(key>>13)
 }
 
@@ -111,6 +120,13 @@ func (c *cache21_0_13) set(key uint32) {
 // assumes key < 2097152
 func (c *cache21_0_13) setUnchecked(key uint32) {
 	k := key & ((1 << 13) - 1)
-	v := uint8(key >> 13)
-	c[k] = v
+	v := uint32(uint8(key >> 13))
+	shift := 8 * (k % 4)
+	word := &c[k/4]
+	for {
+		old := atomic.LoadUint32(word)
+		if atomic.CompareAndSwapUint32(word, old, old&^(0xFF<<shift)|v<<shift) {
+			return
+		}
+	}
 }
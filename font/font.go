@@ -181,6 +181,8 @@ type Font struct {
 	GDEF tables.GDEF // An absent table has a nil GlyphClassDef
 	Trak tables.Trak
 	Ankr tables.Ankr
+	Lcar tables.Lcar
+	Opbd tables.Opbd
 	Feat tables.Feat
 	Ltag tables.Ltag
 	Morx Morx
@@ -362,6 +364,12 @@ func NewFont(ld *ot.Loader) (*Font, error) {
 	raw, _ = ld.RawTable(ot.MustNewTag("ankr"))
 	out.Ankr, _, _ = tables.ParseAnkr(raw, out.nGlyphs)
 
+	raw, _ = ld.RawTable(ot.MustNewTag("lcar"))
+	out.Lcar, _, _ = tables.ParseLcar(raw, out.nGlyphs)
+
+	raw, _ = ld.RawTable(ot.MustNewTag("opbd"))
+	out.Opbd, _, _ = tables.ParseOpbd(raw, out.nGlyphs)
+
 	raw, _ = ld.RawTable(ot.MustNewTag("trak"))
 	out.Trak, _, _ = tables.ParseTrak(raw)
 
@@ -613,9 +621,12 @@ func loadGDEF(ld *ot.Loader, axisCount int, gsub, gpos []byte) (tables.GDEF, err
 }
 
 // Face is a font with user-provided settings.
-// Contrary to the [*Font] objects, Faces are NOT safe for concurrent use.
 // A Face caches glyph extents and rune to glyph mapping, and should be reused when possible.
 //
+// The caches are safe for concurrent use, so that the query methods of a Face may be called
+// from several goroutines. However, the settings (coordinates, ppem, synthesis) must not
+// be modified concurrently with other method calls.
+//
 // Also note that an empty [Face] is invalid : the [NewFace] constructor is required to properly init caches.
 type Face struct {
 	*Font
@@ -626,6 +637,8 @@ type Face struct {
 
 	coords       []tables.Coord
 	xPpem, yPpem uint16
+
+	synthesis Synthesis
 }
 
 // NewFace wraps [font] and initializes glyph caches.
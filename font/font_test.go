@@ -4,6 +4,7 @@ package font
 
 import (
 	"bytes"
+	"sync"
 	"testing"
 
 	hb "github.com/go-text/typesetting-utils/harfbuzz"
@@ -52,7 +53,11 @@ func TestGlyphName(t *testing.T) {
 	}
 	for i, exp := range expected {
 		tu.Assert(t, ft.GlyphName(GID(i)) == exp)
+		gid, ok := ft.GlyphFromName(exp)
+		tu.Assert(t, ok && gid == GID(i))
 	}
+	_, ok := ft.GlyphFromName("unknown")
+	tu.Assert(t, !ok)
 }
 
 func BenchmarkLoad(b *testing.B) {
@@ -213,3 +218,60 @@ func BenchmarkCmap(b *testing.B) {
 		}
 	})
 }
+
+// This test is meant to be run with the race detector enabled :
+// go test -race -run Concurrent
+func TestConcurrentFace(t *testing.T) {
+	font := loadFont(t, "common/Roboto-BoldItalic.ttf")
+	text := []rune("Hi this is a test with some âccents : $£8 襄陽曲四首/魯中都東樓醉起作-李白 刊误")
+
+	// reference values, without caching
+	type result struct {
+		gid     GID
+		ok      bool
+		extents GlyphExtents
+	}
+	expected := make([]result, len(text))
+	for i, r := range text {
+		gid, ok := font.Cmap.Lookup(r)
+		extents, _ := NewFace(font).GlyphExtents(gid)
+		expected[i] = result{gid, ok, extents}
+	}
+
+	face := NewFace(font)
+	var wg sync.WaitGroup
+	for j := 0; j < 8; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 10; k++ {
+				for i, r := range text {
+					gid, ok := face.NominalGlyph(r)
+					extents, _ := face.GlyphExtents(gid)
+					if (result{gid, ok, extents}) != expected[i] {
+						t.Errorf("unexpected result for rune %c", r)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCmapNotSupportedCache(t *testing.T) {
+	var c cache21_0_13
+	c.clear()
+	keys := []uint32{0, 1, 2, 3, 4, 1<<13 + 1, 0x10FFFF}
+	for _, key := range keys {
+		tu.Assert(t, !c.get(key))
+	}
+	for _, key := range keys[:4] {
+		c.set(key)
+	}
+	for i, key := range keys {
+		tu.Assert(t, c.get(key) == (i < 4))
+	}
+	// collision with key 1
+	c.set(1<<13 + 1)
+	tu.Assert(t, !c.get(1) && c.get(1<<13+1) && c.get(0) && c.get(2))
+}
@@ -30,6 +30,21 @@ func (f *Font) GlyphName(glyph GID) string {
 	return ""
 }
 
+// GlyphFromName returns the glyph with the given name, as returned
+// by [Font.GlyphName].
+// It performs a linear search and should not be used in performance critical code.
+func (f *Font) GlyphFromName(name string) (GID, bool) {
+	if name == "" {
+		return 0, false
+	}
+	for gid := 0; gid < f.nGlyphs; gid++ {
+		if f.GlyphName(GID(gid)) == name {
+			return GID(gid), true
+		}
+	}
+	return 0, false
+}
+
 // Upem returns the units per em of the font file.
 // This value is only relevant for scalable fonts.
 func (f *Font) Upem() uint16 { return f.upem }
@@ -233,7 +248,13 @@ func (f *Face) getGlyphAdvanceVar(gid gID, isVertical bool) float32 {
 	return clamp(phantoms[phantomRight].X - phantoms[phantomLeft].X)
 }
 
+// HorizontalAdvance returns the horizontal advance in font units,
+// taking into account the [Synthesis] settings.
 func (f *Face) HorizontalAdvance(gid GID) float32 {
+	return f.applySynthesisAdvance(f.horizontalAdvanceRaw(gid))
+}
+
+func (f *Face) horizontalAdvanceRaw(gid GID) float32 {
 	advance := f.getBaseAdvance(gID(gid), f.hmtx, false)
 	if !f.isVar() {
 		return float32(advance)
@@ -254,7 +275,14 @@ func (f *Face) isVar() bool {
 // defaut value.
 func (f *Font) HasVerticalMetrics() bool { return !f.vmtx.IsEmpty() }
 
+// VerticalAdvance returns the vertical advance in font units,
+// taking into account the [Synthesis] settings.
+// Note that the returned value is negative.
 func (f *Face) VerticalAdvance(gid GID) float32 {
+	return f.applySynthesisAdvance(f.verticalAdvanceRaw(gid))
+}
+
+func (f *Face) verticalAdvanceRaw(gid GID) float32 {
 	// return the opposite of the advance from the font
 	advance := f.getBaseAdvance(gID(gid), f.vmtx, true)
 	if !f.isVar() {
@@ -417,3 +417,45 @@ func TestParseLtag(t *testing.T) {
 	tu.Assert(t, len(ltag.tagRange) == 1)
 	tu.Assert(t, ltag.Language(0) == "pl")
 }
+
+func TestParseLcar(t *testing.T) {
+	src := deHexStr(
+		"0001 0000 0000 " + // version, format
+			"0008 0005 0002 0010 0016 " + // lookup: glyph 5 -> 0x10, glyph 6 -> 0x16
+			"0002 0064 00C8 " + // 2 carets : 100, 200
+			"0001 FF9C", // 1 caret : -100
+	)
+	lcar, _, err := ParseLcar(src, 10)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, lcar.Format == 0)
+	tu.Assert(t, reflect.DeepEqual(lcar.Carets(5), []int16{100, 200}))
+	tu.Assert(t, reflect.DeepEqual(lcar.Carets(6), []int16{-100}))
+	tu.Assert(t, lcar.Carets(4) == nil)
+	tu.Assert(t, Lcar{}.Carets(4) == nil)
+
+	_, _, err = ParseLcar(src[:4], 10)
+	tu.Assert(t, err != nil)
+}
+
+func TestParseOpbd(t *testing.T) {
+	src := deHexStr(
+		"0001 0000 0000 " + // version, format
+			"0008 0005 0002 0010 0018 " + // lookup: glyph 5 -> 0x10, glyph 6 -> 0x18
+			"0032 0000 FFCE 0000 " + // left 50, right -50
+			"0000 0000 FFEC 0000", // right -20
+	)
+	opbd, _, err := ParseOpbd(src, 10)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, opbd.Format == 0)
+	bounds, ok := opbd.Bounds(5)
+	tu.Assert(t, ok && bounds == OpticalBounds{Left: 50, Right: -50})
+	bounds, ok = opbd.Bounds(6)
+	tu.Assert(t, ok && bounds == OpticalBounds{Right: -20})
+	_, ok = opbd.Bounds(4)
+	tu.Assert(t, !ok)
+	_, ok = Opbd{}.Bounds(4)
+	tu.Assert(t, !ok)
+
+	_, _, err = ParseOpbd(src[:4], 10)
+	tu.Assert(t, err != nil)
+}
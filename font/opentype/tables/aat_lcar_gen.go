@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package tables
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Code generated by binarygen from aat_lcar_src.go. DO NOT EDIT
+
+func ParseLcar(src []byte, valuesCount int) (Lcar, int, error) {
+	var item Lcar
+	n := 0
+	if L := len(src); L < 6 {
+		return item, 0, fmt.Errorf("reading Lcar: "+"EOF: expected length: 6, got %d", L)
+	}
+	_ = src[5] // early bound checking
+	item.version = binary.BigEndian.Uint32(src[0:])
+	item.Format = binary.BigEndian.Uint16(src[4:])
+	n += 6
+
+	{
+		var (
+			err  error
+			read int
+		)
+		item.lookup, read, err = ParseAATLookup(src[6:], valuesCount)
+		if err != nil {
+			return item, 0, fmt.Errorf("reading Lcar: %s", err)
+		}
+		n += read
+	}
+	{
+
+		item.rawData = src[0:]
+		n = len(src)
+	}
+	return item, n, nil
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package tables
+
+import "encoding/binary"
+
+// Lcar is the ligature caret table
+// See https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6lcar.html
+type Lcar struct {
+	version uint32 // Version number of the ligature caret table (0x00010000 for the initial version).
+	// Format of the caret values : 0 means the values are distances, 1 means
+	// that they are control point indices.
+	Format uint16
+	// The lookup table returns uint16 offset from the beginning of the table, to the caret values.
+	lookup  AATLookup
+	rawData []byte `subsliceStart:"AtStart" arrayCount:"ToEnd"`
+}
+
+// Carets returns the caret values defined for `glyph`, or nil if not found.
+// See [Lcar.Format] for the interpretation of the values.
+func (lc Lcar) Carets(glyph GlyphID) []int16 {
+	if lc.lookup == nil {
+		return nil
+	}
+	offset, ok := lc.lookup.Class(glyph)
+	if !ok || int(offset)+2 > len(lc.rawData) {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(lc.rawData[offset:]))
+	start := int(offset) + 2
+	if len(lc.rawData) < start+2*count {
+		return nil // invalid table
+	}
+	out := make([]int16, count)
+	for i := range out {
+		out[i] = int16(binary.BigEndian.Uint16(lc.rawData[start+2*i:]))
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package tables
+
+import "encoding/binary"
+
+// Opbd is the optical bounds table
+// See https://developer.apple.com/fonts/TrueType-Reference-Manual/RM06/Chap6opbd.html
+type Opbd struct {
+	version uint32 // Version number of the optical bounds table (0x00010000 for the initial version).
+	// Format of the bounds : 0 means the values are distances, 1 means
+	// that they are control point indices.
+	Format uint16
+	// The lookup table returns uint16 offset from the beginning of the table, to the bounds values.
+	lookup  AATLookup
+	rawData []byte `subsliceStart:"AtStart" arrayCount:"ToEnd"`
+}
+
+// OpticalBounds is an entry of the 'opbd' table, whose values
+// are either distances (in font units) or control point indices.
+type OpticalBounds struct {
+	Left, Top, Right, Bottom int16
+}
+
+// Bounds returns the optical bounds defined for `glyph`, or false if not found.
+// See [Opbd.Format] for the interpretation of the values.
+func (op Opbd) Bounds(glyph GlyphID) (OpticalBounds, bool) {
+	if op.lookup == nil {
+		return OpticalBounds{}, false
+	}
+	offset, ok := op.lookup.Class(glyph)
+	if !ok || int(offset)+8 > len(op.rawData) {
+		return OpticalBounds{}, false
+	}
+	data := op.rawData[offset:]
+	return OpticalBounds{
+		Left:   int16(binary.BigEndian.Uint16(data)),
+		Top:    int16(binary.BigEndian.Uint16(data[2:])),
+		Right:  int16(binary.BigEndian.Uint16(data[4:])),
+		Bottom: int16(binary.BigEndian.Uint16(data[6:])),
+	}, true
+}
@@ -21,6 +21,10 @@ type Post struct {
 	Names        PostNames `unionField:"version"`
 }
 
+// ItalicAngle returns the italic angle in counter-clockwise degrees from the vertical.
+// Zero is for upright text, negative for text that leans to the right (forward).
+func (ps *Post) ItalicAngle() float32 { return float32(int32(ps.italicAngle)) / (1 << 16) }
+
 type PostNames interface {
 	isPostNames()
 }
@@ -425,8 +425,17 @@ func (f *Face) GlyphData(gid GID) GlyphData {
 //
 // It is a bit faster than calling [Face.GlyphData] and may be used for instance
 // when rendering colored glyphs (from the 'COLR' table).
+//
+// The [Synthesis] settings of the face are applied to the outline.
 func (f *Face) GlyphDataOutline(gid GID) (GlyphOutline, bool) {
-	g := gID(gid)
+	out, ok := f.glyphDataOutlineRaw(gID(gid))
+	if ok {
+		f.applySynthesisOutline(out.Segments)
+	}
+	return out, ok
+}
+
+func (f *Face) glyphDataOutlineRaw(g gID) (GlyphOutline, bool) {
 	out, err := f.glyphDataFromCFF1(g)
 	if err == nil {
 		return out, true
@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package font
+
+import (
+	"math"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// Synthesis stores the settings used to simulate bold or oblique
+// styles, for instance when a font family lacks a bold or italic face.
+//
+// When set on a [Face], the synthesis is applied consistently to the glyph
+// outlines (see [Face.GlyphDataOutline]), the glyph extents and the advances.
+// Bitmap and SVG glyphs are not modified.
+//
+// The zero value means no synthesis.
+type Synthesis struct {
+	// Embolden is the synthetic boldness, expressed as a ratio of the em size.
+	// Positive values make a font bolder, negative values thinner.
+	// Typical values are in the 0.01 to 0.05 range.
+	//
+	// Synthetic boldness is applied by offsetting the contour
+	// points of the glyph shape, and the glyph advances are also enlarged.
+	Embolden float32
+
+	// Slant is the synthetic slant, the graphical skew applied to the glyphs.
+	// It is a ratio : for example, a 20% slant would be represented as a 0.2 value.
+	Slant float32
+}
+
+// Synthesis returns the synthetic settings of the face.
+func (f *Face) Synthesis() Synthesis { return f.synthesis }
+
+// SetSynthesis applies synthetic settings to the face.
+func (f *Face) SetSynthesis(s Synthesis) {
+	f.synthesis = s
+	// invalid the cache
+	f.extentsCache.reset()
+}
+
+// emboldenStrength returns the strength, in font units
+func (f *Face) emboldenStrength() float32 {
+	return f.synthesis.Embolden * float32(f.Upem())
+}
+
+// applySynthesisOutline modifies [segments] in place
+func (f *Face) applySynthesisOutline(segments []Segment) {
+	if strength := f.emboldenStrength(); strength != 0 {
+		// as in harfbuzz, the emboldening is not done in place: the glyph
+		// is shifted so that the left side bearing is preserved
+		emboldenOutline(segments, strength, strength, strength/2, strength/2)
+	}
+	if slant := f.synthesis.Slant; slant != 0 {
+		for i := range segments {
+			args := segments[i].ArgsSlice()
+			for j := range args {
+				args[j].X += args[j].Y * slant
+			}
+		}
+	}
+}
+
+// applySynthesisExtents is the counterpart of [applySynthesisOutline]
+// for extents
+func (f *Face) applySynthesisExtents(extents *GlyphExtents) {
+	if slant := f.synthesis.Slant; slant != 0 {
+		x1 := extents.XBearing
+		y1 := extents.YBearing
+		x2 := extents.XBearing + extents.Width
+		y2 := extents.YBearing + extents.Height
+
+		x1 += min32(y1*slant, y2*slant)
+		x2 += max32(y1*slant, y2*slant)
+
+		extents.XBearing = x1
+		extents.Width = x2 - extents.XBearing
+	}
+
+	if strength := f.emboldenStrength(); strength != 0 {
+		extents.YBearing += strength
+		extents.Height -= strength
+		extents.Width += strength
+	}
+}
+
+// applySynthesisAdvance enlarges the (non empty) advance, either
+// horizontal or vertical
+func (f *Face) applySynthesisAdvance(advance float32) float32 {
+	if advance == 0 {
+		return 0
+	}
+	strength := f.emboldenStrength()
+	if advance < 0 { // vertical advances are negative
+		return advance - strength
+	}
+	return advance + strength
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// controlArea returns the signed area of the polygon
+// formed by the contour points
+func controlArea(points []*SegmentPoint, contourEnds []int) float32 {
+	var a float32
+	first := 0
+	for _, end := range contourEnds {
+		for i := first; i < end; i++ {
+			j := i + 1
+			if j >= end {
+				j = first
+			}
+			pi, pj := points[i], points[j]
+			a += pi.X*pj.Y - pi.Y*pj.X
+		}
+		first = end
+	}
+	return a * .5
+}
+
+type vector struct{ x, y float32 }
+
+// normalize scales the vector to unit length,
+// returning its original length
+func (v *vector) normalize() float32 {
+	l := float32(math.Hypot(float64(v.x), float64(v.y)))
+	if l != 0 {
+		v.x /= l
+		v.y /= l
+	}
+	return l
+}
+
+// emboldenOutline offsets the contours of [segments], preserving their direction.
+// The off-curve points are processed as the on-curve ones.
+//
+// It is a port of harfbuzz hb_outline_t::embolden, itself adapted from
+// FreeType FT_Outline_EmboldenXY.
+func emboldenOutline(segments []Segment, xStrength, yStrength, xShift, yShift float32) {
+	// collect the points
+	var (
+		points      []*SegmentPoint
+		contourEnds []int // exclusive
+	)
+	for i := range segments {
+		seg := &segments[i]
+		if seg.Op == ot.SegmentOpMoveTo && len(points) != 0 {
+			contourEnds = append(contourEnds, len(points))
+		}
+		for j := range seg.ArgsSlice() {
+			points = append(points, &seg.Args[j])
+		}
+	}
+	if len(points) == 0 {
+		return
+	}
+	contourEnds = append(contourEnds, len(points))
+
+	xStrength /= 2
+	yStrength /= 2
+
+	orientationNegative := controlArea(points, contourEnds) < 0
+
+	first := 0
+	for _, end := range contourEnds {
+		var (
+			in, out, anchor, shift vector
+			lIn, lOut, lAnchor     float32
+		)
+		last := end - 1
+
+		// Counter j cycles though the points; counter i advances only
+		// when points are moved; anchor k marks the first moved point.
+		for i, j, k := last, first, -1; j != i && i != k; {
+			if j != k {
+				out = vector{points[j].X - points[i].X, points[j].Y - points[i].Y}
+				lOut = out.normalize()
+
+				if lOut == 0 {
+					j = nextIndex(j, first, last)
+					continue
+				}
+			} else {
+				out = anchor
+				lOut = lAnchor
+			}
+
+			if lIn != 0 {
+				if k < 0 {
+					k = i
+					anchor = in
+					lAnchor = lIn
+				}
+
+				d := in.x*out.x + in.y*out.y
+
+				// shift only if turn is less than ~160 degrees
+				if d > -15./16. {
+					d = d + 1
+
+					// shift components along lateral bisector in proper orientation
+					shift.x = in.y + out.y
+					shift.y = in.x + out.x
+
+					if orientationNegative {
+						shift.x = -shift.x
+					} else {
+						shift.y = -shift.y
+					}
+
+					// restrict shift magnitude to better handle collapsing segments
+					q := out.x*in.y - out.y*in.x
+					if orientationNegative {
+						q = -q
+					}
+
+					l := min32(lIn, lOut)
+
+					// non-strict inequalities avoid divide-by-zero when q == l == 0
+					if xStrength*q <= l*d {
+						shift.x = shift.x * xStrength / d
+					} else {
+						shift.x = shift.x * l / q
+					}
+
+					if yStrength*q <= l*d {
+						shift.y = shift.y * yStrength / d
+					} else {
+						shift.y = shift.y * l / q
+					}
+				} else {
+					shift = vector{}
+				}
+
+				for ; i != j; i = nextIndex(i, first, last) {
+					points[i].X += xShift + shift.x
+					points[i].Y += yShift + shift.y
+				}
+			} else {
+				i = j
+			}
+
+			in = out
+			lIn = lOut
+
+			j = nextIndex(j, first, last)
+		}
+
+		first = end
+	}
+}
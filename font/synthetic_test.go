@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package font
+
+import (
+	"reflect"
+	"testing"
+
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func TestEmboldenOutline(t *testing.T) {
+	square := func() []Segment {
+		return []Segment{
+			moveTo(0, 0),
+			lineTo(100, 0),
+			lineTo(100, 100),
+			lineTo(0, 100),
+			lineTo(0, 0),
+		}
+	}
+
+	// counter-clockwise contour
+	segments := square()
+	emboldenOutline(segments, 10, 10, 5, 5)
+	tu.Assert(t, reflect.DeepEqual(segments, []Segment{
+		moveTo(0, 0),
+		lineTo(110, 0),
+		lineTo(110, 110),
+		lineTo(0, 110),
+		lineTo(0, 0),
+	}))
+
+	// clockwise contour : the orientation is preserved
+	segments = square()
+	segments[1], segments[3] = segments[3], segments[1]
+	emboldenOutline(segments, 10, 10, 5, 5)
+	tu.Assert(t, reflect.DeepEqual(segments, []Segment{
+		moveTo(0, 0),
+		lineTo(0, 110),
+		lineTo(110, 110),
+		lineTo(110, 0),
+		lineTo(0, 0),
+	}))
+
+	// negative strength
+	segments = square()
+	emboldenOutline(segments, -10, -10, -5, -5)
+	tu.Assert(t, reflect.DeepEqual(segments, []Segment{
+		moveTo(0, 0),
+		lineTo(90, 0),
+		lineTo(90, 90),
+		lineTo(0, 90),
+		lineTo(0, 0),
+	}))
+
+	// empty outline
+	emboldenOutline(nil, 10, 10, 5, 5)
+}
+
+func outlineBounds(segments []Segment) (minX, minY, maxX, maxY float32) {
+	minX, minY, maxX, maxY = segments[0].Args[0].X, segments[0].Args[0].Y, segments[0].Args[0].X, segments[0].Args[0].Y
+	for i := range segments {
+		for _, p := range segments[i].ArgsSlice() {
+			minX, minY = min32(minX, p.X), min32(minY, p.Y)
+			maxX, maxY = max32(maxX, p.X), max32(maxY, p.Y)
+		}
+	}
+	return
+}
+
+func TestSynthesis(t *testing.T) {
+	ft := loadFont(t, "common/DejaVuSans.ttf")
+	face := NewFace(ft)
+	gid, _ := face.NominalGlyph('H')
+
+	advance := face.HorizontalAdvance(gid)
+	extents, _ := face.GlyphExtents(gid)
+	outline, _ := face.GlyphDataOutline(gid)
+
+	face.SetSynthesis(Synthesis{Embolden: 0.02})
+	strength := 0.02 * float32(face.Upem())
+	tu.Assert(t, face.HorizontalAdvance(gid) == advance+strength)
+	boldExtents, _ := face.GlyphExtents(gid)
+	tu.Assert(t, boldExtents.XBearing == extents.XBearing)
+	tu.Assert(t, boldExtents.Width == extents.Width+strength)
+	tu.Assert(t, boldExtents.YBearing == extents.YBearing+strength)
+	tu.Assert(t, boldExtents.Height == extents.Height-strength)
+	// the extents match the modified outline
+	boldOutline, _ := face.GlyphDataOutline(gid)
+	tu.Assert(t, len(boldOutline.Segments) == len(outline.Segments))
+	minX, minY, maxX, maxY := outlineBounds(boldOutline.Segments)
+	tu.Assert(t, absf(minX-boldExtents.XBearing) < 1 && absf(maxY-boldExtents.YBearing) < 1)
+	tu.Assert(t, absf(maxX-minX-boldExtents.Width) < 1 && absf(minY-maxY-boldExtents.Height) < 1)
+
+	face.SetSynthesis(Synthesis{Slant: 0.2})
+	tu.Assert(t, face.HorizontalAdvance(gid) == advance)
+	slantedOutline, _ := face.GlyphDataOutline(gid)
+	for i, seg := range slantedOutline.Segments {
+		exp := outline.Segments[i].Args[0]
+		tu.Assert(t, seg.Args[0].X == exp.X+0.2*exp.Y && seg.Args[0].Y == exp.Y)
+	}
+	slantedExtents, _ := face.GlyphExtents(gid)
+	minX, _, maxX, _ = outlineBounds(slantedOutline.Segments)
+	tu.Assert(t, absf(minX-slantedExtents.XBearing) < 1 && absf(maxX-minX-slantedExtents.Width) < 1)
+
+	// empty glyphs are not modified
+	face.SetSynthesis(Synthesis{Embolden: 0.02})
+	space, _ := face.NominalGlyph(' ')
+	spaceOutline, _ := face.GlyphDataOutline(space)
+	tu.Assert(t, len(spaceOutline.Segments) == 0)
+}
+
+func absf(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -520,6 +520,34 @@ func (face *Face) SetVariations(variations []Variation) {
 	face.SetCoords(face.NormalizeVariations(designCoords))
 }
 
+// VariationCoords returns the normalized coordinates obtained by applying
+// [variations] on top of the current coordinates of the face, without modifying it.
+// Contrary to [SetVariations], the axes not specified in [variations]
+// keep their current value.
+//
+// It returns nil if the font is not variable.
+func (face *Face) VariationCoords(variations []Variation) []VarCoord {
+	fv := face.Font.fvar
+	if len(fv) == 0 { // the font is not variable...
+		return nil
+	}
+
+	out := make([]VarCoord, len(fv))
+	copy(out, face.coords)
+
+	// normalization is done axis by axis, so that the
+	// default values have no effect on the specified axes
+	normalized := face.NormalizeVariations(fv.getDesignCoordsDefault(variations))
+	for _, variation := range variations {
+		for index, axis := range fv {
+			if axis.Tag == variation.Tag {
+				out[index] = normalized[index]
+			}
+		}
+	}
+	return out
+}
+
 // getDesignCoordsDefault returns the design coordinates corresponding to the given pairs of axis/value.
 // The default value of the axis is used when not specified in the variations.
 func (fv fvar) getDesignCoordsDefault(variations []Variation) []float32 {
@@ -148,6 +148,31 @@ func TestAdvanceHVar(t *testing.T) {
 	}
 }
 
+func TestVariationCoords(t *testing.T) {
+	font := loadFont(t, "toys/Var1.ttf")
+	wght, opsz := ot.MustNewTag("wght"), ot.MustNewTag("opsz")
+
+	face := NewFace(font)
+	tu.Assert(t, len(face.VariationCoords(nil)) == len(font.fvar))
+	tu.Assert(t, reflect.DeepEqual(face.VariationCoords(nil), make([]VarCoord, len(font.fvar))))
+
+	face.SetVariations([]Variation{{wght, 200}})
+	ref := append([]VarCoord(nil), face.Coords()...)
+	tu.Assert(t, ref[0] != 0)
+
+	coords := face.VariationCoords([]Variation{{opsz, 72}})
+	tu.Assert(t, reflect.DeepEqual(face.Coords(), ref)) // not modified
+	tu.Assert(t, coords[0] == ref[0] && coords[2] == 1<<14)
+
+	// same as SetVariations when all the axes are specified
+	other := NewFace(font)
+	other.SetVariations([]Variation{{wght, 200}, {opsz, 72}})
+	tu.Assert(t, reflect.DeepEqual(other.Coords(), coords))
+
+	// non variable fonts
+	tu.Assert(t, NewFace(loadFont(t, "common/DejaVuSans.ttf")).VariationCoords([]Variation{{wght, 200}}) == nil)
+}
+
 func TestAdvanceNoHVar(t *testing.T) {
 	font := loadFont(t, "toys/GVAR-no-HVAR.ttf")
 
@@ -92,6 +92,19 @@ type Buffer struct {
 	// Precise the cluster handling behavior.
 	ClusterLevel ClusterLevel
 
+	// Trace, if not nil, is called at each step of the shaping process,
+	// and may be used to debug fonts. See [TraceFunc] for more details.
+	// It is not reset by [Buffer.Clear].
+	Trace TraceFunc
+	// set when Trace returns false
+	traceAborted bool
+
+	// PlanCache is used by [Buffer.Shape] to store the shaping plans.
+	// [NewBuffer] sets a cache private to the buffer, but
+	// a cache may be shared between buffers, including in different goroutines.
+	// It is not reset by [Buffer.Clear].
+	PlanCache *ShapePlanCache
+
 	// some pathological cases can be constructed
 	// (for example with GSUB tables), where the size of the buffer
 	// grows out of bounds
@@ -106,7 +119,8 @@ type Buffer struct {
 
 	haveOutput bool
 
-	planCache map[Face][]*shapePlan
+	// storage used when applying GSUB and GPOS lookups
+	applyContext otApplyContext
 
 	digest setDigest
 }
@@ -117,7 +131,7 @@ func NewBuffer() *Buffer {
 	return &Buffer{
 		ClusterLevel:              MonotoneGraphemes,
 		maxOps:                    maxOpsDefault,
-		planCache:                 map[Face][]*shapePlan{},
+		PlanCache:                 new(ShapePlanCache),
 		notFoundVariationSelector: 0xFFFFFFFF,
 	}
 }
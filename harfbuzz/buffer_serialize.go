@@ -0,0 +1,336 @@
+package harfbuzz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* ported from harfbuzz/src/hb-buffer-serialize.cc
+ * Copyright © 2012,2013  Google, Inc.
+ * Google Author(s): Behdad Esfahbod */
+
+// SerializeFormat is the format used by [Buffer.Serialize] and [Buffer.Deserialize].
+type SerializeFormat uint8
+
+const (
+	// SerializeFormatText is the compact text format used by hb-shape,
+	// for instance [a=0+500|b=1@10,0+600]
+	SerializeFormatText SerializeFormat = iota
+	// SerializeFormatJSON is the JSON format used by hb-shape, for instance
+	// [{"g":"a","cl":0,"dx":0,"dy":0,"ax":500,"ay":0}]
+	SerializeFormatJSON
+)
+
+// SerializeFlags controls the content of the serialized buffer.
+type SerializeFlags uint16
+
+const (
+	// SerializeNoClusters does not output the cluster values
+	SerializeNoClusters SerializeFlags = 1 << iota
+	// SerializeNoPositions does not output the positions
+	SerializeNoPositions
+	// SerializeNoGlyphNames outputs glyph indices instead of names
+	SerializeNoGlyphNames
+	// SerializeGlyphExtents outputs the glyph extents
+	SerializeGlyphExtents
+	// SerializeGlyphFlags outputs the glyph flags (see [GlyphUnsafeToBreak] for instance)
+	SerializeGlyphFlags
+	// SerializeNoAdvances does not output the advances, and
+	// adds them to the offsets of the following glyphs instead
+	SerializeNoAdvances
+)
+
+// glyphToString returns the glyph name, or gidDDD if the glyph has no name.
+func (f *Font) glyphToString(glyph GID) string {
+	if name := f.funcs.GlyphName(glyph); name != "" {
+		return name
+	}
+	return fmt.Sprintf("gid%d", glyph)
+}
+
+// glyphFromString is the reverse of [glyphToString], also accepting
+// glyph indices
+func (f *Font) glyphFromString(s string) (GID, bool) {
+	if gid, ok := f.funcs.GlyphFromName(s); ok {
+		return gid, true
+	}
+	s = strings.TrimPrefix(s, "gid")
+	if gid, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return GID(gid), true
+	}
+	return 0, false
+}
+
+// Serialize returns a representation of the glyphs in the buffer, using the
+// same format as HarfBuzz, so that outputs of hb-shape may be compared.
+// [font] is used to retrieve glyph names and extents.
+//
+// As in HarfBuzz, an empty buffer is serialized as an empty string.
+// Positions are omitted if they are not available, which happens
+// when serializing an intermediate state from a [TraceFunc].
+func (b *Buffer) Serialize(font *Font, format SerializeFormat, flags SerializeFlags) string {
+	if len(b.Info) == 0 {
+		return ""
+	}
+	if len(b.Pos) < len(b.Info) {
+		flags |= SerializeNoPositions
+	}
+	var sb strings.Builder
+	if format == SerializeFormatJSON {
+		b.serializeJSON(&sb, font, flags)
+	} else {
+		b.serializeText(&sb, font, flags)
+	}
+	return sb.String()
+}
+
+func (b *Buffer) serializeText(sb *strings.Builder, font *Font, flags SerializeFlags) {
+	sb.WriteByte('[')
+	var x, y Position
+	for i, glyph := range b.Info {
+		if i != 0 {
+			sb.WriteByte('|')
+		}
+
+		if flags&SerializeNoGlyphNames != 0 {
+			fmt.Fprintf(sb, "%d", glyph.Glyph)
+		} else {
+			sb.WriteString(font.glyphToString(glyph.Glyph))
+		}
+
+		if flags&SerializeNoClusters == 0 {
+			fmt.Fprintf(sb, "=%d", glyph.Cluster)
+		}
+
+		var pos GlyphPosition
+		if flags&SerializeNoPositions == 0 {
+			pos = b.Pos[i]
+			if x+pos.XOffset != 0 || y+pos.YOffset != 0 {
+				fmt.Fprintf(sb, "@%d,%d", x+pos.XOffset, y+pos.YOffset)
+			}
+			if flags&SerializeNoAdvances == 0 {
+				fmt.Fprintf(sb, "+%d", pos.XAdvance)
+				if pos.YAdvance != 0 {
+					fmt.Fprintf(sb, ",%d", pos.YAdvance)
+				}
+			}
+		}
+
+		if flags&SerializeGlyphFlags != 0 {
+			if mask := glyph.Mask & glyphFlagDefined; mask != 0 {
+				fmt.Fprintf(sb, "#%X", mask)
+			}
+		}
+
+		if flags&SerializeGlyphExtents != 0 {
+			extents, _ := font.GlyphExtents(glyph.Glyph)
+			fmt.Fprintf(sb, "<%d,%d,%d,%d>", extents.XBearing, extents.YBearing, extents.Width, extents.Height)
+		}
+
+		if flags&SerializeNoAdvances != 0 {
+			x += pos.XAdvance
+			y += pos.YAdvance
+		}
+	}
+	sb.WriteByte(']')
+}
+
+func (b *Buffer) serializeJSON(sb *strings.Builder, font *Font, flags SerializeFlags) {
+	var x, y Position
+	for i, glyph := range b.Info {
+		if i == 0 {
+			sb.WriteByte('[')
+		} else {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`{"g":`)
+		if flags&SerializeNoGlyphNames != 0 {
+			fmt.Fprintf(sb, "%d", glyph.Glyph)
+		} else {
+			sb.WriteByte('"')
+			for _, c := range font.glyphToString(glyph.Glyph) {
+				if c == '"' || c == '\\' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteRune(c)
+			}
+			sb.WriteByte('"')
+		}
+
+		if flags&SerializeNoClusters == 0 {
+			fmt.Fprintf(sb, `,"cl":%d`, glyph.Cluster)
+		}
+
+		var pos GlyphPosition
+		if flags&SerializeNoPositions == 0 {
+			pos = b.Pos[i]
+			fmt.Fprintf(sb, `,"dx":%d,"dy":%d`, x+pos.XOffset, y+pos.YOffset)
+			if flags&SerializeNoAdvances == 0 {
+				fmt.Fprintf(sb, `,"ax":%d,"ay":%d`, pos.XAdvance, pos.YAdvance)
+			}
+		}
+
+		if flags&SerializeGlyphFlags != 0 {
+			if mask := glyph.Mask & glyphFlagDefined; mask != 0 {
+				fmt.Fprintf(sb, `,"fl":%d`, mask)
+			}
+		}
+
+		if flags&SerializeGlyphExtents != 0 {
+			extents, _ := font.GlyphExtents(glyph.Glyph)
+			fmt.Fprintf(sb, `,"xb":%d,"yb":%d,"w":%d,"h":%d`, extents.XBearing, extents.YBearing, extents.Width, extents.Height)
+		}
+		sb.WriteByte('}')
+
+		if flags&SerializeNoAdvances != 0 {
+			x += pos.XAdvance
+			y += pos.YAdvance
+		}
+	}
+	sb.WriteByte(']')
+}
+
+// Deserialize parses glyphs serialized by [Buffer.Serialize] (or hb-shape),
+// and appends them to the buffer.
+// Glyph names are resolved using [font], and glyph extents are ignored.
+//
+// Note that when the advances have been omitted, the positions of the glyphs
+// are stored as offsets.
+func (b *Buffer) Deserialize(s string, font *Font, format SerializeFormat) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if format == SerializeFormatJSON {
+		return b.deserializeJSON(s, font)
+	}
+	return b.deserializeText(s, font)
+}
+
+func (b *Buffer) deserializeText(s string, font *Font) error {
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return errors.New("invalid serialized buffer: missing brackets")
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		return nil
+	}
+	for _, item := range strings.Split(s, "|") {
+		info, pos, err := parseTextGlyph(item, font)
+		if err != nil {
+			return fmt.Errorf("invalid serialized glyph %q: %s", item, err)
+		}
+		b.Info = append(b.Info, info)
+		b.Pos = append(b.Pos, pos)
+	}
+	return nil
+}
+
+// parseTextGlyph parses name=cluster@dx,dy+ax,ay#flags<xb,yb,w,h>,
+// where all parts except the name are optional
+func parseTextGlyph(s string, font *Font) (info GlyphInfo, pos GlyphPosition, err error) {
+	// split on the delimiters
+	end := strings.IndexAny(s, "=@+#<")
+	if end == -1 {
+		end = len(s)
+	}
+	name := s[:end]
+	var ok bool
+	info.Glyph, ok = font.glyphFromString(name)
+	if !ok {
+		return info, pos, fmt.Errorf("unknown glyph %q", name)
+	}
+
+	for s = s[end:]; s != ""; {
+		delim := s[0]
+		end = strings.IndexAny(s[1:], "=@+#<")
+		if end == -1 {
+			end = len(s)
+		} else {
+			end++
+		}
+		value := s[1:end]
+		s = s[end:]
+
+		switch delim {
+		case '=':
+			info.Cluster, err = strconv.Atoi(value)
+		case '@':
+			err = parsePair(value, &pos.XOffset, &pos.YOffset, true)
+		case '+':
+			err = parsePair(value, &pos.XAdvance, &pos.YAdvance, false)
+		case '#':
+			var mask uint64
+			mask, err = strconv.ParseUint(value, 16, 32)
+			info.Mask = GlyphMask(mask) & glyphFlagDefined
+		case '<':
+			if !strings.HasSuffix(value, ">") {
+				err = errors.New("invalid extents")
+			}
+		}
+		if err != nil {
+			return info, pos, err
+		}
+	}
+	return info, pos, nil
+}
+
+// parsePair parses "x,y", or "x" if [requireY] is false
+func parsePair(s string, x, y *Position, requireY bool) error {
+	xs, ys, hasY := strings.Cut(s, ",")
+	if requireY && !hasY {
+		return errors.New("missing y coordinate")
+	}
+	v, err := strconv.ParseInt(xs, 10, 32)
+	if err != nil {
+		return err
+	}
+	*x = Position(v)
+	if hasY {
+		v, err = strconv.ParseInt(ys, 10, 32)
+		if err != nil {
+			return err
+		}
+		*y = Position(v)
+	}
+	return nil
+}
+
+type jsonGlyph struct {
+	G  json.RawMessage `json:"g"`
+	Cl int             `json:"cl"`
+	Dx Position        `json:"dx"`
+	Dy Position        `json:"dy"`
+	Ax Position        `json:"ax"`
+	Ay Position        `json:"ay"`
+	Fl GlyphMask       `json:"fl"`
+}
+
+func (b *Buffer) deserializeJSON(s string, font *Font) error {
+	var glyphs []jsonGlyph
+	if err := json.Unmarshal([]byte(s), &glyphs); err != nil {
+		return fmt.Errorf("invalid serialized buffer: %s", err)
+	}
+	for _, g := range glyphs {
+		var (
+			name string
+			gid  GID
+		)
+		if err := json.Unmarshal(g.G, &gid); err != nil { // glyph name
+			if err = json.Unmarshal(g.G, &name); err != nil {
+				return fmt.Errorf("invalid serialized buffer: %s", err)
+			}
+			var ok bool
+			gid, ok = font.glyphFromString(name)
+			if !ok {
+				return fmt.Errorf("invalid serialized buffer: unknown glyph %q", name)
+			}
+		}
+		b.Info = append(b.Info, GlyphInfo{Glyph: gid, Cluster: g.Cl, Mask: g.Fl & glyphFlagDefined})
+		b.Pos = append(b.Pos, GlyphPosition{XOffset: g.Dx, YOffset: g.Dy, XAdvance: g.Ax, YAdvance: g.Ay})
+	}
+	return nil
+}
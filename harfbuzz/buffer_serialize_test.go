@@ -0,0 +1,103 @@
+package harfbuzz
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func TestSerialize(t *testing.T) {
+	ft := NewFont(font.NewFace(openFontFileTT(t, "common/DejaVuSans.ttf")))
+	buffer := NewBuffer()
+	buffer.Info = []GlyphInfo{{Glyph: 43, Cluster: 0}, {Glyph: 72, Cluster: 1, Mask: GlyphUnsafeToBreak | GlyphUnsafeToConcat}}
+	buffer.Pos = []GlyphPosition{{XAdvance: 1543}, {XAdvance: 1260, XOffset: 10, YOffset: -20, YAdvance: 5}}
+
+	for _, test := range []struct {
+		flags      SerializeFlags
+		text, json string
+	}{
+		{
+			0,
+			"[H=0+1543|e=1@10,-20+1260,5]",
+			`[{"g":"H","cl":0,"dx":0,"dy":0,"ax":1543,"ay":0},{"g":"e","cl":1,"dx":10,"dy":-20,"ax":1260,"ay":5}]`,
+		},
+		{
+			SerializeNoGlyphNames | SerializeNoClusters,
+			"[43+1543|72@10,-20+1260,5]",
+			`[{"g":43,"dx":0,"dy":0,"ax":1543,"ay":0},{"g":72,"dx":10,"dy":-20,"ax":1260,"ay":5}]`,
+		},
+		{
+			SerializeNoPositions | SerializeGlyphFlags,
+			"[H=0|e=1#3]",
+			`[{"g":"H","cl":0},{"g":"e","cl":1,"fl":3}]`,
+		},
+		{
+			SerializeNoAdvances,
+			"[H=0|e=1@1553,-20]",
+			`[{"g":"H","cl":0,"dx":0,"dy":0},{"g":"e","cl":1,"dx":1553,"dy":-20}]`,
+		},
+		{
+			SerializeGlyphExtents | SerializeNoPositions,
+			"[H=0<201,1493,1138,-1493>|e=1<113,1147,1038,-1176>]",
+			`[{"g":"H","cl":0,"xb":201,"yb":1493,"w":1138,"h":-1493},{"g":"e","cl":1,"xb":113,"yb":1147,"w":1038,"h":-1176}]`,
+		},
+	} {
+		tu.AssertC(t, buffer.Serialize(ft, SerializeFormatText, test.flags) == test.text, buffer.Serialize(ft, SerializeFormatText, test.flags))
+		tu.AssertC(t, buffer.Serialize(ft, SerializeFormatJSON, test.flags) == test.json, buffer.Serialize(ft, SerializeFormatJSON, test.flags))
+	}
+
+	tu.Assert(t, NewBuffer().Serialize(ft, SerializeFormatText, 0) == "")
+	tu.Assert(t, NewBuffer().Serialize(ft, SerializeFormatJSON, 0) == "")
+}
+
+func TestDeserialize(t *testing.T) {
+	ft := NewFont(font.NewFace(openFontFileTT(t, "common/DejaVuSans.ttf")))
+	buffer := NewBuffer()
+	buffer.AddRunes([]rune("Hello office"), 0, -1)
+	buffer.Props = SegmentProperties{Direction: LeftToRight, Script: language.Latin}
+	buffer.Flags = ProduceUnsafeToConcat
+	buffer.Shape(ft, nil)
+
+	const flags = SerializeGlyphFlags
+	for _, format := range []SerializeFormat{SerializeFormatText, SerializeFormatJSON} {
+		s := buffer.Serialize(ft, format, flags)
+
+		parsed := NewBuffer()
+		err := parsed.Deserialize(s, ft, format)
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, len(parsed.Info) == len(buffer.Info))
+		for i, info := range buffer.Info {
+			got := parsed.Info[i]
+			tu.Assert(t, got.Glyph == info.Glyph && got.Cluster == info.Cluster && got.Mask == info.Mask&glyphFlagDefined)
+			tu.Assert(t, parsed.Pos[i] == buffer.Pos[i])
+		}
+		tu.Assert(t, parsed.Serialize(ft, format, flags) == s)
+	}
+
+	// glyph indices and gidDDD names
+	parsed := NewBuffer()
+	err := parsed.Deserialize("[43=0+1543|gid72=1<113,1147,1038,-1176>|space]", ft, SerializeFormatText)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, reflect.DeepEqual(parsed.Pos, []GlyphPosition{{XAdvance: 1543}, {}, {}}))
+	tu.Assert(t, parsed.Info[0].Glyph == 43 && parsed.Info[1].Glyph == 72 && parsed.Info[1].Cluster == 1)
+	tu.Assert(t, ft.glyphToString(parsed.Info[2].Glyph) == "space")
+
+	for _, invalid := range []string{
+		"H=0",
+		"[H=a]",
+		"[H@1]",
+		"[H+1,a]",
+		"[unknownGlyph]",
+		"[H<1,2]",
+	} {
+		err = NewBuffer().Deserialize(invalid, ft, SerializeFormatText)
+		tu.Assert(t, err != nil)
+	}
+	err = NewBuffer().Deserialize(`[{"g":"unknownGlyph"}]`, ft, SerializeFormatJSON)
+	tu.Assert(t, err != nil)
+	err = NewBuffer().Deserialize(`[{"g":true}]`, ft, SerializeFormatJSON)
+	tu.Assert(t, err != nil)
+}
@@ -0,0 +1,119 @@
+package harfbuzz
+
+import (
+	"fmt"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+var (
+	tagGSUB = ot.NewTag('G', 'S', 'U', 'B')
+	tagGPOS = ot.NewTag('G', 'P', 'O', 'S')
+)
+
+// TraceStep identifies a step of the shaping process, see [TraceEvent].
+type TraceStep uint8
+
+const (
+	// TracePreprocessText is the text preprocessing done by
+	// the script specific shapers (for instance for Hangul or Thai), before any substitution
+	TracePreprocessText TraceStep = iota
+	// TraceNormalize is the Unicode normalization, which maps
+	// the runes to glyphs
+	TraceNormalize
+	// TraceTable is the application of a layout table, as given by [TraceEvent.Table]:
+	// GSUB, GPOS, or the AAT 'morx' and 'kerx' tables.
+	TraceTable
+	// TraceStage is one stage of a GSUB or GPOS table, grouping the lookups of
+	// features applied together. [TraceEvent.Index] is the stage index.
+	TraceStage
+	// TraceLookup is the application of one GSUB or GPOS lookup, whose index
+	// is [TraceEvent.Index].
+	TraceLookup
+	// TracePause is a hook of the script specific shapers, run at the end of a stage
+	// (for instance the Indic reordering). [TraceEvent.Index] is the stage index.
+	TracePause
+	// TracePostprocessGlyphs is the glyph post-processing done
+	// by the script specific shapers, after positioning
+	TracePostprocessGlyphs
+)
+
+// TraceEvent is reported to a [TraceFunc], at the start and
+// the end of each step of the shaping process.
+type TraceEvent struct {
+	// Table is the table being applied, for
+	// [TraceTable], [TraceStage], [TraceLookup] and [TracePause]
+	Table ot.Tag
+	// Feature is the feature the lookup belongs to, for [TraceLookup]
+	Feature ot.Tag
+	// Index is the stage index for [TraceStage] and [TracePause],
+	// and the lookup index for [TraceLookup]
+	Index int
+
+	Step TraceStep
+	// Start is true at the start of the step, false at its end.
+	Start bool
+}
+
+// String returns a description similar to the messages of HarfBuzz.
+func (ev TraceEvent) String() string {
+	prefix := "end"
+	if ev.Start {
+		prefix = "start"
+	}
+	switch ev.Step {
+	case TracePreprocessText:
+		return prefix + " preprocess-text"
+	case TraceNormalize:
+		return prefix + " normalize"
+	case TraceTable:
+		return fmt.Sprintf("%s table %s", prefix, ev.Table)
+	case TraceStage:
+		return fmt.Sprintf("%s stage %d of table %s", prefix, ev.Index, ev.Table)
+	case TraceLookup:
+		return fmt.Sprintf("%s lookup %d feature '%s'", prefix, ev.Index, ev.Feature)
+	case TracePause:
+		return fmt.Sprintf("%s pause after stage %d of table %s", prefix, ev.Index, ev.Table)
+	case TracePostprocessGlyphs:
+		return prefix + " postprocess-glyphs"
+	default:
+		return prefix + " unknown step"
+	}
+}
+
+// TraceFunc is called during shaping, at the start and end of each step, which
+// makes it possible to inspect the content of [buffer] (for instance with [Buffer.Serialize]),
+// and understand how the font is applied.
+//
+// Returning false aborts the shaping : no more steps are applied, and
+// the buffer is left in its intermediate state.
+//
+// Note that before the [TraceNormalize] step, the buffer contains runes and no glyphs, and that
+// the positions are only valid after the start of the GPOS table.
+type TraceFunc func(buffer *Buffer, font *Font, event TraceEvent) bool
+
+// trace reports the event to the user callback, if any,
+// and returns false if the shaping has been aborted.
+func (b *Buffer) trace(font *Font, event TraceEvent) bool {
+	if b.Trace == nil {
+		return true
+	}
+	if !b.traceAborted && !b.Trace(b, font, event) {
+		b.traceAborted = true
+	}
+	return !b.traceAborted
+}
+
+// traceStep is a convenience function reporting the start and end of a step
+func (b *Buffer) traceStep(font *Font, event TraceEvent, step func()) {
+	event.Start = true
+	if !b.trace(font, event) {
+		return
+	}
+	step()
+	event.Start = false
+	b.trace(font, event)
+}
+
+// aborted returns true if the user callback has aborted the shaping
+func (b *Buffer) aborted() bool { return b.traceAborted }
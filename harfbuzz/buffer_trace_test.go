@@ -0,0 +1,100 @@
+package harfbuzz
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+type tracedEvent struct {
+	event  TraceEvent
+	buffer string
+}
+
+func shapeTraced(ft *Font, text string, props SegmentProperties, trace func(TraceEvent) bool) (*Buffer, []tracedEvent) {
+	var events []tracedEvent
+	buffer := NewBuffer()
+	buffer.AddRunes([]rune(text), 0, -1)
+	buffer.Props = props
+	buffer.Trace = func(b *Buffer, f *Font, ev TraceEvent) bool {
+		events = append(events, tracedEvent{ev, b.Serialize(f, SerializeFormatText, SerializeNoGlyphNames)})
+		return trace(ev)
+	}
+	buffer.Shape(ft, nil)
+	return buffer, events
+}
+
+func TestTrace(t *testing.T) {
+	ft := NewFont(font.NewFace(openFontFileTT(t, "common/NotoSansArabic.ttf")))
+	props := SegmentProperties{Direction: RightToLeft, Script: language.Arabic, Language: language.NewLanguage("ar")}
+	const text = "لا بسم"
+
+	buffer, events := shapeTraced(ft, text, props, func(TraceEvent) bool { return true })
+
+	// the trace does not change the result
+	ref := NewBuffer()
+	ref.AddRunes([]rune(text), 0, -1)
+	ref.Props = props
+	ref.Shape(ft, nil)
+	tu.Assert(t, buffer.Serialize(ft, SerializeFormatText, 0) == ref.Serialize(ft, SerializeFormatText, 0))
+
+	// events are balanced
+	var (
+		stack         []TraceEvent
+		steps         = map[TraceStep]int{}
+		lookups       = map[string]int{}
+		changedLookup bool
+	)
+	for i, ev := range events {
+		steps[ev.event.Step]++
+		if ev.event.Start {
+			stack = append(stack, ev.event)
+			continue
+		}
+		tu.Assert(t, len(stack) != 0)
+		start := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		start.Start = false
+		tu.AssertC(t, start == ev.event, ev.event.String())
+
+		if ev.event.Step == TraceLookup {
+			lookups[ev.event.Table.String()]++
+			// lookups are reported with the buffer before and after
+			tu.Assert(t, events[i-1].event.Step == TraceLookup && events[i-1].event.Start)
+			if events[i-1].buffer != ev.buffer {
+				changedLookup = true
+			}
+		}
+	}
+	tu.Assert(t, len(stack) == 0)
+	tu.Assert(t, changedLookup)
+	tu.Assert(t, lookups["GSUB"] > 0 && lookups["GPOS"] > 0)
+	for _, step := range []TraceStep{TracePreprocessText, TraceNormalize, TraceTable, TraceStage, TraceLookup, TracePause, TracePostprocessGlyphs} {
+		tu.AssertC(t, steps[step] > 0, TraceEvent{Step: step}.String())
+	}
+	tu.Assert(t, events[0].event.String() == "start preprocess-text")
+	tu.Assert(t, events[len(events)-1].event.String() == "end postprocess-glyphs")
+	tu.Assert(t, TraceEvent{Step: TraceLookup, Start: true, Index: 3, Feature: tagGSUB}.String() == "start lookup 3 feature 'GSUB'")
+	tu.Assert(t, TraceEvent{Step: TraceTable, Table: tagGPOS}.String() == "end table GPOS")
+
+	// abort at the first GPOS lookup
+	buffer, aborted := shapeTraced(ft, text, props, func(ev TraceEvent) bool {
+		return !(ev.Step == TraceLookup && ev.Table == tagGPOS)
+	})
+	last := aborted[len(aborted)-1].event
+	tu.Assert(t, last.Step == TraceLookup && last.Start && last.Table == tagGPOS)
+	tu.Assert(t, len(aborted) < len(events))
+	// the buffer is left in its intermediate state
+	tu.Assert(t, buffer.Serialize(ft, SerializeFormatText, SerializeNoGlyphNames) == aborted[len(aborted)-1].buffer)
+	tu.Assert(t, buffer.Props.Direction == RightToLeft)
+
+	// a new shaping is not affected by a previous abort
+	buffer.Trace = func(*Buffer, *Font, TraceEvent) bool { return true }
+	buffer.Clear()
+	buffer.AddRunes([]rune(text), 0, -1)
+	buffer.Props = props
+	buffer.Shape(ft, nil)
+	tu.Assert(t, buffer.Serialize(ft, SerializeFormatText, 0) == ref.Serialize(ft, SerializeFormatText, 0))
+}
@@ -0,0 +1,65 @@
+package harfbuzz
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+)
+
+// These tests are meant to be run with the race detector enabled :
+// go test -race -run Concurrent
+
+func shapeAll(ft *Font, buffer *Buffer, texts []string) []string {
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		buffer.Clear()
+		buffer.AddRunes([]rune(text), 0, -1)
+		buffer.GuessSegmentProperties()
+		buffer.Shape(ft, nil)
+		out[i] = buffer.Serialize(ft, SerializeFormatText, SerializeGlyphFlags|SerializeGlyphExtents)
+	}
+	return out
+}
+
+func TestConcurrentFont(t *testing.T) {
+	texts := []string{
+		"Hello office, AVAWAY", "لا بسم الله", "مرحبا بالعالم", "ကြွေးကြော်", "ہمارا پیار", "日本語", "ffi ABC",
+	}
+	for _, fnt := range []*font.Font{
+		openFontFile(t, "fonts/NotoSansMyanmar-Regular.ttf"),
+		openFontFile(t, "fonts/NotoNastaliqUrdu-Regular.ttf"),
+		openFontFile(t, "fonts/aat-morx.ttf"),
+		openFontFile(t, "fonts/aat-trak.ttf"),
+		openFontFile(t, "harfbuzz_reference/in-house/fonts/MORXTwentyeight.ttf"),
+		openFontFile(t, "fonts/SourceSansVariable-Roman.anchor.ttf"),
+		openFontFileTT(t, "common/DejaVuSans.ttf"),
+		openFontFileTT(t, "common/NotoSansArabic.ttf"),
+	} {
+		// the font, its face and the plans are shared
+		ft := NewFont(font.NewFace(fnt))
+		expected := shapeAll(ft, NewBuffer(), texts)
+
+		var (
+			wg    sync.WaitGroup
+			cache ShapePlanCache
+		)
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				buffer := NewBuffer()
+				buffer.PlanCache = &cache
+				for k := 0; k < 5; k++ {
+					got := shapeAll(ft, buffer, texts)
+					for i := range got {
+						if got[i] != expected[i] {
+							t.Errorf("unexpected output %s, expected %s", got[i], expected[i])
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -9,14 +9,90 @@ import (
 
 type Face = *font.Face
 
+// FontFuncs provides the glyph level information used during shaping :
+// glyph mapping, metrics, origins, extents, contour points and names.
+//
+// All the values are expressed in font units (see [FontFuncs.Upem]), and are
+// scaled by the [Font].
+//
+// [*font.Face] implements FontFuncs, and is used by default by [NewFont].
+// Custom implementations may be used to shape with glyphs which are not
+// provided by an OpenType font (for instance a bitmap or a runtime generated font),
+// or to override some of the values of a face, for instance with hinted advances.
+// The easiest way to override a single callback is to embed the parent
+// FontFuncs in a struct :
+//
+//	type hintedFuncs struct{ harfbuzz.FontFuncs }
+//
+//	func (hf hintedFuncs) HorizontalAdvance(gid font.GID) float32 {
+//		return round(hf.FontFuncs.HorizontalAdvance(gid))
+//	}
+//
+// and to use it with [Font.WithFuncs] or [NewFontWithFuncs].
+type FontFuncs interface {
+	// Upem returns the units per em of the font, used
+	// to interpret all the other values.
+	Upem() uint16
+
+	// NominalGlyph returns the glyph used to represent the given rune,
+	// or false if not found.
+	NominalGlyph(ch rune) (GID, bool)
+	// VariationGlyph retrieves the glyph ID for a specified Unicode code point
+	// followed by a specified Variation Selector code point, or false if not found
+	VariationGlyph(ch, varSelector rune) (GID, bool)
+
+	// HorizontalAdvance returns the horizontal advance of the glyph.
+	HorizontalAdvance(gid GID) float32
+	// HasVerticalMetrics returns true if [VerticalAdvance] and [GlyphVOrigin]
+	// should be used. If false, the vertical advance is deduced from
+	// the font extents.
+	HasVerticalMetrics() bool
+	// VerticalAdvance returns the vertical advance of the glyph,
+	// which is usually negative.
+	VerticalAdvance(gid GID) float32
+
+	// GlyphHOrigin returns the horizontal origin of the glyph, or false
+	// if it should be deduced from the vertical origin.
+	GlyphHOrigin(gid GID) (x, y int32, found bool)
+	// GlyphVOrigin returns the vertical origin of the glyph.
+	GlyphVOrigin(gid GID) (x, y float32)
+
+	// GlyphExtents returns the extents of the glyph, or false
+	// if not available.
+	GlyphExtents(gid GID) (font.GlyphExtents, bool)
+	// GetGlyphContourPoint returns the coordinates of the given point,
+	// used by some GPOS anchors.
+	GetGlyphContourPoint(gid GID, pointIndex uint16) (x, y int32, ok bool)
+
+	// GlyphName returns the name of the glyph, or an empty string.
+	GlyphName(gid GID) string
+	// GlyphFromName is the reverse of [GlyphName].
+	GlyphFromName(name string) (GID, bool)
+
+	// FontHExtents returns the extents used for horizontal text, or false
+	// if they are not available.
+	FontHExtents() (font.FontExtents, bool)
+	// FontVExtents returns the extents used for vertical text, or false
+	// if they are not available.
+	FontVExtents() (font.FontExtents, bool)
+}
+
+var _ FontFuncs = (*font.Face)(nil)
+
 // Font is used internally as a wrapper around the provided Face.
 //
 // Font are constructed with `NewFont` and adjusted by accessing the fields
 // Ptem, XScale, YScale.
 //
 // Fonts private fields only depend on the provided [*font.Font], so a Font object is suitable for caching.
+//
+// The accelerators built by [NewFont] are immutable, so that a Font may be used
+// concurrently by several buffers, as long as its exported fields
+// and its face settings are not modified. A shallow copy of a Font shares
+// its accelerators, and may be used to shape with a different scale.
 type Font struct {
-	face Face
+	face  Face
+	funcs FontFuncs // default to face
 
 	gsubAccels, gposAccels []otLayoutLookupAccelerator // accelators for lookup
 	morxAccels             [][]morxSubtableAccelerator
@@ -61,29 +137,6 @@ type Font struct {
 	// Given a device resolution (in dpi) and a point size, the scale to
 	// get result in pixels is given by : pointSize * dpi / 72
 	XScale, YScale int32
-
-	// slant sets the "synthetic slant" of a font. By default is zero.
-	// Synthetic slant is the graphical skew applied to the font
-	// at rendering time.
-	//
-	// HarfBuzz needs to know this value to adjust shaping results,
-	// metrics, and style values to match the slanted rendering.
-	//
-	// Note: The slant value is a ratio.  For example, a
-	// 20% slant would be represented as a 0.2 value.
-	slant float32
-
-	// embolden sets the "synthetic boldness" of a font.
-	//
-	// Positive values for xEmbolden / yEmbolden make a font
-	// bolder, negative values thinner. Typical values are in the
-	// 0.01 to 0.05 range. The default value is zero.
-	//
-	// Synthetic boldness is applied by offsetting the contour
-	// points of the glyph shape.
-	//
-	// Glyph advance-widths are also adjusted
-	xEmbolden float32
 }
 
 // NewFont constructs a new font object from the specified face.
@@ -96,6 +149,7 @@ func NewFont(face Face) *Font {
 	var font Font
 
 	font.face = face
+	font.funcs = face
 	font.faceUpem = Position(font.face.Upem())
 	font.XScale = font.faceUpem
 	font.YScale = font.faceUpem
@@ -124,6 +178,39 @@ func NewFont(face Face) *Font {
 	return &font
 }
 
+// NewFontWithFuncs constructs a new font object using [funcs] to
+// query glyph information, and [face] for the layout tables (GSUB, GPOS, morx, etc.).
+//
+// If [face] is nil, an empty face is used, meaning no layout table is applied :
+// this is useful for fonts which are not backed by an OpenType file.
+func NewFontWithFuncs(face Face, funcs FontFuncs) *Font {
+	if face == nil {
+		face = font.NewFace(new(font.Font))
+	}
+	out := NewFont(face)
+	out.funcs = funcs
+	out.faceUpem = Position(funcs.Upem())
+	out.XScale, out.YScale = out.faceUpem, out.faceUpem
+	return out
+}
+
+// WithFuncs returns a shallow copy of the font, sharing its face, accelerators and scale,
+// but using [funcs] to query glyph information.
+//
+// This is the equivalent of HarfBuzz sub-fonts : the parent
+// funcs, returned by [Font.Funcs], may be embedded in [funcs] to only override
+// some of the callbacks.
+func (f *Font) WithFuncs(funcs FontFuncs) *Font {
+	out := *f
+	out.funcs = funcs
+	out.faceUpem = Position(funcs.Upem())
+	return &out
+}
+
+// Funcs returns the object used to query glyph information,
+// which is by default the font face.
+func (f *Font) Funcs() FontFuncs { return f.funcs }
+
 // SetVarCoordsDesign applies a list of variation coordinates, in design-space units,
 // to the font.
 func (f *Font) SetVarCoordsDesign(coords []float32) {
@@ -136,14 +223,22 @@ func (f *Font) SetVarCoordsDesign(coords []float32) {
 func (f *Font) Face() Face { return f.face }
 
 func (f *Font) nominalGlyph(r rune, notFound GID) (GID, bool) {
-	g, ok := f.face.NominalGlyph(r)
+	g, ok := f.funcs.NominalGlyph(r)
 	if !ok {
 		g = notFound
 	}
 	return g, ok
 }
 
-func (f *Font) xStrength() Position { return roundf(float32(f.XScale) * f.xEmbolden) }
+// slant returns the synthetic slant of the face, see [font.Synthesis].
+// HarfBuzz needs to know this value to adjust shaping results.
+func (f *Font) slant() float32 { return f.face.Synthesis().Slant }
+
+// xEmbolden returns the synthetic boldness of the face, see [font.Synthesis].
+// Note that glyph advances and extents are directly adjusted by the face.
+func (f *Font) xEmbolden() float32 { return f.face.Synthesis().Embolden }
+
+func (f *Font) xStrength() Position { return roundf(float32(f.XScale) * f.xEmbolden()) }
 
 // ---- Convert from font-space to user-space ----
 
@@ -173,13 +268,11 @@ type GlyphExtents struct {
 // GlyphExtents fetches the GlyphExtents data for a glyph ID
 // in the specified font, or false if not found
 func (f *Font) GlyphExtents(glyph GID) (out GlyphExtents, ok bool) {
-	ext, ok := f.face.GlyphExtents(glyph)
+	ext, ok := f.funcs.GlyphExtents(glyph)
 	if !ok {
 		return out, false
 	}
 
-	syntheticGlyphExtents(&ext, f.slant, f.xEmbolden)
-
 	out.XBearing = f.emScalefX(ext.XBearing)
 	out.Width = f.emScalefX(ext.Width)
 	out.YBearing = f.emScalefY(ext.YBearing)
@@ -202,19 +295,15 @@ func (f *Font) GlyphAdvanceForDirection(glyph GID, dir Direction) (x, y Position
 // GlyphHAdvance fetches the advance for a glyph ID in the font,
 // for horizontal text segments.
 func (f *Font) GlyphHAdvance(glyph GID) Position {
-	adv := f.face.HorizontalAdvance(glyph)
-	out := f.emScalefX(adv)
-	if f.xEmbolden != 0 && out != 0 {
-		out += f.xStrength()
-	}
-	return out
+	adv := f.funcs.HorizontalAdvance(glyph)
+	return f.emScalefX(adv)
 }
 
 // Fetches the advance for a glyph ID in the font,
 // for vertical text segments.
 func (f *Font) getGlyphVAdvance(glyph GID) Position {
-	if f.face.HasVerticalMetrics() {
-		adv := f.face.VerticalAdvance(glyph)
+	if f.funcs.HasVerticalMetrics() {
+		adv := f.funcs.VerticalAdvance(glyph)
 		return f.emScalefY(adv)
 	} else {
 		fontExtents := f.fontHExtentsWithFallback()
@@ -249,16 +338,16 @@ func (f *Font) getGlyphOriginForDirection(glyph GID, direction Direction) (x, y
 }
 
 func (f *Font) getGlyphHOriginWithFallback(glyph GID) (Position, Position) {
-	x, y, ok := f.face.GlyphHOrigin(glyph)
+	x, y, ok := f.funcs.GlyphHOrigin(glyph)
 	if !ok {
-		xf, yf := f.face.GlyphVOrigin(glyph)
+		xf, yf := f.funcs.GlyphVOrigin(glyph)
 		x, y := f.emScalefX(xf), f.emScalefY(yf)
 		dx, dy := f.guessVOriginMinusHOrigin(glyph)
 		return x - dx, y - dy
 	}
 
 	x_, y_ := f.emScalefX(float32(x)), f.emScalefY(float32(y))
-	if f.xEmbolden != 0 {
+	if f.xEmbolden() != 0 {
 		/* Slant is ignored as it does not affect glyph origin */
 
 		/* Embolden */
@@ -270,11 +359,11 @@ func (f *Font) getGlyphHOriginWithFallback(glyph GID) (Position, Position) {
 }
 
 func (f *Font) getGlyphVOriginWithFallback(glyph GID) (Position, Position) {
-	x, y := f.face.GlyphVOrigin(glyph)
+	x, y := f.funcs.GlyphVOrigin(glyph)
 	x_, y_ := f.emScalefX(2*x)/2, f.emScalefY(y) // harfbuzz divides by 2 in Position unit
 	/* Slant is ignored as it does not affect glyph origin */
 	/* Embolden */
-	if f.xEmbolden != 0 {
+	if f.xEmbolden() != 0 {
 		strength := f.xStrength()
 		x_ += strength
 		y_ += strength
@@ -289,7 +378,7 @@ func (f *Font) guessVOriginMinusHOrigin(glyph GID) (x, y Position) {
 }
 
 func (f *Font) getHExtendsAscender() Position {
-	extents, ok := f.face.FontHExtents()
+	extents, ok := f.funcs.FontHExtents()
 	if !ok {
 		return f.YScale * 4 / 5
 	}
@@ -297,7 +386,7 @@ func (f *Font) getHExtendsAscender() Position {
 }
 
 func (f *Font) hasGlyph(ch rune) bool {
-	_, ok := f.face.NominalGlyph(ch)
+	_, ok := f.funcs.NominalGlyph(ch)
 	return ok
 }
 
@@ -317,7 +406,7 @@ func (f *Font) addGlyphHOrigin(glyph GID, x, y Position) (Position, Position) {
 }
 
 func (f *Font) getGlyphContourPointForOrigin(glyph GID, pointIndex uint16, direction Direction) (x, y Position, ok bool) {
-	x, y, ok = f.face.GetGlyphContourPoint(glyph, pointIndex)
+	x, y, ok = f.funcs.GetGlyphContourPoint(glyph, pointIndex)
 	if ok {
 		x, y = f.subtractGlyphOriginForDirection(glyph, direction, x, y)
 	}
@@ -326,7 +415,7 @@ func (f *Font) getGlyphContourPointForOrigin(glyph GID, pointIndex uint16, direc
 }
 
 func (f *Font) fontHExtentsWithFallback() font.FontExtents {
-	extents, ok := f.face.FontHExtents()
+	extents, ok := f.funcs.FontHExtents()
 	extents.Ascender = float32(f.emScalefY(extents.Ascender))
 	extents.Descender = float32(f.emScalefY(extents.Descender))
 	extents.LineGap = float32(f.emScalefY(extents.LineGap))
@@ -351,7 +440,7 @@ func (f *Font) ExtentsForDirection(direction Direction) font.FontExtents {
 	if direction.isHorizontal() {
 		return f.fontHExtentsWithFallback()
 	} else {
-		extents, ok = f.face.FontVExtents()
+		extents, ok = f.funcs.FontVExtents()
 		extents.Ascender = float32(f.emScalefX(extents.Ascender))
 		extents.Descender = float32(f.emScalefX(extents.Descender))
 		extents.LineGap = float32(f.emScalefX(extents.LineGap))
@@ -361,7 +450,7 @@ func (f *Font) ExtentsForDirection(direction Direction) font.FontExtents {
 			extents.LineGap = 0
 		}
 
-		xStrength := float32(f.XScale) * f.xEmbolden
+		xStrength := float32(f.XScale) * f.xEmbolden()
 		extents.Ascender += xStrength
 	}
 	return extents
@@ -393,37 +482,6 @@ func (font *Font) getYDelta(varStore tables.ItemVarStore, device tables.DeviceTa
 	}
 }
 
-func syntheticGlyphExtents(extents *font.GlyphExtents, slant, xEmbolden float32) {
-	/* Slant. */
-	if slant != 0 {
-		x1 := extents.XBearing
-		y1 := extents.YBearing
-		x2 := extents.XBearing + extents.Width
-		y2 := extents.YBearing + extents.Height
-
-		x1 += minF(y1*slant, y2*slant)
-		x2 += maxF(y1*slant, y2*slant)
-
-		extents.XBearing = x1
-		extents.Width = x2 - extents.XBearing
-	}
-
-	/* Embolden. */
-	if xEmbolden != 0 {
-		/* Y */
-		yShift := xEmbolden
-		extents.YBearing += yShift
-		extents.Height -= yShift
-
-		/* X */
-		xShift := xEmbolden
-		// if embolden_in_place {
-		// 	extents.x_bearing -= xShift / 2
-		// }
-		extents.Width += xShift
-	}
-}
-
 // GetOTLigatureCarets fetches a list of the caret positions defined for a ligature glyph in the GDEF
 // table of the font (or nil if not found).
 func (f *Font) GetOTLigatureCarets(direction Direction, glyph GID) []Position {
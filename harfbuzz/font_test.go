@@ -279,3 +279,97 @@ func TestUnifont(t *testing.T) {
 	font := NewFont(font.NewFace(ft))
 	buf.Shape(font, nil) // just check for crashes
 }
+
+type doubleAdvanceFuncs struct{ FontFuncs }
+
+func (df doubleAdvanceFuncs) HorizontalAdvance(gid GID) float32 {
+	return 2 * df.FontFuncs.HorizontalAdvance(gid)
+}
+
+func TestFontFuncsOverride(t *testing.T) {
+	ft := NewFont(font.NewFace(openFontFileTT(t, "common/DejaVuSans.ttf")))
+	tu.Assert(t, ft.Funcs() == FontFuncs(ft.Face()))
+
+	shape := func(ft *Font) *Buffer {
+		buf := NewBuffer()
+		buf.AddRunes([]rune("AVAWAY fi"), 0, -1)
+		buf.GuessSegmentProperties()
+		buf.Shape(ft, nil)
+		return buf
+	}
+
+	sub := ft.WithFuncs(doubleAdvanceFuncs{ft.Funcs()})
+	tu.Assert(t, ft.Funcs() == FontFuncs(ft.Face())) // parent is not modified
+	ref, got := shape(ft), shape(sub)
+	tu.Assert(t, len(ref.Info) == len(got.Info))
+	for i := range ref.Info {
+		tu.Assert(t, ref.Info[i].Glyph == got.Info[i].Glyph)
+		// kerning is still applied
+		expected := ref.Pos[i].XAdvance + ft.GlyphHAdvance(ref.Info[i].Glyph)
+		tu.AssertC(t, got.Pos[i].XAdvance == expected, fmt.Sprint(got.Pos[i].XAdvance, expected))
+	}
+}
+
+// monoFuncs is a synthetic, monospace font, mapping
+// ASCII letters to their code point.
+type monoFuncs struct{}
+
+func (monoFuncs) Upem() uint16 { return 1000 }
+
+func (monoFuncs) NominalGlyph(ch rune) (GID, bool) {
+	if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == ' ' {
+		return GID(ch), true
+	}
+	return 0, false
+}
+func (monoFuncs) VariationGlyph(ch, varSelector rune) (GID, bool) { return 0, false }
+func (monoFuncs) HorizontalAdvance(gid GID) float32               { return 600 }
+func (monoFuncs) HasVerticalMetrics() bool                        { return false }
+func (monoFuncs) VerticalAdvance(gid GID) float32                 { return -1000 }
+func (monoFuncs) GlyphHOrigin(gid GID) (x, y int32, found bool)   { return 0, 0, true }
+func (monoFuncs) GlyphVOrigin(gid GID) (x, y float32)             { return 300, 800 }
+func (monoFuncs) GlyphExtents(gid GID) (font.GlyphExtents, bool) {
+	return font.GlyphExtents{XBearing: 50, YBearing: 700, Width: 500, Height: -700}, true
+}
+func (monoFuncs) GetGlyphContourPoint(gid GID, pointIndex uint16) (x, y int32, ok bool) {
+	return 0, 0, false
+}
+func (monoFuncs) GlyphName(gid GID) string { return "" }
+func (monoFuncs) GlyphFromName(name string) (GID, bool) {
+	return 0, false
+}
+
+func (monoFuncs) FontHExtents() (font.FontExtents, bool) {
+	return font.FontExtents{Ascender: 800, Descender: -200}, true
+}
+func (monoFuncs) FontVExtents() (font.FontExtents, bool) { return font.FontExtents{}, false }
+
+func TestSyntheticFontFuncs(t *testing.T) {
+	ft := NewFontWithFuncs(nil, monoFuncs{})
+	ft.XScale, ft.YScale = 2000, 2000
+
+	buf := NewBuffer()
+	buf.AddRunes([]rune("Hi go!"), 0, -1)
+	buf.GuessSegmentProperties()
+	buf.Shape(ft, nil)
+
+	tu.Assert(t, len(buf.Info) == 6)
+	for i, r := range "Hi go" {
+		tu.Assert(t, buf.Info[i].Glyph == GID(r))
+		tu.Assert(t, buf.Pos[i].XAdvance == 1200)
+	}
+	tu.Assert(t, buf.Info[5].Glyph == 0) // not found
+
+	extents, ok := ft.GlyphExtents(buf.Info[0].Glyph)
+	tu.Assert(t, ok && extents.Width == 1000)
+
+	// vertical metrics are deduced from the font extents
+	buf.Clear()
+	buf.AddRunes([]rune("go"), 0, -1)
+	buf.Props.Direction = TopToBottom
+	buf.GuessSegmentProperties()
+	buf.Shape(ft, nil)
+	tu.Assert(t, buf.Pos[0].YAdvance == -2000)
+	tu.AssertC(t, buf.Serialize(ft, SerializeFormatText, 0) == "[gid103=0@-600,-1600+0,-2000|gid111=1@-600,-1600+0,-2000]",
+		buf.Serialize(ft, SerializeFormatText, 0))
+}
@@ -52,69 +52,28 @@ func TestDebug(t *testing.T) {
 	fmt.Println(out)
 }
 
-// Generates gidDDD if glyph has no name.
-func (f *Font) glyphToString(glyph GID) string {
-	if name := f.face.GlyphName(glyph); name != "" {
-		return name
-	}
-
-	return fmt.Sprintf("gid%d", glyph)
-}
-
 // return a compact representation of the buffer contents
 func (b *Buffer) serialize(font *Font, opt formatOpts) string {
-	if len(b.Info) == 0 {
-		return "" //  the reference does not return []
-	}
-	gs := new(strings.Builder)
-	gs.WriteByte('[')
-	var x, y Position
-	for i, glyph := range b.Info {
-		if opt.hideGlyphNames {
-			fmt.Fprintf(gs, "%d", glyph.Glyph)
-		} else {
-			gs.WriteString(font.glyphToString(glyph.Glyph))
-		}
-
-		if !opt.hideClusters {
-			fmt.Fprintf(gs, "=%d", glyph.Cluster)
-		}
-		pos := b.Pos[i]
-
-		if !opt.hidePositions {
-			if x+pos.XOffset != 0 || y+pos.YOffset != 0 {
-				fmt.Fprintf(gs, "@%d,%d", x+pos.XOffset, y+pos.YOffset)
-			}
-			if !opt.hideAdvances {
-				fmt.Fprintf(gs, "+%d", pos.XAdvance)
-				if pos.YAdvance != 0 {
-					fmt.Fprintf(gs, ",%d", pos.YAdvance)
-				}
-			}
-		}
-
-		if opt.showFlags {
-			if mask := glyph.Mask & glyphFlagDefined; mask != 0 {
-				fmt.Fprintf(gs, "#%d", mask)
-			}
-		}
-
-		if opt.showExtents {
-			extents, _ := font.GlyphExtents(glyph.Glyph)
-			fmt.Fprintf(gs, "<%d,%d,%d,%d>", extents.XBearing, extents.YBearing, extents.Width, extents.Height)
-		}
-
-		if i != len(b.Info)-1 {
-			gs.WriteByte('|')
-		}
-
-		if opt.hideAdvances {
-			x += pos.XAdvance
-			y += pos.YAdvance
-		}
+	var flags SerializeFlags
+	if opt.hideGlyphNames {
+		flags |= SerializeNoGlyphNames
+	}
+	if opt.hideClusters {
+		flags |= SerializeNoClusters
+	}
+	if opt.hidePositions {
+		flags |= SerializeNoPositions
+	}
+	if opt.hideAdvances {
+		flags |= SerializeNoAdvances
+	}
+	if opt.showFlags {
+		flags |= SerializeGlyphFlags
+	}
+	if opt.showExtents {
+		flags |= SerializeGlyphExtents
 	}
-	gs.WriteByte(']')
-	return gs.String()
+	return b.Serialize(font, SerializeFormatText, flags)
 }
 
 func (fo *fontOpts) loadFont(t *testing.T) *Font {
@@ -134,6 +93,7 @@ func (fo *fontOpts) loadFont(t *testing.T) *Font {
 	face := font.NewFace(ft)
 	face.SetPpem(fo.xPpem, fo.yPpem)
 	face.SetVariations(fo.variations)
+	face.SetSynthesis(font.Synthesis{Embolden: float32(fo.embolden), Slant: float32(fo.slant)})
 
 	font := NewFont(face)
 
@@ -145,8 +105,6 @@ func (fo *fontOpts) loadFont(t *testing.T) *Font {
 	}
 
 	font.Ptem = float32(fo.ptem)
-	font.slant = float32(fo.slant)
-	font.xEmbolden = float32(fo.embolden)
 
 	scaleX := scalbnf(float64(fo.fontSizeX), fo.subpixelBits)
 	scaleY := scalbnf(float64(fo.fontSizeY), fo.subpixelBits)
@@ -114,17 +114,26 @@ func (c *otApplyContext) applyBackward(accel *otLayoutLookupAccelerator) bool {
 func (m *otMap) apply(proxy otProxy, plan *otShapePlan, font *Font, buffer *Buffer) {
 	tableIndex := proxy.tableIndex
 	i := 0
-	c := &m.applyContext
+	c := &buffer.applyContext
 
 	c.reset(tableIndex, font, buffer)
 	c.recurseFunc = proxy.recurseFunc
 
+	table := tagGSUB
+	if tableIndex == 1 {
+		table = tagGPOS
+	}
+
 	for stageI, stage := range m.stages[tableIndex] {
 
 		if debugMode {
 			fmt.Printf("\tAPPLY - stage %d\n", stageI)
 		}
 
+		if !buffer.trace(font, TraceEvent{Step: TraceStage, Start: true, Table: table, Index: stageI}) {
+			return
+		}
+
 		for ; i < stage.lastLookup; i++ {
 			lookup := m.lookups[tableIndex][i]
 			lookupIndex := lookup.index
@@ -147,7 +156,16 @@ func (m *otMap) apply(proxy otProxy, plan *otShapePlan, font *Font, buffer *Buff
 				if len(c.buffer.Info) > c.buffer.maxLen {
 					return
 				}
+
+				ev := TraceEvent{Step: TraceLookup, Start: true, Table: table, Index: int(lookupIndex), Feature: lookup.featureTag}
+				if !buffer.trace(font, ev) {
+					return
+				}
 				c.applyString(proxy.otProxyMeta, accel)
+				ev.Start = false
+				if !buffer.trace(font, ev) {
+					return
+				}
 			}
 
 			if debugMode {
@@ -166,10 +184,22 @@ func (m *otMap) apply(proxy otProxy, plan *otShapePlan, font *Font, buffer *Buff
 				fmt.Println("\t\tExecuting pause function")
 			}
 
+			ev := TraceEvent{Step: TracePause, Start: true, Table: table, Index: stageI}
+			if !buffer.trace(font, ev) {
+				return
+			}
 			if stage.pauseFunc(plan, font, buffer) {
 				// Refresh working buffer digest since buffer changed.
 				buffer.updateDigest()
 			}
+			ev.Start = false
+			if !buffer.trace(font, ev) {
+				return
+			}
+		}
+
+		if !buffer.trace(font, TraceEvent{Step: TraceStage, Table: table, Index: stageI}) {
+			return
 		}
 	}
 }
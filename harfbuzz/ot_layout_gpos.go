@@ -143,8 +143,8 @@ func positionFinishOffsetsGPOS(font *Font, buffer *Buffer) {
 		}
 	}
 
-	if font.slant != 0 && direction.isHorizontal() {
-		slantXY := font.slant * float32(font.XScale) / float32(font.YScale)
+	if font.slant() != 0 && direction.isHorizontal() {
+		slantXY := font.slant() * float32(font.XScale) / float32(font.YScale)
 
 		/* Slanting shaping results is only supported for horizontal text,
 		 * as it gets weird otherwise. */
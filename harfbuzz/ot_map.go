@@ -383,8 +383,6 @@ type otMap struct {
 	chosenScript [2]tables.Tag
 	globalMask   GlyphMask
 	foundScript  [2]bool
-
-	applyContext otApplyContext // buffer
 }
 
 func (m *otMap) needsFallback(featureTag tables.Tag) bool {
@@ -461,7 +459,9 @@ func (m *otMap) substitute(plan *otShapePlan, font *Font, buffer *Buffer) {
 	}
 
 	proxy := otProxy{otProxyMeta: proxyGSUB, accels: font.gsubAccels}
-	m.apply(proxy, plan, font, buffer)
+	buffer.traceStep(font, TraceEvent{Step: TraceTable, Table: tagGSUB}, func() {
+		m.apply(proxy, plan, font, buffer)
+	})
 
 	if debugMode {
 		fmt.Println("SUBSTITUTE - end table GSUB")
@@ -475,7 +475,9 @@ func (m *otMap) position(plan *otShapePlan, font *Font, buffer *Buffer) {
 	}
 
 	proxy := otProxy{otProxyMeta: proxyGPOS, accels: font.gposAccels}
-	m.apply(proxy, plan, font, buffer)
+	buffer.traceStep(font, TraceEvent{Step: TraceTable, Table: tagGPOS}, func() {
+		m.apply(proxy, plan, font, buffer)
+	})
 
 	if debugMode {
 		fmt.Println("POSITION - end table GPOS")
@@ -185,7 +185,12 @@ func (sp *otShapePlan) position(font *Font, buffer *Buffer) {
 	if sp.applyGpos {
 		sp.otMap.position(sp, font, buffer)
 	} else if sp.applyKerx {
-		sp.aatLayoutPosition(font, buffer)
+		buffer.traceStep(font, TraceEvent{Step: TraceTable, Table: ot.NewTag('k', 'e', 'r', 'x')}, func() {
+			sp.aatLayoutPosition(font, buffer)
+		})
+	}
+	if buffer.aborted() {
+		return
 	}
 
 	if sp.applyKern {
@@ -533,7 +538,7 @@ func hideDefaultIgnorables(buffer *Buffer, font *Font) {
 		ok        bool
 	)
 	if invisible == 0 {
-		invisible, ok = font.face.NominalGlyph(' ')
+		invisible, ok = font.funcs.NominalGlyph(' ')
 	}
 	if buffer.Flags&RemoveDefaultIgnorables == 0 && ok {
 		// replace default-ignorables with a zero-advance invisible glyph.
@@ -574,7 +579,12 @@ func (c *otContext) substituteBeforePosition() {
 	// substituteDefault : normalize and sets Glyph
 	c.otRotateChars()
 
-	otShapeNormalize(c.plan, buffer, c.font)
+	buffer.traceStep(c.font, TraceEvent{Step: TraceNormalize}, func() {
+		otShapeNormalize(c.plan, buffer, c.font)
+	})
+	if buffer.aborted() {
+		return
+	}
 
 	c.setupMasks()
 
@@ -597,12 +607,17 @@ func (c *otContext) substituteBeforePosition() {
 	}
 
 	if c.plan.applyMorx {
-		c.plan.aatLayoutSubstitute(c.font, c.buffer, c.userFeatures)
+		buffer.traceStep(c.font, TraceEvent{Step: TraceTable, Table: ot.NewTag('m', 'o', 'r', 'x')}, func() {
+			c.plan.aatLayoutSubstitute(c.font, c.buffer, c.userFeatures)
+		})
 		c.buffer.updateDigest()
 	} else {
 		c.buffer.updateDigest()
 		c.plan.substitute(c.font, buffer)
 	}
+	if buffer.aborted() {
+		return
+	}
 
 	//
 	if c.plan.applyMorx && c.plan.applyGpos {
@@ -622,7 +637,9 @@ func (c *otContext) substituteAfterPosition() {
 	if debugMode {
 		fmt.Println("POSTPROCESS glyphs start")
 	}
-	c.plan.shaper.postprocessGlyphs(c.plan, c.buffer, c.font)
+	c.buffer.traceStep(c.font, TraceEvent{Step: TracePostprocessGlyphs}, func() {
+		c.plan.shaper.postprocessGlyphs(c.plan, c.buffer, c.font)
+	})
 	if debugMode {
 		fmt.Println("POSTPROCESS glyphs end ")
 	}
@@ -701,6 +718,9 @@ func (c *otContext) positionComplex() {
 	}
 
 	c.plan.position(c.font, c.buffer) // apply GPOS, AAT
+	if c.buffer.aborted() {
+		return
+	}
 
 	if c.plan.zeroMarks {
 		if markBehavior == zeroWidthMarksByGdefLate {
@@ -731,6 +751,9 @@ func (c *otContext) position() {
 	}
 
 	c.positionComplex()
+	if c.buffer.aborted() {
+		return
+	}
 
 	if c.buffer.Props.Direction.isBackward() {
 		c.buffer.Reverse()
@@ -852,26 +875,35 @@ func (sp *shaperOpentype) shape(font *Font, buffer *Buffer, features []Feature)
 	if debugMode {
 		fmt.Printf("PREPROCESS text start\n")
 	}
-	c.plan.shaper.preprocessText(c.plan, c.buffer, c.font)
+	c.buffer.traceStep(c.font, TraceEvent{Step: TracePreprocessText}, func() {
+		c.plan.shaper.preprocessText(c.plan, c.buffer, c.font)
+	})
 	if debugMode {
 		fmt.Println("PREPROCESS text end:", c.buffer.Info)
 	}
 
-	c.substituteBeforePosition() // apply GSUB
+	// if the shaping is aborted by the trace callback,
+	// the buffer is left in its intermediate state
+	if !c.buffer.aborted() {
+		c.substituteBeforePosition() // apply GSUB
+	}
 
 	if debugMode {
 		fmt.Println("AFTER SUBSTITUTE", c.buffer.Info)
 	}
 
-	c.position()
+	if !c.buffer.aborted() {
+		c.position()
+	}
 
 	if debugMode {
 		fmt.Println("AFTER POSITION", c.buffer.Pos)
 	}
 
-	c.substituteAfterPosition()
-
-	propagateFlags(c.buffer)
+	if !c.buffer.aborted() {
+		c.substituteAfterPosition()
+		propagateFlags(c.buffer)
+	}
 
 	c.buffer.Props.Direction = c.targetDirection
 
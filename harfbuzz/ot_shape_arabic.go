@@ -711,8 +711,8 @@ func arabicFallbackSynthesizeLookupSingle(ft *Font, featureIndex int) *lookupGSU
 	// populate arrays
 	for u := rune(firstArabicShape); u <= lastArabicShape; u++ {
 		s := rune(arabicShaping[u-firstArabicShape][featureIndex])
-		uGlyph, hasU := ft.face.NominalGlyph(u)
-		sGlyph, hasS := ft.face.NominalGlyph(s)
+		uGlyph, hasU := ft.funcs.NominalGlyph(u)
+		sGlyph, hasS := ft.funcs.NominalGlyph(s)
 
 		if s == 0 || !hasU || !hasS || uGlyph == sGlyph || uGlyph > 0xFFFF || sGlyph > 0xFFFF {
 			continue
@@ -762,7 +762,7 @@ func arabicFallbackSynthesizeLookupLigature(ft *Font, ligatureTable []arabicTabl
 
 	// sort out the first-glyphs
 	for firstGlyphIdx, lig := range ligatureTable {
-		firstGlyph, ok := ft.face.NominalGlyph(lig.First)
+		firstGlyph, ok := ft.funcs.NominalGlyph(lig.First)
 		if !ok {
 			continue
 		}
@@ -785,7 +785,7 @@ func arabicFallbackSynthesizeLookupLigature(ft *Font, ligatureTable []arabicTabl
 		var ligatureSet tables.LigatureSet
 		for _, v := range ligs {
 			ligatureU := v.ligature
-			ligatureGlyph, hasLigature := ft.face.NominalGlyph(ligatureU)
+			ligatureGlyph, hasLigature := ft.funcs.NominalGlyph(ligatureU)
 			if !hasLigature {
 				continue
 			}
@@ -793,7 +793,7 @@ func arabicFallbackSynthesizeLookupLigature(ft *Font, ligatureTable []arabicTabl
 			components := v.components
 			var componentGIDs []gID
 			for _, componentU := range components {
-				componentGlyph, hasComponent := ft.face.NominalGlyph(componentU)
+				componentGlyph, hasComponent := ft.funcs.NominalGlyph(componentU)
 				if !hasComponent {
 					break
 				}
@@ -845,11 +845,11 @@ type arabicFallbackPlan struct {
 
 func (fbPlan *arabicFallbackPlan) initWin1256(plan *otShapePlan, font *Font) bool {
 	// does this font look like it's Windows-1256-encoded?
-	g1, _ := font.face.NominalGlyph(0x0627) /* ALEF */
-	g2, _ := font.face.NominalGlyph(0x0644) /* LAM */
-	g3, _ := font.face.NominalGlyph(0x0649) /* ALEF MAKSURA */
-	g4, _ := font.face.NominalGlyph(0x064A) /* YEH */
-	g5, _ := font.face.NominalGlyph(0x0652) /* SUKUN */
+	g1, _ := font.funcs.NominalGlyph(0x0627) /* ALEF */
+	g2, _ := font.funcs.NominalGlyph(0x0644) /* LAM */
+	g3, _ := font.funcs.NominalGlyph(0x0649) /* ALEF MAKSURA */
+	g4, _ := font.funcs.NominalGlyph(0x064A) /* YEH */
+	g5, _ := font.funcs.NominalGlyph(0x0652) /* SUKUN */
 	if !(g1 == 199 && g2 == 225 && g3 == 236 && g4 == 237 && g5 == 250) {
 		return false
 	}
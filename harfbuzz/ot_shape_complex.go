@@ -230,7 +230,7 @@ func syllabicInsertDottedCircles(font *Font, buffer *Buffer, brokenSyllableType,
 		return false
 	}
 
-	dottedcircleGlyph, ok := font.face.NominalGlyph(0x25CC)
+	dottedcircleGlyph, ok := font.funcs.NominalGlyph(0x25CC)
 	if !ok {
 		return false
 	}
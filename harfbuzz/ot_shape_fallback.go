@@ -400,7 +400,7 @@ func fallbackSpaces(font *Font, buffer *Buffer) {
 			}
 		case spaceFigure:
 			for u := '0'; u <= '9'; u++ {
-				if glyph, ok := font.face.NominalGlyph(u); ok {
+				if glyph, ok := font.funcs.NominalGlyph(u); ok {
 					if horizontal {
 						pos[i].XAdvance = font.GlyphHAdvance(glyph)
 					} else {
@@ -409,9 +409,9 @@ func fallbackSpaces(font *Font, buffer *Buffer) {
 				}
 			}
 		case spacePunctuation:
-			glyph, ok := font.face.NominalGlyph('.')
+			glyph, ok := font.funcs.NominalGlyph('.')
 			if !ok {
-				glyph, ok = font.face.NominalGlyph(',')
+				glyph, ok = font.funcs.NominalGlyph(',')
 			}
 			if ok {
 				if horizontal {
@@ -82,7 +82,7 @@ func isT(u rune) bool {
 }
 
 func isZeroWidthChar(font *Font, unicode rune) bool {
-	glyph, ok := font.face.NominalGlyph(unicode)
+	glyph, ok := font.funcs.NominalGlyph(unicode)
 	return ok && font.GlyphHAdvance(glyph) == 0
 }
 
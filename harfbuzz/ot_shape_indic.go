@@ -263,7 +263,7 @@ type indicShapePlan struct {
 
 func (indicPlan *indicShapePlan) loadViramaGlyph(font *Font) GID {
 	if indicPlan.viramaGlyph == ^GID(0) {
-		glyph, ok := font.face.NominalGlyph(indicPlan.config.virama)
+		glyph, ok := font.funcs.NominalGlyph(indicPlan.config.virama)
 		if indicPlan.config.virama == 0 || !ok {
 			glyph = 0
 		}
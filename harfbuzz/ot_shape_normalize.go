@@ -70,7 +70,7 @@ type otNormalizeContext struct {
 }
 
 func setGlyph(info *GlyphInfo, font *Font) {
-	info.Glyph, _ = font.face.NominalGlyph(info.codepoint)
+	info.Glyph, _ = font.funcs.NominalGlyph(info.codepoint)
 }
 
 func outputChar(buffer *Buffer, unichar rune, glyph GID) {
@@ -93,12 +93,12 @@ func decompose(c *otNormalizeContext, shortest bool, ab rune) int {
 	if !ok {
 		return 0
 	}
-	bGlyph, ok = font.face.NominalGlyph(b)
+	bGlyph, ok = font.funcs.NominalGlyph(b)
 	if b != 0 && !ok {
 		return 0
 	}
 
-	aGlyph, hasA := font.face.NominalGlyph(a)
+	aGlyph, hasA := font.funcs.NominalGlyph(a)
 	if shortest && hasA {
 		/// output a and b
 		outputChar(buffer, a, aGlyph)
@@ -151,7 +151,7 @@ func (c *otNormalizeContext) decomposeCurrentCharacter(shortest bool) {
 
 	if buffer.cur(0).isUnicodeSpace() {
 		spaceType := uniSpaceFallbackType(u)
-		if spaceGlyph, ok := c.font.face.NominalGlyph(0x0020); spaceType != notSpace && (ok || buffer.Invisible != 0) {
+		if spaceGlyph, ok := c.font.funcs.NominalGlyph(0x0020); spaceType != notSpace && (ok || buffer.Invisible != 0) {
 			if !ok {
 				spaceGlyph = buffer.Invisible
 			}
@@ -165,7 +165,7 @@ func (c *otNormalizeContext) decomposeCurrentCharacter(shortest bool) {
 	if u == 0x2011 {
 		/* U+2011 is the only sensible character that is a no-break version of another character
 		 * and not a space. The space ones are handled already.  Handle this lone one. */
-		if otherGlyph, ok := c.font.face.NominalGlyph(0x2010); ok {
+		if otherGlyph, ok := c.font.funcs.NominalGlyph(0x2010); ok {
 			nextChar(buffer, otherGlyph)
 			return
 		}
@@ -184,7 +184,7 @@ func (c *otNormalizeContext) handleVariationSelectorCluster(end int) {
 	for buffer.idx < end-1 {
 		if uniIsVariationSelector(buffer.cur(+1).codepoint) {
 			var ok bool
-			buffer.cur(0).Glyph, ok = font.face.VariationGlyph(buffer.cur(0).codepoint, buffer.cur(+1).codepoint)
+			buffer.cur(0).Glyph, ok = font.funcs.VariationGlyph(buffer.cur(0).codepoint, buffer.cur(+1).codepoint)
 			if ok {
 				r := buffer.cur(0).codepoint
 				buffer.replaceGlyphs(2, []rune{r}, nil)
@@ -303,7 +303,7 @@ func otShapeNormalize(plan *otShapePlan, buffer *Buffer, font *Font) {
 				ok bool
 			)
 			for i = buffer.idx; i < end; i++ {
-				buffer.Info[i].Glyph, ok = font.face.NominalGlyph(buffer.Info[i].codepoint)
+				buffer.Info[i].Glyph, ok = font.funcs.NominalGlyph(buffer.Info[i].codepoint)
 				if !ok {
 					break
 				}
@@ -410,7 +410,7 @@ func otShapeNormalize(plan *otShapePlan, buffer *Buffer, font *Font) {
 					/* And compose. */
 					composed, ok := c.compose(&c, buffer.outInfo[starter].codepoint, buffer.cur(0).codepoint)
 					if ok { // And the font has glyph for the composite.
-						glyph, ok := font.face.NominalGlyph(composed) /* Composes. */
+						glyph, ok := font.funcs.NominalGlyph(composed) /* Composes. */
 						if ok {
 							buffer.nextGlyph() /* Copy to out-buffer. */
 							buffer.mergeOutClusters(starter, len(buffer.outInfo))
@@ -2,7 +2,9 @@ package harfbuzz
 
 import (
 	"fmt"
+	"sync"
 
+	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/font/opentype/tables"
 )
 
@@ -26,29 +28,35 @@ import (
 //
 // It also depends on the properties of the segment of text : the `Props`
 // field of the buffer must be set before calling `Shape`.
+//
+// The plan is fetched from the `PlanCache` of the buffer, or compiled and
+// added to it.
 func (b *Buffer) Shape(font *Font, features []Feature) {
-	shapePlan := b.newShapePlanCached(font, b.Props, features, font.varCoords())
-	shapePlan.execute(font, b, features)
+	if b.PlanCache == nil {
+		b.PlanCache = new(ShapePlanCache)
+	}
+	shapePlan := b.PlanCache.Get(font, b.Props, features)
+	shapePlan.Execute(font, b, features)
 }
 
-// Shape plans are an internal mechanism. Each plan contains state
-// describing how HarfBuzz will shape a particular text segment, based on
-// the combination of segment properties and the capabilities in the
-// font face in use.
+// ShapePlan contains the state describing how a particular text segment will be shaped,
+// based on the combination of segment properties, user features, and the capabilities
+// of the font in use (including its variation coordinates).
 //
-// Shape plans are not used for shaping directly, but can be queried to
-// access certain information about how shaping will perform, given a set
-// of specific input parameters (script, language, direction, features,
-// etc.).
+// Compiling a plan is expensive, and [Buffer.Shape] uses a cache of plans, so that
+// most client programs will not need to deal with shape plans directly.
+// However, when shaping a lot of text with the same settings, a plan may be
+// built once and executed on many buffers.
 //
-// Most client programs will not need to deal with shape plans directly.
-type shapePlan struct {
+// A ShapePlan is immutable once built : it may be executed concurrently
+// on different buffers.
+type ShapePlan struct {
 	shaper       shaperOpentype
 	props        SegmentProperties
 	userFeatures []Feature
 }
 
-func (plan *shapePlan) init(copy bool, font *Font, props SegmentProperties,
+func (plan *ShapePlan) init(copy bool, font *Font, props SegmentProperties,
 	userFeatures []Feature, coords []tables.Coord,
 ) {
 	plan.props = props
@@ -71,7 +79,7 @@ func (plan *shapePlan) init(copy bool, font *Font, props SegmentProperties,
 	plan.shaper.init(font.face.Font, coords)
 }
 
-func (plan shapePlan) userFeaturesMatch(other shapePlan) bool {
+func (plan *ShapePlan) userFeaturesMatch(other *ShapePlan) bool {
 	if len(plan.userFeatures) != len(other.userFeatures) {
 		return false
 	}
@@ -85,21 +93,22 @@ func (plan shapePlan) userFeaturesMatch(other shapePlan) bool {
 	return true
 }
 
-func (plan shapePlan) equal(other shapePlan) bool {
-	return plan.props == other.props && plan.userFeaturesMatch(other)
+func (plan *ShapePlan) equal(other *ShapePlan) bool {
+	return plan.props == other.props && plan.shaper.key == other.shaper.key && plan.userFeaturesMatch(other)
 }
 
-// Constructs a shaping plan for a combination of @face, @userFeatures, @props,
-// plus the variation-space coordinates @coords.
-// See newShapePlanCached for caching support.
-func newShapePlan(font *Font, props SegmentProperties,
-	userFeatures []Feature, coords []tables.Coord,
-) *shapePlan {
+// NewShapePlan constructs a shaping plan for a combination of the font face,
+// the segment properties [props] and the [userFeatures],
+// plus the current variation-space coordinates of [font].
+//
+// See [ShapePlanCache] for caching support.
+func NewShapePlan(font *Font, props SegmentProperties, userFeatures []Feature) *ShapePlan {
+	coords := font.varCoords()
 	if debugMode {
 		fmt.Printf("NEW SHAPE PLAN: face:%p features:%v coords:%v\n", &font.face, userFeatures, coords)
 	}
 
-	var sp shapePlan
+	var sp ShapePlan
 
 	sp.init(true, font, props, userFeatures, coords)
 
@@ -111,13 +120,19 @@ func newShapePlan(font *Font, props SegmentProperties,
 	return &sp
 }
 
-// Executes the given shaping plan on the specified `buffer`, using
-// the given `font` and `features`.
-func (sp *shapePlan) execute(font *Font, buffer *Buffer, features []Feature) {
+// Props returns the segment properties the plan has been built for.
+func (sp *ShapePlan) Props() SegmentProperties { return sp.props }
+
+// Execute shapes [buffer] using the plan, with the given `font` and `features`,
+// which must be the ones used to build the plan (see [NewShapePlan]).
+// The `Props` of the buffer are ignored and replaced by the ones of the plan.
+func (sp *ShapePlan) Execute(font *Font, buffer *Buffer, features []Feature) {
 	if debugMode {
 		fmt.Printf("EXECUTE shape plan %p features:%v shaper:%T\n", sp, features, sp.shaper.plan.shaper)
 	}
 
+	buffer.Props = sp.props
+	buffer.traceAborted = false
 	sp.shaper.shape(font, buffer, features)
 }
 
@@ -125,28 +140,40 @@ func (sp *shapePlan) execute(font *Font, buffer *Buffer, features []Feature) {
  * Caching
  */
 
-// creates (or returns) a cached shaping plan suitable for reuse, for a combination
-// of `face`, `userFeatures`, `props`, plus the variation-space coordinates `coords`.
-func (b *Buffer) newShapePlanCached(font *Font, props SegmentProperties,
-	userFeatures []Feature, coords []tables.Coord,
-) *shapePlan {
-	var key shapePlan
-	key.init(false, font, props, userFeatures, coords)
+// ShapePlanCache stores shaping plans, so that they may be reused
+// across shaping calls. Plans are keyed by font, segment properties, features, and
+// variation coordinates.
+//
+// A ShapePlanCache is safe for concurrent use, so that it
+// may be shared between buffers used in different goroutines.
+// The zero value is an empty cache ready to use.
+type ShapePlanCache struct {
+	plans map[*font.Font][]*ShapePlan
 
-	plans := b.planCache[font.face]
+	// MaxFonts, if positive, limits the number of fonts
+	// for which plans are kept. When the limit is exceeded, the cache is emptied.
+	MaxFonts int
 
-	for _, plan := range plans {
-		if plan.equal(key) {
-			if debugMode {
-				fmt.Printf("\tPLAN %p fulfilled from cache\n", plan)
-			}
-			return plan
+	mu sync.Mutex
+}
+
+// Get returns a cached shaping plan suitable for reuse, for a combination
+// of `font`, `userFeatures`, `props`, plus the variation-space coordinates of `font`.
+// If no such plan is found, a new one is created with [NewShapePlan] and added to the cache.
+func (c *ShapePlanCache) Get(font *Font, props SegmentProperties, userFeatures []Feature) *ShapePlan {
+	var key ShapePlan
+	key.init(false, font, props, userFeatures, font.varCoords())
+
+	if plan := c.lookup(key.shaper.tables, &key); plan != nil {
+		if debugMode {
+			fmt.Printf("\tPLAN %p fulfilled from cache\n", plan)
 		}
+		return plan
 	}
-	plan := newShapePlan(font, props, userFeatures, coords)
 
-	plans = append(plans, plan)
-	b.planCache[font.face] = plans
+	// compile outside of the lock, since it may be slow
+	plan := NewShapePlan(font, props, userFeatures)
+	plan = c.store(key.shaper.tables, &key, plan)
 
 	if debugMode {
 		fmt.Printf("\tPLAN %p inserted into cache\n", plan)
@@ -154,3 +181,33 @@ func (b *Buffer) newShapePlanCached(font *Font, props SegmentProperties,
 
 	return plan
 }
+
+func (c *ShapePlanCache) lookup(ft *font.Font, key *ShapePlan) *ShapePlan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, plan := range c.plans[ft] {
+		if plan.equal(key) {
+			return plan
+		}
+	}
+	return nil
+}
+
+// store adds [plan] to the cache, unless another goroutine
+// has inserted an equivalent plan, which is then returned
+func (c *ShapePlanCache) store(ft *font.Font, key, plan *ShapePlan) *ShapePlan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	plans := c.plans[ft]
+	for _, cached := range plans {
+		if cached.equal(key) {
+			return cached
+		}
+	}
+	if c.plans == nil || (c.MaxFonts > 0 && plans == nil && len(c.plans) >= c.MaxFonts) {
+		c.plans = make(map[*font.Font][]*ShapePlan)
+	}
+	c.plans[ft] = append(plans, plan)
+	return plan
+}
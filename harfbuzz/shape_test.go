@@ -0,0 +1,106 @@
+package harfbuzz
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func shapeWithPlan(plan *ShapePlan, ft *Font, text string, features []Feature) string {
+	buffer := NewBuffer()
+	buffer.AddRunes([]rune(text), 0, -1)
+	plan.Execute(ft, buffer, features)
+	return buffer.Serialize(ft, SerializeFormatText, SerializeGlyphFlags)
+}
+
+func TestShapePlanCache(t *testing.T) {
+	ftA := openFontFileTT(t, "common/DejaVuSans.ttf")
+	ftB := openFontFileTT(t, "common/NotoSansArabic.ttf")
+	fontA, fontB := NewFont(font.NewFace(ftA)), NewFont(font.NewFace(ftB))
+
+	latin := SegmentProperties{Direction: LeftToRight, Script: language.Latin, Language: language.NewLanguage("en")}
+	arabic := SegmentProperties{Direction: RightToLeft, Script: language.Arabic, Language: language.NewLanguage("ar")}
+	noLiga := []Feature{{Tag: ot.MustNewTag("liga"), Value: 0, Start: FeatureGlobalStart, End: FeatureGlobalEnd}}
+	noLigaRanged := []Feature{{Tag: ot.MustNewTag("liga"), Value: 0, Start: 1, End: 3}}
+
+	var cache ShapePlanCache
+	plan := cache.Get(fontA, latin, nil)
+	tu.Assert(t, plan.Props() == latin)
+	tu.Assert(t, cache.Get(fontA, latin, nil) == plan)
+	tu.Assert(t, cache.Get(fontA, arabic, nil) != plan)
+	tu.Assert(t, cache.Get(fontA, latin, noLiga) != plan)
+	tu.Assert(t, cache.Get(fontA, latin, noLiga) == cache.Get(fontA, latin, noLiga))
+	tu.Assert(t, cache.Get(fontA, latin, noLigaRanged) != cache.Get(fontA, latin, noLiga))
+	tu.Assert(t, cache.Get(fontB, latin, nil) != plan)
+
+	// plans only depend on the font, not on the face settings
+	tu.Assert(t, cache.Get(NewFont(font.NewFace(ftA)), latin, nil) == plan)
+
+	// bounded cache
+	cache = ShapePlanCache{MaxFonts: 1}
+	plan = cache.Get(fontA, latin, nil)
+	tu.Assert(t, cache.Get(fontA, latin, nil) == plan)
+	cache.Get(fontB, latin, nil)
+	tu.Assert(t, cache.Get(fontA, latin, nil) != plan)
+}
+
+func TestShapePlanExecute(t *testing.T) {
+	ft := NewFont(font.NewFace(openFontFileTT(t, "common/DejaVuSans.ttf")))
+	props := SegmentProperties{Direction: LeftToRight, Script: language.Latin, Language: language.NewLanguage("en")}
+	noLiga := []Feature{{Tag: ot.MustNewTag("liga"), Value: 0, Start: FeatureGlobalStart, End: FeatureGlobalEnd}}
+
+	for _, features := range [][]Feature{nil, noLiga} {
+		plan := NewShapePlan(ft, props, features)
+		for _, text := range []string{"office", "Hello world", "ffi"} {
+			buffer := NewBuffer()
+			buffer.AddRunes([]rune(text), 0, -1)
+			buffer.Props = props
+			buffer.Shape(ft, features)
+			exp := buffer.Serialize(ft, SerializeFormatText, SerializeGlyphFlags)
+
+			tu.Assert(t, shapeWithPlan(plan, ft, text, features) == exp)
+		}
+	}
+	// features are compiled into the plan
+	tu.Assert(t, shapeWithPlan(NewShapePlan(ft, props, nil), ft, "ffi", nil) != shapeWithPlan(NewShapePlan(ft, props, noLiga), ft, "ffi", noLiga))
+}
+
+func TestShapePlanConcurrent(t *testing.T) {
+	ft := openFontFileTT(t, "common/NotoSansArabic.ttf")
+	props := SegmentProperties{Direction: RightToLeft, Script: language.Arabic, Language: language.NewLanguage("ar")}
+	texts := []string{"لا بسم", "الله", "مرحبا بالعالم"}
+
+	var cache ShapePlanCache
+	expected := make([]string, len(texts))
+	for i, text := range texts {
+		ref := NewFont(font.NewFace(ft))
+		expected[i] = shapeWithPlan(NewShapePlan(ref, props, nil), ref, text, nil)
+	}
+
+	var wg sync.WaitGroup
+	for j := 0; j < 8; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hbFont := NewFont(font.NewFace(ft))
+			buffer := NewBuffer()
+			buffer.PlanCache = &cache
+			for k := 0; k < 10; k++ {
+				for i, text := range texts {
+					buffer.Clear()
+					buffer.AddRunes([]rune(text), 0, -1)
+					buffer.Props = props
+					buffer.Shape(hbFont, nil)
+					if got := buffer.Serialize(hbFont, SerializeFormatText, SerializeGlyphFlags); got != expected[i] {
+						t.Errorf("unexpected output %s", got)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package hyphenation finds the hyphenation points of words, using
+// the algorithm described by F. M. Liang in "Word Hy-phen-a-tion by Com-put-er",
+// and the pattern files used by TeX.
+//
+// Patterns for many languages are distributed by the hyph-utf8 project
+// (https://github.com/hyphenation/tex-hyphen), either as TeX files
+// (hyph-xx.tex), or as plain text files (hyph-xx.pat.txt and hyph-xx.hyp.txt).
+package hyphenation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-text/typesetting/language"
+)
+
+// Hyphenator stores the hyphenation patterns and exceptions for one language.
+//
+// The zero value has no patterns and never hyphenates : use [Parse],
+// [Hyphenator.AddPattern] and [Hyphenator.AddException] to fill it.
+type Hyphenator struct {
+	// patterns maps the letters of a pattern to its values,
+	// which have length len(letters) + 1
+	patterns map[string][]uint8
+	// exceptions maps a (lower case) word to its hyphenation points
+	exceptions map[string][]int
+	// maxPatternLength is the maximum number of letters in a pattern
+	maxPatternLength int
+
+	// LeftMin and RightMin are the minimum number of letters
+	// kept before the first hyphen and after the last hyphen.
+	// Values smaller than 1 are interpreted as 1.
+	LeftMin, RightMin int
+}
+
+// NewHyphenator returns an empty [Hyphenator] using
+// the TeX defaults for LeftMin (2) and RightMin (3).
+func NewHyphenator() *Hyphenator {
+	return &Hyphenator{LeftMin: 2, RightMin: 3}
+}
+
+// AddPattern adds a Liang pattern, like "hen5at" or ".hy3p", where the digits
+// are the priority of the hyphenation points between the letters, and '.'
+// marks the start or the end of a word.
+func (h *Hyphenator) AddPattern(pattern string) error {
+	var (
+		letters []rune
+		values  = []uint8{0}
+	)
+	for _, r := range pattern {
+		if '0' <= r && r <= '9' {
+			values[len(values)-1] = uint8(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		values = append(values, 0)
+	}
+	if len(letters) == 0 {
+		return fmt.Errorf("invalid hyphenation pattern %q", pattern)
+	}
+	if h.patterns == nil {
+		h.patterns = make(map[string][]uint8)
+	}
+	h.patterns[string(letters)] = values
+	if len(letters) > h.maxPatternLength {
+		h.maxPatternLength = len(letters)
+	}
+	return nil
+}
+
+// AddException adds a word whose hyphenation points are given
+// explicitly with '-', like "ta-ble", overriding the patterns.
+func (h *Hyphenator) AddException(word string) {
+	var (
+		letters []rune
+		points  []int
+	)
+	for _, r := range word {
+		if r == '-' {
+			points = append(points, len(letters))
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+	}
+	if h.exceptions == nil {
+		h.exceptions = make(map[string][]int)
+	}
+	h.exceptions[string(letters)] = points
+}
+
+// Hyphenate returns the positions where [word] may be hyphenated,
+// in increasing order. A position i means that a hyphen may
+// be inserted between word[i-1] and word[i].
+func (h *Hyphenator) Hyphenate(word []rune) []int {
+	leftMin, rightMin := h.LeftMin, h.RightMin
+	if leftMin < 1 {
+		leftMin = 1
+	}
+	if rightMin < 1 {
+		rightMin = 1
+	}
+	if len(word) < leftMin+rightMin {
+		return nil
+	}
+
+	lower := make([]rune, len(word)+2)
+	lower[0], lower[len(lower)-1] = '.', '.'
+	for i, r := range word {
+		lower[i+1] = unicode.ToLower(r)
+	}
+
+	var points []int
+	if exception, ok := h.exceptions[string(lower[1:len(lower)-1])]; ok {
+		for _, p := range exception {
+			if leftMin <= p && p <= len(word)-rightMin {
+				points = append(points, p)
+			}
+		}
+		return points
+	}
+
+	// values[i] is the priority of the position before lower[i]
+	values := make([]uint8, len(lower)+1)
+	for start := range lower {
+		for end := start + 1; end <= len(lower) && end-start <= h.maxPatternLength; end++ {
+			pattern, ok := h.patterns[string(lower[start:end])]
+			if !ok {
+				continue
+			}
+			for i, v := range pattern {
+				if v > values[start+i] {
+					values[start+i] = v
+				}
+			}
+		}
+	}
+	// the position before word[i] is the one before lower[i+1]
+	for i := leftMin; i <= len(word)-rightMin; i++ {
+		if values[i+1]%2 == 1 {
+			points = append(points, i)
+		}
+	}
+	return points
+}
+
+// Parse reads hyphenation patterns, either from a TeX file (using the \patterns{...}
+// and \hyphenation{...} commands, and optionally \lefthyphenmin and \righthyphenmin),
+// or from a plain text file with one pattern per line.
+// Comments starting with '%' are ignored.
+func Parse(r io.Reader) (*Hyphenator, error) {
+	out := NewHyphenator()
+	const (
+		plain = iota
+		patterns
+		exceptions
+		other
+	)
+	mode := plain
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '%'); i != -1 {
+			line = line[:i]
+		}
+		for _, token := range strings.Fields(line) {
+			switch {
+			case strings.HasPrefix(token, `\patterns{`):
+				mode, token = patterns, strings.TrimPrefix(token, `\patterns{`)
+			case strings.HasPrefix(token, `\hyphenation{`):
+				mode, token = exceptions, strings.TrimPrefix(token, `\hyphenation{`)
+			case strings.HasPrefix(token, `\lefthyphenmin`), strings.HasPrefix(token, `\righthyphenmin`):
+				_, value, _ := strings.Cut(token, "=")
+				v, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid hyphenation file: %s", token)
+				}
+				if strings.HasPrefix(token, `\left`) {
+					out.LeftMin = v
+				} else {
+					out.RightMin = v
+				}
+				continue
+			case strings.HasPrefix(token, `\`):
+				// unsupported command, like \message{...}
+				if mode == plain {
+					mode = other
+				}
+				continue
+			}
+
+			closing := strings.HasSuffix(token, "}")
+			token = strings.TrimSuffix(token, "}")
+			if token != "" {
+				switch mode {
+				case plain, patterns:
+					if err := out.AddPattern(token); err != nil {
+						return nil, err
+					}
+				case exceptions:
+					out.AddException(token)
+				}
+			}
+			if closing {
+				mode = other
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out.patterns) == 0 && len(out.exceptions) == 0 {
+		return nil, errors.New("invalid hyphenation file: no patterns found")
+	}
+	return out, nil
+}
+
+// Dictionaries maps languages to their [Hyphenator].
+type Dictionaries map[language.Language]*Hyphenator
+
+// Lookup returns the [Hyphenator] for [lang], using simple
+// inheritance for regional variants (so that "de-ch" may use the "de" patterns),
+// or nil if no patterns are available.
+func (d Dictionaries) Lookup(lang language.Language) *Hyphenator {
+	for _, l := range lang.SimpleInheritance() {
+		if h, ok := d[l]; ok {
+			return h
+		}
+	}
+	return nil
+}
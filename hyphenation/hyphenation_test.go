@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package hyphenation
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+// hyphenated returns [word] with the hyphenation points marked by '-'
+func hyphenated(h *Hyphenator, word string) string {
+	runes := []rune(word)
+	var sb strings.Builder
+	last := 0
+	for _, p := range h.Hyphenate(runes) {
+		sb.WriteString(string(runes[last:p]))
+		sb.WriteByte('-')
+		last = p
+	}
+	sb.WriteString(string(runes[last:]))
+	return sb.String()
+}
+
+func TestParseTeX(t *testing.T) {
+	f, err := os.Open("testdata/hyph-test.tex")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+
+	h, err := Parse(f)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(h.patterns) == 12)
+	tu.Assert(t, h.LeftMin == 2 && h.RightMin == 3)
+
+	for word, expected := range map[string]string{
+		"hyphenation":   "hy-phen-ation",
+		"Hyphenation":   "Hy-phen-ation",
+		"concatenation": "con-ca-te-na-tion",
+		"table":         "ta-ble",  // exception
+		"project":       "project", // exception without points
+		"hen":           "hen",
+	} {
+		tu.AssertC(t, hyphenated(h, word) == expected, hyphenated(h, word))
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	h, err := Parse(strings.NewReader("hy3ph\nhe2n\nhena4\n\nhen5at\n1na n2at 1tio 2io o2n % comment"))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, hyphenated(h, "hyphenation") == "hy-phen-ation")
+
+	_, err = Parse(strings.NewReader("% only comments"))
+	tu.Assert(t, err != nil)
+	_, err = Parse(strings.NewReader(`\lefthyphenmin=a`))
+	tu.Assert(t, err != nil)
+}
+
+func TestMinLengths(t *testing.T) {
+	h := NewHyphenator()
+	for _, p := range []string{"a1b", "b1c", "c1d", "d1e"} {
+		tu.AssertNoErr(t, h.AddPattern(p))
+	}
+	tu.Assert(t, reflect.DeepEqual(h.Hyphenate([]rune("abcde")), []int{2}))
+
+	h.LeftMin, h.RightMin = 1, 1
+	tu.Assert(t, reflect.DeepEqual(h.Hyphenate([]rune("abcde")), []int{1, 2, 3, 4}))
+
+	h.LeftMin, h.RightMin = 3, 3
+	tu.Assert(t, len(h.Hyphenate([]rune("abcde"))) == 0)
+
+	tu.Assert(t, h.AddPattern("123") != nil)
+}
+
+func TestDictionaries(t *testing.T) {
+	de, fi := NewHyphenator(), NewHyphenator()
+	dicts := Dictionaries{"de": de, "fi": fi}
+	tu.Assert(t, dicts.Lookup(language.NewLanguage("de-CH")) == de)
+	tu.Assert(t, dicts.Lookup(language.NewLanguage("fi")) == fi)
+	tu.Assert(t, dicts.Lookup(language.NewLanguage("fr")) == nil)
+}
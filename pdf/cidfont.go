@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package pdf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
+
+// Flags are the font characteristics stored in the /Flags entry
+// of a font descriptor.
+type Flags uint32
+
+const (
+	FixedPitch  Flags = 1 << 0
+	Serif       Flags = 1 << 1
+	Symbolic    Flags = 1 << 2
+	Script      Flags = 1 << 3
+	Nonsymbolic Flags = 1 << 5
+	Italic      Flags = 1 << 6
+	AllCap      Flags = 1 << 16
+	SmallCap    Flags = 1 << 17
+	ForceBold   Flags = 1 << 18
+)
+
+// FontDescriptor stores the entries of a font descriptor dictionary,
+// expressed in glyph space.
+type FontDescriptor struct {
+	FontName    string
+	Flags       Flags
+	FontBBox    [4]int // llx, lly, urx, ury
+	ItalicAngle float32
+	Ascent      int
+	Descent     int // negative for descent below the baseline
+	Leading     int
+	CapHeight   int
+	XHeight     int
+	StemV       int // estimated from the font weight
+	AvgWidth    int
+	MaxWidth    int
+}
+
+// Descriptor returns the font descriptor for the font.
+// Note that /FontFile entry is provided by [Font.FontFile].
+func (f *Font) Descriptor() FontDescriptor {
+	out := FontDescriptor{FontName: f.PostScriptName()}
+
+	head, _, _ := font.LoadHeadTable(f.ld, nil)
+	out.FontBBox = [4]int{
+		f.toPDFUnits(float32(head.XMin)), f.toPDFUnits(float32(head.YMin)),
+		f.toPDFUnits(float32(head.XMax)), f.toPDFUnits(float32(head.YMax)),
+	}
+
+	raw, _ := f.ld.RawTable(ot.MustNewTag("post"))
+	post, _, _ := tables.ParsePost(raw)
+	out.ItalicAngle = post.ItalicAngle()
+
+	if extents, ok := f.face.FontHExtents(); ok {
+		out.Ascent = f.toPDFUnits(extents.Ascender)
+		out.Descent = f.toPDFUnits(extents.Descender)
+		out.Leading = f.toPDFUnits(extents.LineGap)
+	} else {
+		out.Ascent, out.Descent = out.FontBBox[3], out.FontBBox[1]
+	}
+	out.CapHeight = f.toPDFUnits(f.face.LineMetric(font.CapHeight))
+	if out.CapHeight == 0 { // required entry
+		out.CapHeight = out.Ascent
+	}
+	out.XHeight = f.toPDFUnits(f.face.LineMetric(font.XHeight))
+
+	raw, _ = f.ld.RawTable(ot.MustNewTag("OS/2"))
+	os2, _, err := tables.ParseOs2(raw)
+	if err == nil {
+		out.AvgWidth = f.toPDFUnits(float32(os2.XAvgCharWidth))
+	}
+	raw, _ = f.ld.RawTable(ot.MustNewTag("hhea"))
+	if hhea, _, err := tables.ParseHhea(raw); err == nil {
+		out.MaxWidth = f.toPDFUnits(float32(hhea.AdvanceMax))
+	}
+
+	desc := f.face.Describe()
+	// there is no stem information in OpenType fonts:
+	// use a common heuristic based on the weight
+	weight := desc.Aspect.Weight
+	if weight == 0 {
+		weight = font.WeightNormal
+	}
+	out.StemV = int(10 + 220*(weight-50)/900)
+
+	if f.face.IsMonospace() {
+		out.Flags |= FixedPitch
+	}
+	if desc.Aspect.Style == font.StyleItalic || out.ItalicAngle != 0 {
+		out.Flags |= Italic
+	}
+	// the glyphs are accessed by CIDs, not through a standard
+	// latin character set
+	out.Flags |= Symbolic
+
+	return out
+}
+
+// CIDFont stores the entries of a CIDFont dictionary,
+// using /Registry (Adobe) /Ordering (Identity) /Supplement 0 as CIDSystemInfo.
+type CIDFont struct {
+	Subtype  string // CIDFontType2 for TrueType fonts, CIDFontType0 for CFF fonts
+	BaseFont string
+	DW       int    // default width
+	W        Widths // widths of the used glyphs
+
+	// CIDToGIDMap is the content of the /CIDToGIDMap stream,
+	// mapping the used CIDs to glyphs (two bytes per CID).
+	// It is only used for CIDFontType2 fonts, and is nil otherwise.
+	CIDToGIDMap []byte
+}
+
+// CIDFont returns the CIDFont dictionary for the used glyphs.
+func (f *Font) CIDFont() CIDFont {
+	out := CIDFont{BaseFont: f.PostScriptName(), DW: 1000}
+
+	glyphs := f.usedGlyphs()
+	out.W = f.widths(glyphs)
+
+	if f.cff != nil {
+		out.Subtype = "CIDFontType0"
+	} else {
+		out.Subtype = "CIDFontType2"
+		var maxCID uint16
+		if len(glyphs) != 0 {
+			maxCID = f.cid(glyphs[len(glyphs)-1])
+		}
+		out.CIDToGIDMap = make([]byte, 2*(int(maxCID)+1))
+		for _, gid := range glyphs {
+			binary.BigEndian.PutUint16(out.CIDToGIDMap[2*int(f.cid(gid)):], uint16(gid))
+		}
+	}
+	return out
+}
+
+// WidthRange stores the widths of consecutive CIDs.
+type WidthRange struct {
+	First  uint16 // first CID
+	Widths []int
+}
+
+// Widths is the content of a /W array.
+type Widths []WidthRange
+
+// String returns the PDF representation of the array,
+// such as [1 [500 600] 10 [300]]
+func (ws Widths) String() string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, w := range ws {
+		if i != 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%d [", w.First)
+		for j, width := range w.Widths {
+			if j != 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%d", width)
+		}
+		sb.WriteByte(']')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// widths groups the widths of [glyphs], sorted by CIDs
+func (f *Font) widths(glyphs []font.GID) Widths {
+	var out Widths
+	for _, gid := range glyphs {
+		cid := f.cid(gid)
+		width := f.toPDFUnits(f.face.HorizontalAdvance(gid))
+		if L := len(out); L != 0 && int(out[L-1].First)+len(out[L-1].Widths) == int(cid) {
+			out[L-1].Widths = append(out[L-1].Widths, width)
+		} else {
+			out = append(out, WidthRange{First: cid, Widths: []int{width}})
+		}
+	}
+	return out
+}
+
+// FontFile stores an embedded font program.
+type FontFile struct {
+	// Key is the font descriptor entry referencing
+	// the stream : FontFile2 or FontFile3
+	Key string
+	// Subtype is the /Subtype entry of the stream, or an empty string
+	// if not required.
+	Subtype string
+	// Content is the (uncompressed) content of the stream.
+	Content []byte
+}
+
+// tables required in a TrueType font program embedded in PDF
+var trueTypeTables = [...]string{"OS/2", "cmap", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "post", "prep"}
+
+// FontFile returns the font program to embed.
+//
+// For TrueType fonts, a font file is rebuilt with the tables
+// used by PDF readers, so that collections or WOFF files are also supported.
+// For OpenType fonts with CFF outlines, the raw 'CFF ' table is returned.
+//
+// The font is not subsetted.
+func (f *Font) FontFile() (FontFile, error) {
+	if f.cff != nil {
+		raw, err := f.ld.RawTable(ot.MustNewTag("CFF "))
+		if err != nil {
+			return FontFile{}, err
+		}
+		return FontFile{Key: "FontFile3", Subtype: "CIDFontType0C", Content: raw}, nil
+	}
+
+	var ts []ot.Table // sorted by tag
+	for _, tag := range trueTypeTables {
+		tag := ot.MustNewTag(tag)
+		if !f.ld.HasTable(tag) {
+			continue
+		}
+		raw, err := f.ld.RawTable(tag)
+		if err != nil {
+			return FontFile{}, err
+		}
+		ts = append(ts, ot.Table{Tag: tag, Content: raw})
+	}
+	return FontFile{Key: "FontFile2", Content: ot.WriteTTF(ts)}, nil
+}
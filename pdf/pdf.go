@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package pdf provides the data required to embed fonts in PDF documents,
+// as composite (Type0) fonts using the Identity-H encoding.
+//
+// A [Font] records the glyphs used in the document (typically from [shaping.Output]s),
+// and then provides the content of the PDF objects:
+//   - the font descriptor, with its metrics and flags, see [Font.Descriptor]
+//   - the CIDFont dictionary, with its /W array and CIDToGIDMap, see [Font.CIDFont]
+//   - the ToUnicode CMap, built from the shaping clusters, see [Font.ToUnicode]
+//   - the embedded font program, see [Font.FontFile]
+//
+// Serializing these objects to the PDF file format is left to the caller.
+//
+// All the metrics are expressed in PDF glyph space (1000 units per em).
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/cff"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/shaping"
+)
+
+// Font gathers the glyphs used in a document, with their Unicode text,
+// and provides the PDF objects required to embed it.
+//
+// The code used in PDF content streams for a glyph is its CID, written with
+// two bytes (see [Font.Encode]). The CID is the glyph ID, except for CID-keyed
+// CFF fonts, where it is given by the CFF charset.
+type Font struct {
+	ld   *ot.Loader
+	face *font.Face
+
+	cff *cff.CFF // nil for TrueType fonts
+
+	used map[font.GID][]rune // the text of each glyph, possibly empty
+}
+
+// NewFont returns a [Font] with no glyphs used.
+// [ld] provides the raw tables of the font, and [ft] must have been
+// loaded from it, typically using [font.NewFont].
+//
+// An error is returned for fonts which can't be embedded,
+// such as bitmap only fonts, or CFF2 fonts.
+func NewFont(ld *ot.Loader, ft *font.Font) (*Font, error) {
+	out := &Font{ld: ld, face: font.NewFace(ft), used: make(map[font.GID][]rune)}
+	switch {
+	case ld.HasTable(ot.MustNewTag("glyf")):
+	case ld.HasTable(ot.MustNewTag("CFF ")):
+		raw, err := ld.RawTable(ot.MustNewTag("CFF "))
+		if err != nil {
+			return nil, err
+		}
+		out.cff, err = cff.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+	case ld.HasTable(ot.MustNewTag("CFF2")):
+		return nil, errors.New("CFF2 fonts are not supported in PDF")
+	default:
+		return nil, errors.New("font with no outlines")
+	}
+	return out, nil
+}
+
+// AddRun marks the glyphs of [run] as used.
+// [text] is the input of the shaping step which produced [run], and is
+// used to build the ToUnicode mapping: the text of a cluster is attributed to its first glyph,
+// so that ligatures map to all their runes.
+//
+// When a glyph is used with different texts, the first one is used.
+func (f *Font) AddRun(text []rune, run shaping.Output) {
+	for i := 0; i < len(run.Glyphs); {
+		g := run.Glyphs[i]
+		clusterSize := g.GlyphCount
+		if clusterSize <= 0 {
+			clusterSize = 1
+		}
+		// the first glyph in the cluster, in logical order
+		first := i
+		if run.Direction.Progression() == di.TowardTopLeft {
+			first = i + clusterSize - 1
+		}
+		for j := i; j < i+clusterSize && j < len(run.Glyphs); j++ {
+			var runes []rune
+			if j == first && g.ClusterIndex >= 0 && g.ClusterIndex+g.RuneCount <= len(text) {
+				runes = text[g.ClusterIndex : g.ClusterIndex+g.RuneCount]
+			}
+			f.addGlyph(run.Glyphs[j].GlyphID, runes)
+		}
+		i += clusterSize
+	}
+}
+
+// AddGlyphs marks the given glyphs as used,
+// without text information.
+func (f *Font) AddGlyphs(glyphs ...font.GID) {
+	for _, gid := range glyphs {
+		f.addGlyph(gid, nil)
+	}
+}
+
+func (f *Font) addGlyph(gid font.GID, runes []rune) {
+	if current, ok := f.used[gid]; ok && len(current) != 0 {
+		return
+	}
+	f.used[gid] = append([]rune(nil), runes...)
+}
+
+// Encode returns the string to use in PDF content streams (such as with the 'Tj' operator)
+// to display [glyphs].
+// [glyphs] are also marked as used, without text information.
+func (f *Font) Encode(glyphs []shaping.Glyph) []byte {
+	out := make([]byte, 2*len(glyphs))
+	for i, g := range glyphs {
+		f.addGlyph(g.GlyphID, nil)
+		binary.BigEndian.PutUint16(out[2*i:], f.cid(g.GlyphID))
+	}
+	return out
+}
+
+// cid returns the character identifier used for [gid]
+func (f *Font) cid(gid font.GID) uint16 {
+	if f.cff != nil {
+		if cid, ok := f.cff.GlyphCID(gid); ok {
+			return cid
+		}
+	}
+	return uint16(gid)
+}
+
+// usedGlyphs returns the used glyphs, sorted by CID
+func (f *Font) usedGlyphs() []font.GID {
+	out := make([]font.GID, 0, len(f.used))
+	for gid := range f.used {
+		out = append(out, gid)
+	}
+	sort.Slice(out, func(i, j int) bool { return f.cid(out[i]) < f.cid(out[j]) })
+	return out
+}
+
+// toPDFUnits converts from font units to glyph space units
+func (f *Font) toPDFUnits(v float32) int {
+	scaled := float64(v) * 1000 / float64(f.face.Upem())
+	if scaled < 0 {
+		return -int(-scaled + 0.5)
+	}
+	return int(scaled + 0.5)
+}
+
+// PostScriptName returns the font name to use for the BaseFont and FontName
+// entries, without the subset tag.
+func (f *Font) PostScriptName() string {
+	raw, _ := f.ld.RawTable(ot.MustNewTag("name"))
+	names, _, _ := tables.ParseName(raw)
+	const namePostscript tables.NameID = 6
+	name := names.Name(namePostscript)
+	if name == "" {
+		name = names.Name(4) // full name
+	}
+
+	// remove the characters not allowed in PDF names
+	out := make([]rune, 0, len(name))
+	for _, r := range name {
+		if r <= ' ' || r > '~' {
+			continue
+		}
+		switch r {
+		case '[', ']', '(', ')', '{', '}', '<', '>', '/', '%', '#':
+			continue
+		}
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return "Unknown"
+	}
+	return string(out)
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func loadFont(t *testing.T, filename string) (*Font, *font.Face) {
+	t.Helper()
+	file, err := td.Files.ReadFile(filename)
+	tu.AssertNoErr(t, err)
+	ld, err := ot.NewLoader(bytes.NewReader(file))
+	tu.AssertNoErr(t, err)
+	ft, err := font.NewFont(ld)
+	tu.AssertNoErr(t, err)
+	out, err := NewFont(ld, ft)
+	tu.AssertNoErr(t, err)
+	return out, font.NewFace(ft)
+}
+
+func shape(face *font.Face, text []rune, dir di.Direction, script language.Script) shaping.Output {
+	return (&shaping.HarfbuzzShaper{}).Shape(shaping.Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: dir,
+		Face:      face,
+		Size:      fixed.I(12),
+		Script:    script,
+	})
+}
+
+func TestTrueType(t *testing.T) {
+	f, face := loadFont(t, "common/DejaVuSans.ttf")
+	text := []rune("office ffi")
+	run := shape(face, text, di.DirectionLTR, language.Latin)
+	tu.Assert(t, len(run.Glyphs) < len(text)) // ligatures
+	f.AddRun(text, run)
+
+	desc := f.Descriptor()
+	tu.Assert(t, desc.FontName == "DejaVuSans")
+	tu.Assert(t, desc.Flags&Symbolic != 0 && desc.Flags&Italic == 0 && desc.Flags&FixedPitch == 0)
+	tu.Assert(t, desc.Ascent > 0 && desc.Descent < 0 && desc.CapHeight > 0)
+	tu.Assert(t, desc.FontBBox[0] < desc.FontBBox[2] && desc.FontBBox[1] < desc.FontBBox[3])
+	tu.Assert(t, desc.StemV > 0)
+
+	cid := f.CIDFont()
+	tu.Assert(t, cid.Subtype == "CIDFontType2")
+	var nbWidths int
+	for _, w := range cid.W {
+		nbWidths += len(w.Widths)
+	}
+	tu.Assert(t, nbWidths == len(f.used))
+	tu.Assert(t, strings.HasPrefix(cid.W.String(), "[3 [318]"))
+	for _, g := range run.Glyphs {
+		tu.Assert(t, binary.BigEndian.Uint16(cid.CIDToGIDMap[2*g.GlyphID:]) == uint16(g.GlyphID))
+	}
+
+	// the ligature maps to all its runes
+	cmap := string(f.ToUnicode())
+	tu.Assert(t, strings.Contains(cmap, "<006600660069>"))
+	tu.Assert(t, strings.Contains(cmap, "<0020>"))
+	tu.Assert(t, strings.HasSuffix(cmap, "endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n"))
+
+	ff, err := f.FontFile()
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, ff.Key == "FontFile2")
+	// the font file is valid
+	ld, err := ot.NewLoader(bytes.NewReader(ff.Content))
+	tu.AssertNoErr(t, err)
+	_, err = font.NewFont(ld)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, !ld.HasTable(ot.MustNewTag("GSUB")))
+}
+
+func TestCFF(t *testing.T) {
+	f, face := loadFont(t, "common/Raleway-v4020-Regular.otf")
+	text := []rune("Hello")
+	run := shape(face, text, di.DirectionLTR, language.Latin)
+	f.AddRun(text, run)
+
+	tu.Assert(t, f.CIDFont().Subtype == "CIDFontType0")
+	tu.Assert(t, f.CIDFont().CIDToGIDMap == nil)
+
+	ff, err := f.FontFile()
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, ff.Key == "FontFile3" && ff.Subtype == "CIDFontType0C")
+	tu.Assert(t, bytes.HasPrefix(ff.Content, []byte{1, 0, 4})) // CFF header
+
+	// name-keyed: CIDs are glyph IDs
+	encoded := f.Encode(run.Glyphs)
+	tu.Assert(t, len(encoded) == 2*len(run.Glyphs))
+	tu.Assert(t, binary.BigEndian.Uint16(encoded) == uint16(run.Glyphs[0].GlyphID))
+}
+
+func TestToUnicodeRTL(t *testing.T) {
+	f, face := loadFont(t, "common/NotoSansArabic.ttf")
+	text := []rune("لا")
+	run := shape(face, text, di.DirectionRTL, language.Arabic)
+	tu.Assert(t, len(run.Glyphs) == 1) // lam-alef ligature
+	f.AddRun(text, run)
+	tu.Assert(t, strings.Contains(string(f.ToUnicode()), "<06440627>"))
+}
+
+func TestToUnicodeSurrogates(t *testing.T) {
+	f, _ := loadFont(t, "common/DejaVuSans.ttf")
+	f.AddRun([]rune("😀"), shaping.Output{Glyphs: []shaping.Glyph{{GlyphID: 10, RuneCount: 1, GlyphCount: 1}}})
+	tu.Assert(t, strings.Contains(string(f.ToUnicode()), "<000A> <D83DDE00>"))
+}
+
+func TestWidths(t *testing.T) {
+	ws := Widths{{First: 1, Widths: []int{500, 600}}, {First: 10, Widths: []int{300}}}
+	tu.Assert(t, ws.String() == "[1 [500 600] 10 [300]]")
+}
+
+func TestDescriptorItalic(t *testing.T) {
+	f, _ := loadFont(t, "common/Roboto-BoldItalic.ttf")
+	desc := f.Descriptor()
+	tu.Assert(t, desc.ItalicAngle < 0)
+	tu.Assert(t, desc.Flags&Italic != 0)
+	tu.Assert(t, desc.StemV > 150) // bold
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+)
+
+// the maximum number of entries in a beginbfchar block
+const maxBfChar = 100
+
+const toUnicodeHeader = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+`
+
+const toUnicodeFooter = `endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end
+`
+
+// ToUnicode returns the content of the /ToUnicode CMap stream,
+// mapping the CIDs of the used glyphs to their text, as registered by [Font.AddRun].
+// Glyphs without text (such as the additional glyphs of a decomposed cluster)
+// are not included.
+func (f *Font) ToUnicode() []byte {
+	type entry struct {
+		cid  uint16
+		text []rune
+	}
+	var entries []entry
+	for _, gid := range f.usedGlyphs() {
+		if text := f.used[gid]; len(text) != 0 {
+			entries = append(entries, entry{f.cid(gid), text})
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(toUnicodeHeader)
+	for start := 0; start < len(entries); start += maxBfChar {
+		end := start + maxBfChar
+		if end > len(entries) {
+			end = len(entries)
+		}
+		fmt.Fprintf(&out, "%d beginbfchar\n", end-start)
+		for _, e := range entries[start:end] {
+			fmt.Fprintf(&out, "<%04X> <", e.cid)
+			for _, u := range utf16.Encode(e.text) {
+				fmt.Fprintf(&out, "%04X", u)
+			}
+			out.WriteString(">\n")
+		}
+		out.WriteString("endbfchar\n")
+	}
+	out.WriteString(toUnicodeFooter)
+	return out.Bytes()
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package sdf
+
+import "math"
+
+// isCorner returns true if the turn between the directions [a] and [b]
+// is sharp, as defined by [crossThreshold], the sinus of the angle threshold.
+func isCorner(a, b point, crossThreshold float64) bool {
+	a, b = a.normalized(), b.normalized()
+	return a.dot(b) <= 0 || math.Abs(a.cross(b)) > crossThreshold
+}
+
+// colorEdges assigns colors to the edges of the contours, so that
+// the two edges meeting at a corner only share one channel.
+// The contours are modified in place, and returned since contours with one
+// corner are split to use three colors.
+//
+// This is the simple strategy described in Viktor Chlumský's thesis,
+// "Shape Decomposition for Multi-channel Distance Fields", and implemented
+// in msdfgen.
+func colorEdges(contours [][]edge, angleThreshold float64) [][]edge {
+	crossThreshold := math.Sin(angleThreshold)
+	colors := [3]edgeColor{cyan, magenta, yellow}
+	for ci, contour := range contours {
+		if len(contour) == 0 {
+			continue
+		}
+		// identify the corners
+		var corners []int
+		prevDir := contour[len(contour)-1].direction(1)
+		for i := range contour {
+			if isCorner(prevDir, contour[i].direction(0), crossThreshold) {
+				corners = append(corners, i)
+			}
+			prevDir = contour[i].direction(1)
+		}
+
+		switch len(corners) {
+		case 0: // smooth contour
+			for i := range contour {
+				contour[i].color = white
+			}
+		case 1: // "teardrop" : use three colors, starting at the corner
+			if len(contour) < 3 {
+				var split []edge
+				for i := range contour {
+					parts := contour[i].splitInThirds()
+					split = append(split, parts[:]...)
+				}
+				// the corner is preserved, since it is at the start of an original edge
+				corners[0] *= 3
+				contour = split
+				contours[ci] = contour
+			}
+			thirds := [3]edgeColor{magenta, white, yellow}
+			n := len(contour)
+			for k := 0; k < n; k++ {
+				i := (corners[0] + k) % n
+				contour[i].color = thirds[3*k/n]
+			}
+		default:
+			// switch color at each corner; the last spline
+			// must also differ from the first one
+			nbCorners := len(corners)
+			start := corners[0]
+			spline := 0
+			n := len(contour)
+			for k := 0; k < n; k++ {
+				i := (start + k) % n
+				if spline+1 < nbCorners && corners[spline+1] == i {
+					spline++
+				}
+				c := colors[spline%3]
+				if spline == nbCorners-1 && nbCorners%3 == 1 {
+					c = colors[1]
+				}
+				contour[i].color = c
+			}
+		}
+	}
+	return contours
+}
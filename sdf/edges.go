@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package sdf
+
+import "math"
+
+type point struct{ x, y float64 }
+
+func (p point) add(q point) point             { return point{p.x + q.x, p.y + q.y} }
+func (p point) sub(q point) point             { return point{p.x - q.x, p.y - q.y} }
+func (p point) scale(s float64) point         { return point{p.x * s, p.y * s} }
+func (p point) dot(q point) float64           { return p.x*q.x + p.y*q.y }
+func (p point) cross(q point) float64         { return p.x*q.y - p.y*q.x }
+func (p point) length() float64               { return math.Hypot(p.x, p.y) }
+func (p point) lerp(q point, t float64) point { return p.add(q.sub(p).scale(t)) }
+
+func (p point) normalized() point {
+	l := p.length()
+	if l == 0 {
+		return point{}
+	}
+	return point{p.x / l, p.y / l}
+}
+
+// edgeColor is a bit set of the RGB channels used by an edge
+type edgeColor uint8
+
+const (
+	red edgeColor = 1 << iota
+	green
+	blue
+
+	yellow  = red | green
+	magenta = red | blue
+	cyan    = green | blue
+	white   = red | green | blue
+)
+
+// edge is a line, a quadratic or a cubic Bézier curve
+type edge struct {
+	p     [4]point // only the first degree + 1 points are used
+	deg   int      // 1, 2 or 3
+	color edgeColor
+}
+
+func (e *edge) start() point { return e.p[0] }
+func (e *edge) end() point   { return e.p[e.deg] }
+
+// point returns the point at parameter t
+func (e *edge) point(t float64) point {
+	switch e.deg {
+	case 1:
+		return e.p[0].lerp(e.p[1], t)
+	case 2:
+		a, b := e.p[0].lerp(e.p[1], t), e.p[1].lerp(e.p[2], t)
+		return a.lerp(b, t)
+	default:
+		a, b, c := e.p[0].lerp(e.p[1], t), e.p[1].lerp(e.p[2], t), e.p[2].lerp(e.p[3], t)
+		ab, bc := a.lerp(b, t), b.lerp(c, t)
+		return ab.lerp(bc, t)
+	}
+}
+
+// direction returns the derivative at parameter t, falling back
+// to the chord for degenerate control points.
+func (e *edge) direction(t float64) point {
+	var d point
+	switch e.deg {
+	case 1:
+		d = e.p[1].sub(e.p[0])
+	case 2:
+		d = e.p[1].sub(e.p[0]).lerp(e.p[2].sub(e.p[1]), t).scale(2)
+	default:
+		a, b, c := e.p[1].sub(e.p[0]), e.p[2].sub(e.p[1]), e.p[3].sub(e.p[2])
+		d = a.lerp(b, t).lerp(b.lerp(c, t), t).scale(3)
+	}
+	if d.x == 0 && d.y == 0 {
+		return e.end().sub(e.start())
+	}
+	return d
+}
+
+// secondDerivative returns the second derivative at parameter t
+func (e *edge) secondDerivative(t float64) point {
+	switch e.deg {
+	case 1:
+		return point{}
+	case 2:
+		return e.p[2].sub(e.p[1].scale(2)).add(e.p[0]).scale(2)
+	default:
+		a := e.p[2].sub(e.p[1].scale(2)).add(e.p[0])
+		b := e.p[3].sub(e.p[2].scale(2)).add(e.p[1])
+		return a.lerp(b, t).scale(6)
+	}
+}
+
+// splitInThirds divides the edge in three parts, preserving its color
+func (e *edge) splitInThirds() [3]edge {
+	var out [3]edge
+	for i := range out {
+		t0, t1 := float64(i)/3, float64(i+1)/3
+		out[i] = e.sub(t0, t1)
+	}
+	return out
+}
+
+// sub returns the part of the curve between t0 and t1
+func (e *edge) sub(t0, t1 float64) edge {
+	out := edge{deg: e.deg, color: e.color}
+	p0, p3 := e.point(t0), e.point(t1)
+	d0, d1 := e.direction(t0).scale(t1-t0), e.direction(t1).scale(t1-t0)
+	switch e.deg {
+	case 1:
+		out.p[0], out.p[1] = p0, p3
+	case 2:
+		out.p[0], out.p[1], out.p[2] = p0, p0.add(d0.scale(0.5)), p3
+	default:
+		out.p[0], out.p[1], out.p[2], out.p[3] = p0, p0.add(d0.scale(1./3)), p3.sub(d1.scale(1./3)), p3
+	}
+	return out
+}
+
+// signedDistance stores the distance between a point and an edge,
+// with the information required to select the closest edge.
+type signedDistance struct {
+	distance float64 // signed, positive on the left of the edge
+	// dot is used to break ties between edges sharing an end point,
+	// and measures how far from orthogonal the closest point is.
+	dot float64
+}
+
+var infiniteDistance = signedDistance{distance: -math.MaxFloat64, dot: 1}
+
+func (sd signedDistance) less(other signedDistance) bool {
+	a, b := math.Abs(sd.distance), math.Abs(other.distance)
+	return a < b || (a == b && sd.dot < other.dot)
+}
+
+func nonZeroSign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// closestParam returns the parameter, clamped to [0, 1], of the point of the edge closest to [p].
+func (e *edge) closestParam(p point) float64 {
+	if e.deg == 1 {
+		ab := e.p[1].sub(e.p[0])
+		t := p.sub(e.p[0]).dot(ab) / ab.dot(ab)
+		return math.Max(0, math.Min(1, t))
+	}
+
+	// evaluate the end points, then refine starting points with Newton iterations
+	// on the derivative of the squared distance
+	const starts = 8
+	bestT, bestD := 0., p.sub(e.p[0]).dot(p.sub(e.p[0]))
+	if d := p.sub(e.end()).dot(p.sub(e.end())); d < bestD {
+		bestT, bestD = 1, d
+	}
+	for i := 0; i <= starts; i++ {
+		t := float64(i) / starts
+		for step := 0; step < 4; step++ {
+			qp := e.point(t).sub(p)
+			d1, d2 := e.direction(t), e.secondDerivative(t)
+			den := d1.dot(d1) + qp.dot(d2)
+			if den == 0 {
+				break
+			}
+			t -= qp.dot(d1) / den
+			if t < 0 || t > 1 {
+				break
+			}
+		}
+		if t < 0 || t > 1 {
+			continue
+		}
+		if d := p.sub(e.point(t)).dot(p.sub(e.point(t))); d < bestD {
+			bestT, bestD = t, d
+		}
+	}
+	return bestT
+}
+
+// signedDistance returns the signed distance from [p] to the edge,
+// and the parameter of the closest point.
+func (e *edge) signedDistance(p point) (signedDistance, float64) {
+	t := e.closestParam(p)
+	q := e.point(t)
+	dir := e.direction(t)
+	pq := p.sub(q)
+	d := pq.length()
+	sign := nonZeroSign(dir.cross(pq))
+	if t > 0 && t < 1 {
+		return signedDistance{sign * d, 0}, t
+	}
+	// at the end points, several edges may be at the same distance
+	return signedDistance{sign * d, math.Abs(dir.normalized().dot(pq.normalized()))}, t
+}
+
+// pseudoDistance extends the edge with its tangents beyond its end points,
+// which is required to preserve sharp corners with multiple channels.
+func (e *edge) pseudoDistance(p point, sd signedDistance, t float64) float64 {
+	var (
+		origin, dir point
+		beyond      bool
+	)
+	if t == 0 {
+		origin, dir = e.start(), e.direction(0).normalized()
+		beyond = p.sub(origin).dot(dir) < 0
+	} else if t == 1 {
+		origin, dir = e.end(), e.direction(1).normalized()
+		beyond = p.sub(origin).dot(dir) > 0
+	}
+	if beyond {
+		if pd := dir.cross(p.sub(origin)); math.Abs(pd) <= math.Abs(sd.distance) {
+			return pd
+		}
+	}
+	return sd.distance
+}
+
+// flatten appends points approximating the edge (excluding its start) to [out]
+func (e *edge) flatten(out []point) []point {
+	if e.deg == 1 {
+		return append(out, e.p[1])
+	}
+	const n = 16
+	for i := 1; i <= n; i++ {
+		out = append(out, e.point(float64(i)/n))
+	}
+	return out
+}
+
+// winding returns the non-zero winding number of [p], for the polygons
+// approximating the contours.
+func winding(polygons [][]point, p point) int {
+	w := 0
+	for _, poly := range polygons {
+		for i := range poly {
+			a, b := poly[i], poly[(i+1)%len(poly)]
+			if a.y <= p.y {
+				if b.y > p.y && b.sub(a).cross(p.sub(a)) > 0 {
+					w++
+				}
+			} else if b.y <= p.y && b.sub(a).cross(p.sub(a)) < 0 {
+				w--
+			}
+		}
+	}
+	return w
+}
@@ -0,0 +1,320 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package sdf generates signed distance fields from glyph outlines,
+// as used by GPU text renderers.
+//
+// Both single channel (SDF) and multi-channel (MSDF) fields are supported.
+// Multi-channel fields preserve the sharp corners of the glyphs, and are
+// decoded in shaders by taking the median of the red, green and blue channels.
+//
+// In both cases, the pixel values encode the signed distance d (in pixels) to the
+// outline as 0.5 + d / Range, clamped to [0, 1], with d positive inside the glyph.
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// Mode selects the kind of distance field
+type Mode uint8
+
+const (
+	// SDF produces a single channel field, stored in an [image.Gray]
+	SDF Mode = iota
+	// MSDF produces a multi-channel field, stored in the RGB channels
+	// of an [image.RGBA], with an opaque alpha channel.
+	MSDF
+)
+
+// Options provides settings for the generation of a distance field.
+type Options struct {
+	Mode Mode
+
+	// PixelsPerEm is the size, in pixels, at which the field is generated.
+	// It defaults to 32.
+	PixelsPerEm float32
+
+	// Range is the width, in pixels, of the range of distances
+	// which are encoded by the field, centered on the outline.
+	// It defaults to 4.
+	Range float32
+
+	// Padding is the number of pixels added around the glyph bounding box.
+	// It defaults to ceil(Range / 2), so that the whole range is encoded.
+	Padding int
+
+	// AngleThreshold is the minimum angle, in radians, between two
+	// edges to be considered a corner by the MSDF edge coloring.
+	// It defaults to 3.
+	AngleThreshold float64
+}
+
+func (opts *Options) setDefaults() {
+	if opts.PixelsPerEm <= 0 {
+		opts.PixelsPerEm = 32
+	}
+	if opts.Range <= 0 {
+		opts.Range = 4
+	}
+	if opts.Padding <= 0 {
+		opts.Padding = int(math.Ceil(float64(opts.Range) / 2))
+	}
+	if opts.AngleThreshold <= 0 {
+		opts.AngleThreshold = 3
+	}
+}
+
+// Metrics describes the placement of a distance field image,
+// relative to the glyph origin.
+type Metrics struct {
+	// Left and Top are the position of the top-left corner of the image,
+	// in pixels at [PixelsPerEm], relative to the glyph origin, with the
+	// Y axis pointing down (so that Top is typically negative).
+	Left, Top float32
+	// Width and Height are the dimensions of the image, in pixels.
+	Width, Height int
+
+	// PixelsPerEm is the size used to generate the field.
+	PixelsPerEm float32
+	// Range is the distance range encoded in the field, in pixels at [PixelsPerEm].
+	Range float32
+}
+
+// Quad returns the rectangle where the image must be drawn,
+// in pixels with the Y axis pointing down, for a glyph [g] shaped with [size]
+// (see [shaping.Input.Size]) and positioned at [dot], the pen position on the baseline.
+//
+// The distance range in screen pixels, used in shaders to compute the
+// anti-aliasing, is Range * size / PixelsPerEm.
+func (m Metrics) Quad(dot fixed.Point26_6, g shaping.Glyph, size fixed.Int26_6) (minX, minY, maxX, maxY float32) {
+	ratio := fixedToFloat(size) / m.PixelsPerEm
+	originX := fixedToFloat(dot.X + g.XOffset)
+	originY := fixedToFloat(dot.Y - g.YOffset)
+	minX, minY = originX+m.Left*ratio, originY+m.Top*ratio
+	maxX, maxY = minX+float32(m.Width)*ratio, minY+float32(m.Height)*ratio
+	return
+}
+
+func fixedToFloat(v fixed.Int26_6) float32 { return float32(v) / 64 }
+
+// Glyph is a generated distance field
+type Glyph struct {
+	// Image is an [*image.Gray] for [SDF] and an [*image.RGBA] for [MSDF].
+	// It is nil for empty outlines.
+	Image   image.Image
+	Metrics Metrics
+}
+
+// Generate computes the distance field of an outline, with [upem] font units per em,
+// usually obtained from [font.Face.GlyphData] and [font.Face.Upem].
+//
+// The outline may use any orientation, but all its contours must be consistent,
+// which is the case for valid fonts.
+func Generate(outline font.GlyphOutline, upem uint16, opts Options) Glyph {
+	opts.setDefaults()
+	contours := parseContours(outline.Segments)
+	if len(contours) == 0 || upem == 0 {
+		return Glyph{Metrics: Metrics{PixelsPerEm: opts.PixelsPerEm, Range: opts.Range}}
+	}
+
+	scale := float64(opts.PixelsPerEm) / float64(upem) // pixels per font unit
+
+	// compute the image bounds, in pixels
+	xMin, yMin, xMax, yMax := bounds(contours)
+	pad := opts.Padding
+	x0 := int(math.Floor(xMin*scale)) - pad
+	x1 := int(math.Ceil(xMax*scale)) + pad
+	y0 := int(math.Floor(yMin*scale)) - pad
+	y1 := int(math.Ceil(yMax*scale)) + pad
+	metrics := Metrics{
+		Left: float32(x0), Top: float32(-y1),
+		Width: x1 - x0, Height: y1 - y0,
+		PixelsPerEm: opts.PixelsPerEm, Range: opts.Range,
+	}
+
+	// the sign of the distances is positive on the left of the edges,
+	// which is the inside for counter-clockwise contours
+	orientation := 1.
+	if area(contours) < 0 {
+		orientation = -1
+	}
+
+	polygons := make([][]point, len(contours))
+	for i, contour := range contours {
+		for _, e := range contour {
+			polygons[i] = e.flatten(polygons[i])
+		}
+	}
+
+	// encode converts a signed distance in font units to a pixel value
+	encode := func(d float64) uint8 {
+		v := 0.5 + d*scale/float64(opts.Range)
+		return uint8(math.Round(255 * math.Max(0, math.Min(1, v))))
+	}
+
+	rect := image.Rect(0, 0, metrics.Width, metrics.Height)
+	var (
+		gray *image.Gray
+		rgba *image.RGBA
+	)
+	if opts.Mode == MSDF {
+		contours = colorEdges(contours, opts.AngleThreshold)
+		rgba = image.NewRGBA(rect)
+	} else {
+		gray = image.NewGray(rect)
+	}
+
+	for j := 0; j < metrics.Height; j++ {
+		for i := 0; i < metrics.Width; i++ {
+			// pixel center, in font units
+			p := point{(float64(x0+i) + 0.5) / scale, (float64(y1-j) - 0.5) / scale}
+			inside := winding(polygons, p) != 0
+
+			if opts.Mode == SDF {
+				d := math.Abs(closest(contours, p, white).distance)
+				if !inside {
+					d = -d
+				}
+				gray.SetGray(i, j, color.Gray{Y: encode(d)})
+				continue
+			}
+
+			var channels [3]float64
+			for c, ch := range [3]edgeColor{red, green, blue} {
+				channels[c] = orientation * closest(contours, p, ch).pseudo
+			}
+			// correct the artifacts : pixels whose median is on the wrong
+			// side of the outline use the true distance
+			if m := median(channels[0], channels[1], channels[2]); (m > 0) != inside {
+				d := math.Abs(closest(contours, p, white).distance)
+				if !inside {
+					d = -d
+				}
+				channels = [3]float64{d, d, d}
+			}
+			rgba.SetRGBA(i, j, color.RGBA{R: encode(channels[0]), G: encode(channels[1]), B: encode(channels[2]), A: 0xff})
+		}
+	}
+
+	out := Glyph{Metrics: metrics}
+	if gray != nil {
+		out.Image = gray
+	} else {
+		out.Image = rgba
+	}
+	return out
+}
+
+func median(a, b, c float64) float64 {
+	return math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+}
+
+type closestEdge struct {
+	signedDistance
+	pseudo float64 // pseudo distance, see [edge.pseudoDistance]
+}
+
+// closest returns the distance to the closest edge using the given channel.
+func closest(contours [][]edge, p point, channel edgeColor) closestEdge {
+	var (
+		best      = infiniteDistance
+		bestEdge  *edge
+		bestParam float64
+	)
+	for _, contour := range contours {
+		for i := range contour {
+			e := &contour[i]
+			if e.color&channel == 0 {
+				continue
+			}
+			if sd, t := e.signedDistance(p); sd.less(best) {
+				best, bestEdge, bestParam = sd, e, t
+			}
+		}
+	}
+	if bestEdge == nil {
+		return closestEdge{signedDistance: best, pseudo: best.distance}
+	}
+	return closestEdge{signedDistance: best, pseudo: bestEdge.pseudoDistance(p, best, bestParam)}
+}
+
+// parseContours converts the segments to edges, dropping the
+// degenerate ones, and closing the contours.
+func parseContours(segments []font.Segment) (out [][]edge) {
+	var (
+		current    []edge
+		start, pos point
+	)
+	toPoint := func(p font.SegmentPoint) point { return point{float64(p.X), float64(p.Y)} }
+	add := func(e edge) {
+		e.color = white
+		for i := 1; i <= e.deg; i++ {
+			if e.p[i] != e.p[0] {
+				current = append(current, e)
+				break
+			}
+		}
+		pos = e.end()
+	}
+	flush := func() {
+		if pos != start {
+			add(edge{p: [4]point{pos, start}, deg: 1})
+		}
+		if len(current) != 0 {
+			out = append(out, current)
+		}
+		current = nil
+	}
+	for _, seg := range segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo:
+			flush()
+			start = toPoint(seg.Args[0])
+			pos = start
+		case ot.SegmentOpLineTo:
+			add(edge{p: [4]point{pos, toPoint(seg.Args[0])}, deg: 1})
+		case ot.SegmentOpQuadTo:
+			add(edge{p: [4]point{pos, toPoint(seg.Args[0]), toPoint(seg.Args[1])}, deg: 2})
+		case ot.SegmentOpCubeTo:
+			add(edge{p: [4]point{pos, toPoint(seg.Args[0]), toPoint(seg.Args[1]), toPoint(seg.Args[2])}, deg: 3})
+		}
+	}
+	flush()
+	return out
+}
+
+// bounds returns the bounding box of the control points
+func bounds(contours [][]edge) (xMin, yMin, xMax, yMax float64) {
+	xMin, yMin = math.Inf(1), math.Inf(1)
+	xMax, yMax = math.Inf(-1), math.Inf(-1)
+	for _, contour := range contours {
+		for _, e := range contour {
+			for _, p := range e.p[:e.deg+1] {
+				xMin, yMin = math.Min(xMin, p.x), math.Min(yMin, p.y)
+				xMax, yMax = math.Max(xMax, p.x), math.Max(yMax, p.y)
+			}
+		}
+	}
+	return
+}
+
+// area returns the signed area of the polygon formed by the control points,
+// which is positive for counter-clockwise outlines.
+func area(contours [][]edge) float64 {
+	var a float64
+	for _, contour := range contours {
+		for _, e := range contour {
+			for i := 0; i < e.deg; i++ {
+				a += e.p[i].cross(e.p[i+1])
+			}
+		}
+	}
+	return a / 2
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package sdf
+
+import (
+	"bytes"
+	"image"
+	"math/bits"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func moveTo(x, y float32) font.Segment {
+	return font.Segment{Op: ot.SegmentOpMoveTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+}
+
+func lineTo(x, y float32) font.Segment {
+	return font.Segment{Op: ot.SegmentOpLineTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+}
+
+func quadTo(cx, cy, x, y float32) font.Segment {
+	return font.Segment{Op: ot.SegmentOpQuadTo, Args: [3]font.SegmentPoint{{X: cx, Y: cy}, {X: x, Y: y}}}
+}
+
+func square(clockwise bool) font.GlyphOutline {
+	if clockwise {
+		return font.GlyphOutline{Segments: []font.Segment{moveTo(0, 0), lineTo(0, 500), lineTo(500, 500), lineTo(500, 0), lineTo(0, 0)}}
+	}
+	return font.GlyphOutline{Segments: []font.Segment{moveTo(0, 0), lineTo(500, 0), lineTo(500, 500), lineTo(0, 500), lineTo(0, 0)}}
+}
+
+// value returns the decoded value of the pixel
+func value(img image.Image, x, y int) uint8 {
+	switch img := img.(type) {
+	case *image.Gray:
+		return img.GrayAt(x, y).Y
+	case *image.RGBA:
+		c := img.RGBAAt(x, y)
+		return uint8(median(float64(c.R), float64(c.G), float64(c.B)))
+	}
+	panic("unexpected image")
+}
+
+func TestSquare(t *testing.T) {
+	for _, clockwise := range []bool{false, true} {
+		for _, mode := range []Mode{SDF, MSDF} {
+			g := Generate(square(clockwise), 1000, Options{Mode: mode})
+			// 500 units at 32 pixels per em is 16 pixels, plus a padding of 2 on each side
+			tu.Assert(t, g.Metrics == Metrics{Left: -2, Top: -18, Width: 20, Height: 20, PixelsPerEm: 32, Range: 4})
+			tu.Assert(t, g.Image.Bounds() == image.Rect(0, 0, 20, 20))
+
+			tu.Assert(t, value(g.Image, 10, 10) == 255) // inside, far from the edges
+			tu.Assert(t, value(g.Image, 0, 10) == 32)   // outside, 1.5 pixel from the edge
+			// pixel centers at 0.5 pixel from the edge : 255 * (0.5 ± 0.5 / 4)
+			tu.Assert(t, value(g.Image, 2, 10) == 159)
+			tu.Assert(t, value(g.Image, 1, 10) == 96)
+		}
+	}
+
+	// the corners are preserved by MSDF: at the corner diagonal, outside,
+	// the median is the pseudo distance to the closest side
+	sdf := Generate(square(false), 1000, Options{Mode: SDF})
+	msdf := Generate(square(false), 1000, Options{Mode: MSDF})
+	tu.Assert(t, value(msdf.Image, 1, 1) > value(sdf.Image, 1, 1))
+	tu.Assert(t, value(msdf.Image, 1, 1) == 96)
+
+	// empty outline
+	g := Generate(font.GlyphOutline{}, 1000, Options{})
+	tu.Assert(t, g.Image == nil)
+}
+
+func TestOptions(t *testing.T) {
+	g := Generate(square(false), 1000, Options{PixelsPerEm: 64, Range: 8, Padding: 5})
+	tu.Assert(t, g.Metrics == Metrics{Left: -5, Top: -37, Width: 42, Height: 42, PixelsPerEm: 64, Range: 8})
+	tu.Assert(t, value(g.Image, 5, 20) == 143) // 255 * (0.5 + 0.5 / 8)
+}
+
+func TestColorEdges(t *testing.T) {
+	checkCorners := func(contour []edge) {
+		prevDir := contour[len(contour)-1].direction(1)
+		prev := contour[len(contour)-1]
+		for _, e := range contour {
+			tu.Assert(t, e.color != 0)
+			if isCorner(prevDir, e.direction(0), 0.14) {
+				tu.Assert(t, bits.OnesCount8(uint8(prev.color&e.color)) == 1)
+			}
+			prevDir, prev = e.direction(1), e
+		}
+	}
+
+	// polygons with 3 to 7 corners
+	for n := 3; n <= 7; n++ {
+		var segments []font.Segment
+		for i := 0; i < n; i++ {
+			x, y := float32(i*100), float32((i%2)*100+i*i)
+			if i == 0 {
+				segments = append(segments, moveTo(x, y))
+			} else {
+				segments = append(segments, lineTo(x, y))
+			}
+		}
+		contours := colorEdges(parseContours(segments), 3)
+		tu.Assert(t, len(contours) == 1)
+		checkCorners(contours[0])
+	}
+
+	// smooth contour
+	circle := []font.Segment{moveTo(0, -100), quadTo(100, -100, 100, 0), quadTo(100, 100, 0, 100), quadTo(-100, 100, -100, 0), quadTo(-100, -100, 0, -100)}
+	contours := colorEdges(parseContours(circle), 3)
+	for _, e := range contours[0] {
+		tu.Assert(t, e.color == white)
+	}
+
+	// teardrop, with only two edges
+	teardrop := []font.Segment{moveTo(0, 0), quadTo(100, 100, 0, 200), quadTo(-100, 300, 0, 0)}
+	contours = colorEdges(parseContours(teardrop), 3)
+	tu.Assert(t, len(contours[0]) == 6)
+	checkCorners(contours[0])
+	tu.Assert(t, contours[0][0].color != contours[0][5].color)
+}
+
+func loadFace(t *testing.T, filename string) *font.Face {
+	file, err := td.Files.ReadFile(filename)
+	tu.AssertNoErr(t, err)
+	face, err := font.ParseTTF(bytes.NewReader(file))
+	tu.AssertNoErr(t, err)
+	return face
+}
+
+func TestGlyphs(t *testing.T) {
+	for _, filename := range []string{
+		"common/DejaVuSans.ttf",
+		"common/Raleway-v4020-Regular.otf", // cubic curves, counter-clockwise
+	} {
+		face := loadFace(t, filename)
+		for _, r := range "Ao&" {
+			gid, _ := face.NominalGlyph(r)
+			outline := face.GlyphData(gid).(font.GlyphOutline)
+			sdf := Generate(outline, face.Upem(), Options{Mode: SDF})
+			msdf := Generate(outline, face.Upem(), Options{Mode: MSDF})
+			tu.Assert(t, sdf.Metrics == msdf.Metrics)
+
+			// the median of the channels is on the same side of the outline
+			var nbInside int
+			for y := 0; y < sdf.Metrics.Height; y++ {
+				for x := 0; x < sdf.Metrics.Width; x++ {
+					s, m := value(sdf.Image, x, y), value(msdf.Image, x, y)
+					tu.Assert(t, (s >= 128) == (m >= 128))
+					if s >= 128 {
+						nbInside++
+					}
+				}
+			}
+			tu.Assert(t, nbInside > 0)
+		}
+
+		// the 'o' has a hole
+		gid, _ := face.NominalGlyph('o')
+		sdf := Generate(face.GlyphData(gid).(font.GlyphOutline), face.Upem(), Options{Mode: SDF})
+		tu.Assert(t, value(sdf.Image, sdf.Metrics.Width/2, sdf.Metrics.Height/2) < 128)
+	}
+}
+
+func TestQuad(t *testing.T) {
+	face := loadFace(t, "common/DejaVuSans.ttf")
+	text := []rune("Ag")
+	size := fixed.I(48)
+	out := (&shaping.HarfbuzzShaper{}).Shape(shaping.Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: face, Size: size, Script: language.Latin,
+	})
+
+	dot := fixed.Point26_6{X: fixed.I(10), Y: fixed.I(100)}
+	for _, g := range out.Glyphs {
+		sdf := Generate(face.GlyphData(g.GlyphID).(font.GlyphOutline), face.Upem(), Options{PixelsPerEm: 24, Padding: 2})
+		minX, minY, maxX, maxY := sdf.Metrics.Quad(dot, g, size)
+		// the quad is scaled by 2, and the padding covers 4 pixels
+		tu.Assert(t, maxX-minX == float32(2*sdf.Metrics.Width))
+		tu.Assert(t, maxY-minY == float32(2*sdf.Metrics.Height))
+
+		// compare with the glyph extents
+		left := fixedToFloat(dot.X+g.XOffset+g.XBearing) - 4
+		top := fixedToFloat(dot.Y-g.YOffset-g.YBearing) - 4
+		tu.Assert(t, minX <= left && left-minX < 2)
+		tu.Assert(t, minY <= top && top-minY < 2)
+
+		dot.X += g.Advance
+	}
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/segmenter"
+	"golang.org/x/image/math/fixed"
+)
+
+// LigatureCarets returns the caret positions for each rune boundary of the run,
+// which may be used to place a cursor inside a ligature glyph (like "ffi").
+//
+// [text] is the text used to shape the run (see [Input.Text]).
+//
+// The returned slice has length o.Runes.Count + 1 : its i-th value is the position of the boundary
+// before the rune o.Runes.Offset + i (in logical order), measured from the (visual) start
+// of the run, along the advance direction : values are thus negative for vertical text.
+//
+// Inside a cluster, the positions of grapheme boundaries are
+// read from the font (GDEF or AAT 'lcar' table) for horizontal ligature glyphs.
+// Otherwise, the cluster advance is equally divided between its graphemes.
+// Boundaries which are not grapheme boundaries are placed at the start of their grapheme.
+func (o *Output) LigatureCarets(text []rune) []fixed.Int26_6 {
+	carets := make([]fixed.Int26_6, o.Runes.Count+1)
+	if o.Runes.Count == 0 {
+		return carets
+	}
+	isGraphemeBoundary := graphemeBoundaries(text[o.Runes.Offset : o.Runes.Offset+o.Runes.Count])
+	rtl := o.Direction.Progression() == di.TowardTopLeft
+
+	var pen fixed.Int26_6
+	for i := 0; i < len(o.Glyphs); {
+		// find the cluster
+		cluster := o.Glyphs[i].ClusterIndex
+		end := i + 1
+		for end < len(o.Glyphs) && o.Glyphs[end].ClusterIndex == cluster {
+			end++
+		}
+		var width fixed.Int26_6
+		for _, g := range o.Glyphs[i:end] {
+			width += g.Advance
+		}
+
+		runeStart, runeCount := cluster-o.Runes.Offset, o.Glyphs[i].RuneCount
+		if runeStart < 0 || runeStart+runeCount > o.Runes.Count { // should not happen on valid outputs
+			pen += width
+			i = end
+			continue
+		}
+
+		// collect the internal grapheme boundaries
+		var internal []int
+		for k := 1; k < runeCount; k++ {
+			if isGraphemeBoundary[runeStart+k] {
+				internal = append(internal, k)
+			}
+		}
+
+		// positions of the internal boundaries, relative to the logical start of the cluster
+		positions := make([]fixed.Int26_6, len(internal))
+		var fontCarets []float32
+		if len(internal) != 0 && end-i == 1 && !o.Direction.IsVertical() {
+			fontCarets = ligatureCarets(o.Face, o.Glyphs[i].GlyphID)
+		}
+		if len(fontCarets) >= len(internal) && len(fontCarets) != 0 {
+			fontCarets = fontCarets[:len(internal)]
+			for m := range positions {
+				if rtl { // carets are sorted in visual order
+					positions[m] = width - o.FromFontUnit(fontCarets[len(internal)-1-m])
+				} else {
+					positions[m] = o.FromFontUnit(fontCarets[m])
+				}
+			}
+		} else { // equal division
+			for m := range positions {
+				positions[m] = width * fixed.Int26_6(m+1) / fixed.Int26_6(len(internal)+1)
+			}
+		}
+
+		// convert to run coordinates
+		toRun := func(p fixed.Int26_6) fixed.Int26_6 {
+			if rtl {
+				return pen + width - p
+			}
+			return pen + p
+		}
+		current := toRun(0)
+		carets[runeStart] = current
+		for k, m := 1, 0; k < runeCount; k++ {
+			if m < len(internal) && internal[m] == k {
+				current = toRun(positions[m])
+				m++
+			}
+			carets[runeStart+k] = current
+		}
+
+		pen += width
+		i = end
+	}
+	if !rtl {
+		carets[o.Runes.Count] = pen
+	}
+	return carets
+}
+
+// ligatureCarets returns the horizontal caret positions of the ligature [glyph],
+// in font units, read from the GDEF table, or from the AAT 'lcar' table.
+// Carets defined by contour points use the x coordinate of the point ;
+// nil is returned if one of them can't be resolved.
+func ligatureCarets(face *font.Face, glyph font.GID) []float32 {
+	if list := face.GDEF.LigCaretList; list.Coverage != nil {
+		if index, ok := list.Coverage.Index(tables.GlyphID(glyph)); ok && index < len(list.LigGlyphs) {
+			values := list.LigGlyphs[index].CaretValues
+			out := make([]float32, len(values))
+			for i, caret := range values {
+				switch caret := caret.(type) {
+				case tables.CaretValue1:
+					out[i] = float32(caret.Coordinate)
+				case tables.CaretValue2:
+					x, _, ok := face.GetGlyphContourPoint(glyph, caret.CaretValuePointIndex)
+					if !ok {
+						return nil
+					}
+					out[i] = float32(x)
+				case tables.CaretValue3:
+					out[i] = float32(caret.Coordinate)
+					if device, ok := caret.Device.(tables.DeviceVariation); ok {
+						out[i] += face.GDEF.ItemVarStore.GetDelta(tables.VariationStoreIndex(device), face.Coords())
+					}
+				default:
+					return nil
+				}
+			}
+			return out
+		}
+	}
+
+	lcar := face.Lcar
+	values := lcar.Carets(tables.GlyphID(glyph))
+	if values == nil {
+		return nil
+	}
+	out := make([]float32, len(values))
+	for i, v := range values {
+		if lcar.Format == 1 { // control point
+			x, _, ok := face.GetGlyphContourPoint(glyph, uint16(v))
+			if !ok {
+				return nil
+			}
+			out[i] = float32(x)
+		} else {
+			out[i] = float32(v)
+		}
+	}
+	return out
+}
+
+// graphemeBoundaries returns a slice of length len(text)+1,
+// with true at each grapheme boundary.
+func graphemeBoundaries(text []rune) []bool {
+	out := make([]bool, len(text)+1)
+	var seg segmenter.Segmenter
+	seg.Init(text)
+	iter := seg.GraphemeIterator()
+	for iter.Next() {
+		out[iter.Grapheme().Offset] = true
+	}
+	out[len(text)] = true
+	return out
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestLigatureCarets(t *testing.T) {
+	var shaper HarfbuzzShaper
+	text := []rune("office")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR, Size: fixed.I(16),
+		Face: loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf"), Script: language.Latin, Language: language.NewLanguage("en"),
+	}
+	out := shaper.Shape(input)
+	tu.Assert(t, len(out.Glyphs) == 4) // o ffi c e
+	lig := out.Glyphs[1]
+
+	// no carets in the font : equal division
+	carets := out.LigatureCarets(text)
+	tu.Assert(t, len(carets) == 7)
+	tu.Assert(t, carets[0] == 0 && carets[1] == out.Glyphs[0].Advance && carets[6] == out.Advance)
+	tu.Assert(t, carets[2] == carets[1]+lig.Advance/3)
+	tu.Assert(t, carets[3] == carets[1]+lig.Advance*2/3)
+	tu.Assert(t, carets[4] == carets[1]+lig.Advance)
+
+	// with carets in the font (AAT lcar, format 0)
+	quarter, half := input.Face.Upem()/4, input.Face.Upem()/2
+	input.Face.Lcar, _, _ = tables.ParseLcar([]byte{
+		0, 1, 0, 0, 0, 0, // version, format
+		0, 8, byte(lig.GlyphID >> 8), byte(lig.GlyphID), 0, 1, 0, 14, // lookup
+		0, 2, byte(quarter >> 8), byte(quarter), byte(half >> 8), byte(half), // carets at 1/4 and 1/2 em
+	}, 1000)
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, carets[2] == carets[1]+fixed.I(4))
+	tu.Assert(t, carets[3] == carets[1]+fixed.I(8))
+	tu.Assert(t, carets[4] == carets[1]+lig.Advance)
+
+	// carets defined by contour points (AAT lcar, format 1), which are not resolved : equal division
+	input.Face.Lcar, _, _ = tables.ParseLcar([]byte{
+		0, 1, 0, 0, 0, 1, // version, format
+		0, 8, byte(lig.GlyphID >> 8), byte(lig.GlyphID), 0, 1, 0, 14, // lookup
+		0, 2, 0, 1, 0, 2, // contour points 1 and 2
+	}, 1000)
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, carets[2] == carets[1]+lig.Advance/3)
+	tu.Assert(t, carets[3] == carets[1]+lig.Advance*2/3)
+
+	// GDEF carets are used first
+	input.Face.GDEF.LigCaretList = tables.LigCaretList{
+		Coverage: tables.Coverage1{Glyphs: []tables.GlyphID{tables.GlyphID(lig.GlyphID)}},
+		LigGlyphs: []tables.LigGlyph{{CaretValues: []tables.CaretValue{
+			tables.CaretValue1{Coordinate: int16(quarter / 2)}, tables.CaretValue1{Coordinate: int16(quarter * 3 / 2)},
+		}}},
+	}
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, carets[2] == carets[1]+fixed.I(2))
+	tu.Assert(t, carets[3] == carets[1]+fixed.I(6))
+
+	// GDEF carets defined by contour points
+	input.Face.GDEF.LigCaretList.LigGlyphs[0].CaretValues[1] = tables.CaretValue2{CaretValuePointIndex: 1}
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, carets[2] == carets[1]+lig.Advance/3)
+	tu.Assert(t, carets[3] == carets[1]+lig.Advance*2/3)
+
+	// combining marks are not grapheme boundaries
+	text = []rune("éx")
+	input.Text, input.RunEnd = text, len(text)
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, len(carets) == 4)
+	tu.Assert(t, carets[1] == carets[0] && carets[2] > carets[1] && carets[3] == out.Advance)
+
+	// right to left
+	text = []rune("لا بسم")
+	input = Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL, Size: fixed.I(16),
+		Face: benchArFace, Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out = shaper.Shape(input)
+	carets = out.LigatureCarets(text)
+	tu.Assert(t, carets[0] == out.Advance && carets[len(text)] == 0)
+	for i := 1; i < len(carets); i++ {
+		tu.Assert(t, carets[i] <= carets[i-1])
+	}
+}
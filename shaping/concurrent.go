@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sync"
+
+	"github.com/go-text/typesetting/harfbuzz"
+)
+
+// ConcurrentShaper implements the Shaper interface using harfbuzz, and,
+// contrary to [HarfbuzzShaper], is safe for concurrent use : a single
+// ConcurrentShaper may be shared by all the goroutines of a program.
+//
+// The harfbuzz fonts (and their accelerators) and the shaping plans are shared,
+// and the buffers are stored in a pool.
+// Note that the faces used as input must not be modified while shaping.
+//
+// The zero value is ready to use.
+type ConcurrentShaper struct {
+	buffers sync.Pool // *shapingBuffer
+
+	fontsMu sync.Mutex
+	fonts   fontLRU
+
+	plansOnce sync.Once
+	plans     harfbuzz.ShapePlanCache
+}
+
+var _ Shaper = (*ConcurrentShaper)(nil)
+
+// shapingBuffer groups the storage used during one shaping call
+type shapingBuffer struct {
+	buf      *harfbuzz.Buffer
+	features []harfbuzz.Feature
+}
+
+// SetFontCacheSize adjusts the size of the font cache within the shaper.
+// See [HarfbuzzShaper.SetFontCacheSize].
+func (s *ConcurrentShaper) SetFontCacheSize(size int) {
+	s.fontsMu.Lock()
+	defer s.fontsMu.Unlock()
+	s.fonts.maxSizeOffset = size - defaultFontCacheSize
+}
+
+// font returns a (shallow) copy of the cached font for [input],
+// so that the scale may be safely adjusted.
+func (s *ConcurrentShaper) font(input Input) harfbuzz.Font {
+	s.fontsMu.Lock()
+	defer s.fontsMu.Unlock()
+	return *s.fonts.font(input)
+}
+
+// planCache returns the shaping plans cache, shared by all the buffers.
+func (s *ConcurrentShaper) planCache() *harfbuzz.ShapePlanCache {
+	s.plansOnce.Do(func() { s.plans.MaxFonts = maxPlanFonts })
+	return &s.plans
+}
+
+func (s *ConcurrentShaper) shape(input Input, skipExtents bool) Output {
+	sb, _ := s.buffers.Get().(*shapingBuffer)
+	if sb == nil {
+		sb = &shapingBuffer{buf: harfbuzz.NewBuffer()}
+		sb.buf.PlanCache = s.planCache()
+	} else {
+		sb.buf.Clear()
+	}
+	defer s.buffers.Put(sb)
+
+	font := s.font(input)
+	return shapeWith(sb.buf, &font, &sb.features, input, skipExtents)
+}
+
+// Shape turns an input into an output.
+// It is safe to call it from several goroutines.
+func (s *ConcurrentShaper) Shape(input Input) Output { return s.shape(input, false) }
+
+// ShapeNoExtents is the same as [Shape], but do not query glyph extents,
+// making if more efficient when only advance is required.
+func (s *ConcurrentShaper) ShapeNoExtents(input Input) Output { return s.shape(input, true) }
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+// This test is meant to be run with the race detector enabled :
+// go test -race -run Concurrent
+func TestConcurrentShaper(t *testing.T) {
+	text := []rune("Hello, world ! مرحبا بالعالم office")
+	var inputs []Input
+	for _, size := range []int{10, 16, 33} {
+		inputs = append(inputs,
+			Input{
+				Text: text, RunStart: 0, RunEnd: 14, Direction: di.DirectionLTR,
+				Face: benchEnFace, Size: fixed.I(size), Script: language.Latin, Language: language.NewLanguage("en"),
+			},
+			Input{
+				Text: text, RunStart: 15, RunEnd: 28, Direction: di.DirectionRTL,
+				Face: benchArFace, Size: fixed.I(size), Script: language.Arabic, Language: language.NewLanguage("ar"),
+			},
+			Input{
+				Text: text, RunStart: 29, RunEnd: len(text), Direction: di.DirectionLTR,
+				Face: benchEnFace, Size: fixed.I(size), Script: language.Latin, Language: language.NewLanguage("en"),
+				FontFeatures: []FontFeature{{Tag: ot.MustNewTag("liga"), Value: 0}},
+			},
+			Input{
+				Text: text, RunStart: 0, RunEnd: 14, Direction: di.DirectionTTB,
+				Face: benchEnFace, Size: fixed.I(size), Script: language.Latin, Language: language.NewLanguage("en"),
+			},
+		)
+	}
+
+	var ref HarfbuzzShaper
+	expected := make([]Output, len(inputs))
+	for i, input := range inputs {
+		expected[i] = ref.Shape(input)
+	}
+
+	var (
+		shaper ConcurrentShaper
+		wg     sync.WaitGroup
+	)
+	for j := 0; j < 8; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			for k := 0; k < 10; k++ {
+				i := (j + k) % len(inputs)
+				if got := shaper.Shape(inputs[i]); !reflect.DeepEqual(got, expected[i]) {
+					t.Errorf("unexpected output for input %d", i)
+				}
+				got := shaper.ShapeNoExtents(inputs[i])
+				tu.Assert(t, len(got.Glyphs) == len(expected[i].Glyphs) && got.Advance == expected[i].Advance)
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	// concurrent shapers share the font cache
+	tu.Assert(t, len(shaper.fonts.m) == 2)
+
+	// the shaping plans are owned by the shapers
+	var other HarfbuzzShaper
+	other.Shape(inputs[0])
+	tu.Assert(t, shaper.plans.MaxFonts == maxPlanFonts)
+	tu.Assert(t, ref.plans != nil && other.plans != nil && ref.plans != other.plans)
+}
@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"github.com/go-text/typesetting/segmenter"
+	"golang.org/x/image/math/fixed"
+)
+
+// TruncatePosition selects which part of the last line permitted by
+// [WrapConfig.TruncateAfterLines] is removed when the text does not fit.
+type TruncatePosition uint8
+
+const (
+	// TruncateEnd removes the end of the text, which is the default behavior.
+	TruncateEnd TruncatePosition = iota
+	// TruncateStart removes the start of the last line, keeping the end of
+	// the paragraph visible (as in "…/long/path").
+	TruncateStart
+	// TruncateMiddle removes the middle of the last line, keeping
+	// its start and the end of the paragraph visible (as in "abc…xyz").
+	TruncateMiddle
+)
+
+// elider stores the paragraph information used to truncate the
+// start or the middle of the last line.
+type elider struct {
+	text []rune
+	seg  segmenter.Segmenter
+
+	// scratch buffers
+	runs    []Output
+	indices []int
+	// advances stores the advance of each glyph cluster,
+	// indexed by its first rune, relative to the line start
+	advances []fixed.Int26_6
+	// clusterStarts is true for the cluster boundaries
+	clusterStarts []bool
+	// cuts are the positions (relative to the line start) where the line may be cut
+	cuts []int
+}
+
+// width returns the advance of the runes [start, end), relative to the line start.
+func (e *elider) width(start, end int) fixed.Int26_6 {
+	var w fixed.Int26_6
+	for _, a := range e.advances[start:end] {
+		w += a
+	}
+	return w
+}
+
+// hasMandatoryBreak returns true if the runes [start, end) (relative to [lineStart])
+// contain a mandatory break, other than at the end of the paragraph.
+func (e *elider) hasMandatoryBreak(lineStart, start, end int) bool {
+	for i := lineStart + start; i < lineStart+end; i++ {
+		if isMandatoryBreak(e.text[i]) && i != len(e.text)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// collect reads the remaining runs of the paragraph (starting at [lineStart]), and
+// computes the cluster advances and the positions where the line may be cut.
+// It returns the width of the remaining text.
+func (e *elider) collect(runs RunIterator, lineStart int, trimTrailingWhitespace bool) fixed.Int26_6 {
+	e.runs, e.indices = e.runs[:0], e.indices[:0]
+	for {
+		index, run, ok := runs.Next()
+		if !ok {
+			break
+		}
+		if run.Runes.Offset+run.Runes.Count <= lineStart {
+			continue
+		}
+		e.runs = append(e.runs, run)
+		e.indices = append(e.indices, index)
+	}
+
+	n := len(e.text) - lineStart
+	e.advances = append(e.advances[:0], make([]fixed.Int26_6, n)...)
+	e.clusterStarts = append(e.clusterStarts[:0], make([]bool, n+1)...)
+	e.clusterStarts[0], e.clusterStarts[n] = true, true
+	for _, run := range e.runs {
+		if offset := run.Runes.Offset - lineStart; offset > 0 {
+			e.clusterStarts[offset] = true
+		}
+		for _, g := range run.Glyphs {
+			c := g.ClusterIndex - lineStart
+			if c < 0 || c >= n {
+				continue
+			}
+			e.advances[c] += abs(g.Advance)
+			e.clusterStarts[c] = true
+		}
+	}
+	if trimTrailingWhitespace {
+		for i := n - 1; i >= 0 && isWhitespace(e.text[lineStart+i]); i-- {
+			e.advances[i] = 0
+		}
+	}
+
+	// only cut at grapheme boundaries which are also cluster boundaries
+	e.cuts = e.cuts[:0]
+	e.seg.Init(e.text[lineStart:])
+	for graphemes := e.seg.GraphemeIterator(); graphemes.Next(); {
+		if start := graphemes.Grapheme().Offset; e.clusterStarts[start] {
+			e.cuts = append(e.cuts, start)
+		}
+	}
+	e.cuts = append(e.cuts, n)
+	return e.width(0, n)
+}
+
+// elide returns the runes [head, tail) (relative to the line start) to remove, so that
+// the remaining text fits in [available].
+// Mandatory breaks are always removed.
+func (e *elider) elide(position TruncatePosition, lineStart int, available fixed.Int26_6) (head, tail int) {
+	h, k := 0, len(e.cuts)-1
+	var headWidth, tailWidth fixed.Int26_6
+	extendHead := func(limit fixed.Int26_6) {
+		for h < k {
+			unit := e.width(e.cuts[h], e.cuts[h+1])
+			if headWidth+tailWidth+unit > limit || e.hasMandatoryBreak(lineStart, e.cuts[h], e.cuts[h+1]) {
+				return
+			}
+			headWidth += unit
+			h++
+		}
+	}
+	if position == TruncateMiddle {
+		extendHead(available / 2)
+	}
+	for k > h {
+		unit := e.width(e.cuts[k-1], e.cuts[k])
+		if headWidth+tailWidth+unit > available || e.hasMandatoryBreak(lineStart, e.cuts[k-1], e.cuts[k]) {
+			break
+		}
+		tailWidth += unit
+		k--
+	}
+	if position == TruncateMiddle {
+		// use the remaining space
+		extendHead(available)
+	}
+	return e.cuts[h], e.cuts[k]
+}
+
+// appendElidedRuns adds to the line candidate the runes [start, end) of the collected runs.
+func (l *LineWrapper) appendElidedRuns(start, end int, trimStart bool) {
+	e := &l.elider
+	for i, run := range e.runs {
+		runStart, runEnd := start, end
+		if runStart < run.Runes.Offset {
+			runStart = run.Runes.Offset
+		}
+		if runEnd > run.Runes.Offset+run.Runes.Count {
+			runEnd = run.Runes.Offset + run.Runes.Count
+		}
+		if runStart >= runEnd {
+			continue
+		}
+		l.recordRun(e.indices[i], run)
+		l.mapper.mapRun(e.indices[i], run)
+		cut := cutRun(run, l.mapper.mapping, runStart, runEnd-1, trimStart && l.scratch.candidateLen() == 0)
+		l.scratch.candidateAppend(l.reshapeCut(lineEdge{index: e.indices[i], run: run, valid: true}, cut))
+	}
+}
+
+// elidedLine builds the last line permitted by [WrapConfig.TruncateAfterLines],
+// for the [TruncateStart] and [TruncateMiddle] positions : the line holds the rest of the paragraph,
+// from which whole graphemes are removed and replaced by the truncator, if it does not fit
+// in [maxWidth]. The removed runes are stored in [l.elided].
+func (l *LineWrapper) elidedLine(maxWidth fixed.Int26_6) Line {
+	e := &l.elider
+	lineStart, end := l.lineStartRune, l.breaker.totalRunes
+	width := e.collect(l.glyphRuns, lineStart, !l.config.DisableTrailingWhitespaceTrim)
+
+	head, tail := end-lineStart, end-lineStart
+	if width > maxWidth || e.hasMandatoryBreak(lineStart, 0, end-lineStart) {
+		head, tail = e.elide(l.config.TruncatePosition, lineStart, maxWidth-l.config.Truncator.Advance)
+	}
+	l.appendElidedRuns(lineStart, lineStart+head, true)
+	if head != tail {
+		l.elided = Range{Offset: lineStart + head, Count: tail - head}
+		truncator := l.config.Truncator
+		truncator.Runes = l.elided
+		l.scratch.candidateAppend(truncator)
+	}
+	l.appendElidedRuns(lineStart+tail, end, false)
+	l.scratch.markCandidateBest()
+	return l.scratch.finalizeBest()
+}
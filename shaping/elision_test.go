@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func wrapElided(config WrapConfig, maxWidth fixed.Int26_6, text []rune, runs []Output) []WrappedLine {
+	var (
+		wrapper LineWrapper
+		lines   []WrappedLine
+	)
+	wrapper.Prepare(config, text, NewSliceIterator(runs))
+	for done := false; !done; {
+		var line WrappedLine
+		line, done = wrapper.WrapNextLineF(maxWidth)
+		if line.Line != nil {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func linesOf(lines []WrappedLine) []Line {
+	var out []Line
+	for _, line := range lines {
+		out = append(out, line.Line)
+	}
+	return out
+}
+
+func TestTruncateStart(t *testing.T) {
+	text := []rune("/usr/local/share/typesetting/shaping/wrapping.go")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	config := WrapConfig{TruncateAfterLines: 1, Truncator: truncator, TruncatePosition: TruncateStart}
+
+	lines := wrapElided(config, fixed.I(150), text, []Output{out.copy()})
+	tu.Assert(t, len(lines) == 1)
+	line := lines[0]
+	// the truncator covers the removed runes
+	checkRuneCounts(t, text, linesOf(lines), 0)
+	tu.Assert(t, line.Line[0].Glyphs[0].GlyphID == truncator.Glyphs[0].GlyphID)
+	tu.Assert(t, line.Elided == Range{Offset: 0, Count: line.Truncated} && line.Truncated > 0)
+	tu.Assert(t, line.Line[0].Runes == line.Elided)
+	tu.Assert(t, lineAdvance(line.Line) <= fixed.I(150))
+	// as much text as possible is kept
+	glyph := out.Glyphs[line.Truncated-1].Advance
+	tu.Assert(t, lineAdvance(line.Line)+glyph > fixed.I(150))
+
+	// the truncation only applies to the last line
+	config.TruncateAfterLines = 2
+	lines = wrapElided(config, fixed.I(150), text, []Output{out.copy()})
+	tu.Assert(t, len(lines) == 2)
+	checkRuneCounts(t, text, linesOf(lines), 0)
+	first := lines[0].Line
+	tu.Assert(t, lines[0].Truncated == 0 && lines[0].Elided == Range{})
+	tu.Assert(t, lines[1].Elided.Offset == first[len(first)-1].Runes.Offset+first[len(first)-1].Runes.Count)
+
+	// nothing is removed when the text fits
+	config.TruncateAfterLines = 1
+	lines = wrapElided(config, fixed.I(1000), text, []Output{out.copy()})
+	tu.Assert(t, len(lines) == 1 && lines[0].Truncated == 0 && len(lines[0].Line) == 1)
+	_, truncated := (&LineWrapper{}).WrapParagraphF(config, fixed.I(150), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, truncated == line.Truncated)
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	text := []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	config := WrapConfig{TruncateAfterLines: 1, Truncator: truncator, TruncatePosition: TruncateMiddle}
+
+	lines := wrapElided(config, fixed.I(120), text, []Output{out.copy()})
+	tu.Assert(t, len(lines) == 1)
+	line := lines[0]
+	checkRuneCounts(t, text, linesOf(lines), 0)
+	tu.Assert(t, len(line.Line) == 3)
+	head, tail := line.Line[0], line.Line[2]
+	tu.Assert(t, line.Line[1].Runes == line.Elided && line.Elided.Count == line.Truncated)
+	tu.Assert(t, head.Runes.Offset == 0 && tail.Runes.Offset+tail.Runes.Count == len(text))
+	tu.Assert(t, lineAdvance(line.Line) <= fixed.I(120))
+	// the kept text is balanced
+	tu.Assert(t, head.Advance <= fixed.I(60) && tail.Advance <= fixed.I(60))
+	diff := head.Advance - tail.Advance
+	tu.Assert(t, abs(diff) <= fixed.I(10))
+}
+
+func TestTruncateGraphemes(t *testing.T) {
+	// decomposed accents, which must not be separated from their base
+	text := []rune("he\u0301le\u0301phe\u0301rique\u0300 a\u0300 la\u0300 mode")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	for _, position := range []TruncatePosition{TruncateStart, TruncateMiddle} {
+		config := WrapConfig{TruncateAfterLines: 1, Truncator: truncator, TruncatePosition: position}
+		for width := 20; width < 120; width += 3 {
+			lines := wrapElided(config, fixed.I(width), text, []Output{out.copy()})
+			tu.Assert(t, len(lines) == 1)
+			elided := lines[0].Elided
+			tu.Assert(t, elided.Count > 0)
+			end := elided.Offset + elided.Count
+			tu.Assert(t, !unicode.Is(unicode.Mn, text[elided.Offset]))
+			tu.Assert(t, end == len(text) || !unicode.Is(unicode.Mn, text[end]))
+		}
+	}
+}
+
+func TestTruncateMandatoryBreak(t *testing.T) {
+	text := []rune("first\nsecond")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	config := WrapConfig{TruncateAfterLines: 1, Truncator: truncator, TruncatePosition: TruncateMiddle}
+
+	// the mandatory break is always removed
+	lines := wrapElided(config, fixed.I(1000), text, []Output{out.copy()})
+	tu.Assert(t, len(lines) == 1)
+	checkRuneCounts(t, text, linesOf(lines), 0)
+	tu.Assert(t, lines[0].Elided == Range{Offset: 5, Count: 1})
+
+	config.TruncatePosition = TruncateStart
+	lines = wrapElided(config, fixed.I(1000), text, []Output{out.copy()})
+	tu.Assert(t, lines[0].Elided == Range{Offset: 0, Count: 6})
+}
+
+func TestTruncateBidi(t *testing.T) {
+	text := []rune("مرحبا بالعالم مرحبا بالعالم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL,
+		Face: benchArFace, Size: fixed.I(16), Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	truncator := shapeLatin([]rune("…"))
+	config := WrapConfig{
+		Direction: di.DirectionRTL, TruncateAfterLines: 1,
+		Truncator: truncator, TruncatePosition: TruncateMiddle,
+	}
+	lines := wrapElided(config, out.Advance/2, text, []Output{out})
+	tu.Assert(t, len(lines) == 1)
+	line := lines[0].Line
+	checkRuneCounts(t, text, linesOf(lines), 0)
+	tu.Assert(t, len(line) == 3)
+	// the start of the line is on the right
+	tu.Assert(t, line[0].VisualIndex == 2 && line[1].VisualIndex == 1 && line[2].VisualIndex == 0)
+	tu.Assert(t, lineAdvance(line) <= out.Advance/2)
+}
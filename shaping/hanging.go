@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/math/fixed"
+)
+
+// HangingPunctuation selects the punctuation which is placed outside of the line
+// bounds, as the CSS 'hanging-punctuation' property does.
+// The values may be combined.
+//
+// A hanging glyph is not taken into account when choosing the line breaks, and
+// when justifying the line. The glyph positions are not modified : instead, the amount
+// by which a line extends beyond its bounds is reported in
+// [WrappedLine.StartOverhang] and [WrappedLine.EndOverhang].
+//
+// See https://www.w3.org/TR/css-text-3/#hanging-punctuation-property
+type HangingPunctuation uint8
+
+const (
+	// HangFirst hangs an opening bracket or quote at the start of the
+	// first line of the paragraph.
+	HangFirst HangingPunctuation = 1 << iota
+	// HangLast hangs a closing bracket or quote at the end of the
+	// last line of the paragraph.
+	HangLast
+	// HangAllowEnd hangs a stop or comma at the end of a line, if
+	// the line does not fit otherwise.
+	HangAllowEnd
+	// HangForceEnd always hangs a stop or comma at the end of a line.
+	HangForceEnd
+)
+
+// isOpeningPunctuation matches the punctuation hanging at the start of a paragraph
+func isOpeningPunctuation(r rune) bool {
+	return r == '"' || r == '\'' || unicode.In(r, unicode.Ps, unicode.Pi, unicode.Pf)
+}
+
+// isClosingPunctuation matches the punctuation hanging at the end of a paragraph
+func isClosingPunctuation(r rune) bool {
+	return r == '"' || r == '\'' || unicode.In(r, unicode.Pe, unicode.Pi, unicode.Pf)
+}
+
+// isStopOrComma matches the punctuation hanging at the end of lines
+func isStopOrComma(r rune) bool {
+	switch r {
+	case ',', '.', '\u060C', '\u06D4', '\u3001', '\u3002', '\uFF0C', '\uFF0E',
+		'\uFE50', '\uFE51', '\uFE52', '\uFF61', '\uFF64':
+		return true
+	}
+	return false
+}
+
+// hanger stores the paragraph information used to compute
+// hanging punctuation and optical margins.
+type hanger struct {
+	punctuation HangingPunctuation
+	optical     bool
+	direction   di.Direction
+	text        []rune
+}
+
+func (h *hanger) prepare(config WrapConfig, paragraph []rune) {
+	h.punctuation = config.HangingPunctuation
+	h.optical = config.OpticalMargins
+	h.direction = config.Direction
+	h.text = paragraph
+}
+
+// clusterAdvance returns the (absolute) advance of the glyphs
+// of the cluster starting at the rune [index].
+func clusterAdvance(runs []Output, index int) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	for _, run := range runs {
+		if index < run.Runes.Offset || index >= run.Runes.Offset+run.Runes.Count {
+			continue
+		}
+		for _, g := range run.Glyphs {
+			if g.ClusterIndex == index {
+				advance += abs(g.Advance)
+			}
+		}
+	}
+	return advance
+}
+
+// startRune returns true if the first rune of the line starting at [start] hangs.
+func (h *hanger) startRune(start int) bool {
+	return start == 0 && h.punctuation&HangFirst != 0 && len(h.text) != 0 && isOpeningPunctuation(h.text[0])
+}
+
+// endRune returns the index of the rune which may hang at the end of the line [start, end),
+// ignoring trailing whitespace, or -1. [forced] is false if the rune only hangs
+// when the line does not fit otherwise.
+func (h *hanger) endRune(start, end int) (index int, forced bool) {
+	if end > len(h.text) {
+		end = len(h.text)
+	}
+	e := end - 1
+	for e >= start && isWhitespace(h.text[e]) {
+		e--
+	}
+	if e < start {
+		return -1, false
+	}
+	r := h.text[e]
+	if h.punctuation&HangLast != 0 && end == len(h.text) && isClosingPunctuation(r) {
+		return e, true
+	}
+	if isStopOrComma(r) {
+		if h.punctuation&HangForceEnd != 0 {
+			return e, true
+		} else if h.punctuation&HangAllowEnd != 0 {
+			return e, false
+		}
+	}
+	return -1, false
+}
+
+// candidateHang returns the advance of the hanging punctuation of the line candidate
+// made of [runs] followed by [last], starting at [start] and ending after [breakAtRune].
+// If [truncating] is true, the end of the line is not considered.
+func (h *hanger) candidateHang(start, breakAtRune int, runs []Output, last Output, truncating bool) fixed.Int26_6 {
+	var hang fixed.Int26_6
+	if h.startRune(start) {
+		hang += clusterAdvance(runs, start) + clusterAdvance([]Output{last}, start)
+	}
+	if truncating {
+		return hang
+	}
+	if e, _ := h.endRune(start, breakAtRune+1); e != -1 {
+		hang += clusterAdvance(runs, e) + clusterAdvance([]Output{last}, e)
+	}
+	return hang
+}
+
+// overhangs returns the amounts by which [line] (whose visual order must have been computed)
+// extends beyond its start and end edges. If [truncated] is true, the line ends
+// with a truncator, which never hangs.
+func (h *hanger) overhangs(line Line, maxWidth fixed.Int26_6, truncated bool) (start, end fixed.Int26_6) {
+	if len(line) == 0 {
+		return 0, 0
+	}
+	lineStart, lineEnd := lineRunes(line)
+	if h.startRune(lineStart) {
+		start = clusterAdvance(line, lineStart)
+	}
+	if e, forced := h.endRune(lineStart, lineEnd); e != -1 && !truncated {
+		if forced || lineExtent(line)-start > maxWidth {
+			end = clusterAdvance(line, e)
+		}
+	}
+
+	if !h.optical {
+		return start, end
+	}
+	before, after := opticalOverhangs(line, h.direction.IsVertical())
+	if h.direction.Progression() == di.TowardTopLeft {
+		before, after = after, before
+	}
+	if start == 0 {
+		start = before
+	}
+	if end == 0 {
+		end = after
+	}
+	return start, end
+}
+
+// lineExtent returns the sum of the (absolute) advances of the runs of [line].
+func lineExtent(line Line) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	for _, run := range line {
+		advance += abs(run.Advance)
+	}
+	return advance
+}
+
+// opticalOverhangs returns the optical margins of the first and last visible glyphs
+// of [line], in visual order.
+func opticalOverhangs(line Line, vertical bool) (before, after fixed.Int26_6) {
+	first, last := -1, -1
+	for visual := 0; visual < len(line) && first == -1; visual++ {
+		for i := range line {
+			if int(line[i].VisualIndex) != visual {
+				continue
+			}
+			for j, g := range line[i].Glyphs {
+				if g.Advance != 0 {
+					first = i
+					before = opticalMargin(&line[i], line[i].Glyphs[j].GlyphID, vertical, true)
+					break
+				}
+			}
+		}
+	}
+	for visual := len(line) - 1; visual >= 0 && last == -1; visual-- {
+		for i := range line {
+			if int(line[i].VisualIndex) != visual {
+				continue
+			}
+			glyphs := line[i].Glyphs
+			for j := len(glyphs) - 1; j >= 0; j-- {
+				if glyphs[j].Advance != 0 {
+					last = i
+					after = opticalMargin(&line[i], glyphs[j].GlyphID, vertical, false)
+					break
+				}
+			}
+		}
+	}
+	return before, after
+}
+
+var (
+	tagLfbd = ot.MustNewTag("lfbd")
+	tagRtbd = ot.MustNewTag("rtbd")
+)
+
+// opticalMargin returns the amount by which [glyph] may extend beyond the line
+// bounds, on its left (or top) side if [before] is true, or on its right (or bottom) side.
+//
+// The AAT 'opbd' table is used if present (only the distance format is supported),
+// and the GPOS 'lfbd' and 'rtbd' features otherwise.
+func opticalMargin(run *Output, glyph font.GID, vertical, before bool) fixed.Int26_6 {
+	if run.Face == nil {
+		return 0
+	}
+	ft := run.Face.Font
+	if bounds, ok := ft.Opbd.Bounds(tables.GlyphID(glyph)); ok {
+		if ft.Opbd.Format != 0 {
+			return 0
+		}
+		var v int16
+		switch {
+		case vertical && before:
+			v = bounds.Top
+		case vertical:
+			v = -bounds.Bottom
+		case before:
+			v = bounds.Left
+		default:
+			v = -bounds.Right
+		}
+		return run.FromFontUnit(float32(v))
+	}
+	if vertical {
+		return 0
+	}
+	if before {
+		value, ok := singlePosition(&ft.GPOS, tagLfbd, glyph)
+		if !ok {
+			return 0
+		}
+		v := value.XPlacement
+		if v == 0 {
+			v = value.XAdvance
+		}
+		return run.FromFontUnit(float32(-v))
+	}
+	value, ok := singlePosition(&ft.GPOS, tagRtbd, glyph)
+	if !ok {
+		return 0
+	}
+	return run.FromFontUnit(float32(-value.XAdvance))
+}
+
+// singlePosition returns the adjustment applied to [glyph] by the first single positioning
+// lookup of [feature] covering it, or false if not found.
+func singlePosition(gpos *font.GPOS, feature ot.Tag, glyph font.GID) (tables.ValueRecord, bool) {
+	index, ok := gpos.FindFeatureIndex(feature)
+	if !ok {
+		return tables.ValueRecord{}, false
+	}
+	for _, lookupIndex := range gpos.Features[index].LookupListIndices {
+		if int(lookupIndex) >= len(gpos.Lookups) {
+			continue
+		}
+		for _, subtable := range gpos.Lookups[lookupIndex].Subtables {
+			single, ok := subtable.(tables.SinglePos)
+			if !ok {
+				continue
+			}
+			coverageIndex, ok := single.Cov().Index(tables.GlyphID(glyph))
+			if !ok {
+				continue
+			}
+			switch data := single.Data.(type) {
+			case tables.SinglePosData1:
+				return data.ValueRecord, true
+			case tables.SinglePosData2:
+				if coverageIndex < len(data.ValueRecords) {
+					return data.ValueRecords[coverageIndex], true
+				}
+			}
+		}
+	}
+	return tables.ValueRecord{}, false
+}
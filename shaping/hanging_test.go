@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func wrapLines(config WrapConfig, maxWidth fixed.Int26_6, text []rune, out Output) []WrappedLine {
+	var (
+		wrapper LineWrapper
+		lines   []WrappedLine
+	)
+	wrapper.Prepare(config, text, NewSliceIterator([]Output{out}))
+	for done := false; !done; {
+		var line WrappedLine
+		line, done = wrapper.WrapNextLineF(maxWidth)
+		if line.Line != nil {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestHangingPunctuation(t *testing.T) {
+	text := []rune("“Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.”")
+	out := shapeLatin(text)
+	advance := func(r rune) fixed.Int26_6 {
+		for _, g := range out.Glyphs {
+			if text[g.ClusterIndex] == r {
+				return g.Advance
+			}
+		}
+		return 0
+	}
+
+	config := WrapConfig{HangingPunctuation: HangFirst | HangLast | HangForceEnd}
+	lines := wrapLines(config, fixed.I(100), text, out.copy())
+	tu.Assert(t, len(lines) > 3)
+	commas := 0
+	for i, line := range lines {
+		tu.Assert(t, lineAdvance(line.Line)-line.StartOverhang-line.EndOverhang <= fixed.I(100))
+		if i == 0 {
+			tu.Assert(t, line.StartOverhang == advance('“'))
+		} else {
+			tu.Assert(t, line.StartOverhang == 0)
+		}
+		_, end := lineRunes(line.Line)
+		switch last := text[lastNonSpace(text, end)]; {
+		case i == len(lines)-1:
+			tu.Assert(t, line.EndOverhang == advance('”'))
+		case last == ',' || last == '.':
+			commas++
+			tu.Assert(t, line.EndOverhang == advance(last))
+		default:
+			tu.Assert(t, line.EndOverhang == 0)
+		}
+	}
+	tu.Assert(t, commas > 0)
+
+	// no hanging by default
+	for _, line := range wrapLines(WrapConfig{}, fixed.I(100), text, out.copy()) {
+		tu.Assert(t, line.StartOverhang == 0 && line.EndOverhang == 0)
+	}
+}
+
+func advanceOf(glyphs []Glyph) (advance fixed.Int26_6) {
+	for _, g := range glyphs {
+		advance += g.Advance
+	}
+	return advance
+}
+
+func lastNonSpace(text []rune, end int) int {
+	for end > 0 && isWhitespace(text[end-1]) {
+		end--
+	}
+	return end - 1
+}
+
+func TestHangingPunctuationEnd(t *testing.T) {
+	text := []rune("aaaa, bbbb")
+	out := shapeLatin(text)
+	comma := out.Glyphs[4].Advance
+	maxWidth := out.Glyphs[0].Advance*4 + comma/2
+
+	// the comma only fits when hanging
+	lines := wrapLines(WrapConfig{}, maxWidth, text, out.copy())
+	tu.Assert(t, lines[0].NextLine == 4)
+	lines = wrapLines(WrapConfig{HangingPunctuation: HangAllowEnd}, maxWidth, text, out.copy())
+	tu.Assert(t, lines[0].NextLine == 6)
+	tu.Assert(t, lines[0].EndOverhang == comma)
+
+	// allow-end only hangs when required
+	maxWidth += comma
+	lines = wrapLines(WrapConfig{HangingPunctuation: HangAllowEnd}, maxWidth, text, out.copy())
+	tu.Assert(t, lines[0].NextLine == 6 && lines[0].EndOverhang == 0)
+	lines = wrapLines(WrapConfig{HangingPunctuation: HangForceEnd}, maxWidth, text, out.copy())
+	tu.Assert(t, lines[0].NextLine == 6 && lines[0].EndOverhang == comma)
+
+	// the hanging comma is not included in the justified width
+	config := WrapConfig{HangingPunctuation: HangForceEnd, Justification: Justification{Enabled: true}}
+	text = []rune("aa bb, cc dd")
+	out = shapeLatin(text)
+	maxWidth = advanceOf(out.Glyphs[:6]) + fixed.I(2)
+	lines = wrapLines(config, maxWidth, text, out.copy())
+	tu.Assert(t, len(lines) == 2 && lines[0].NextLine == 7 && lines[0].EndOverhang == comma)
+	tu.Assert(t, lineAdvance(lines[0].Line) == maxWidth+comma)
+}
+
+func TestOpticalMargins(t *testing.T) {
+	face, err := font.ParseTTF(bytes.NewReader(goregular.TTF))
+	tu.AssertNoErr(t, err)
+	text := []rune("Tab.")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: face, Size: fixed.I(16), Script: language.Latin,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	tGlyph, dotGlyph := out.Glyphs[0].GlyphID, out.Glyphs[3].GlyphID
+	unit := out.FromFontUnit(100)
+
+	config := WrapConfig{OpticalMargins: true}
+	lines := wrapLines(config, fixed.I(100), text, out)
+	tu.Assert(t, lines[0].StartOverhang == 0 && lines[0].EndOverhang == 0)
+
+	// GPOS 'lfbd' and 'rtbd' features
+	single := func(glyph font.GID, xPlacement, xAdvance int16) tables.GPOSLookup {
+		lookup, _, err := tables.ParseSinglePos([]byte{
+			0, 1, 0, 10, 0, 5, // format 1, coverage offset, XPlacement | XAdvance
+			byte(uint16(xPlacement) >> 8), byte(xPlacement), byte(uint16(xAdvance) >> 8), byte(xAdvance),
+			0, 1, 0, 1, byte(glyph >> 8), byte(glyph), // coverage
+		})
+		tu.AssertNoErr(t, err)
+		return lookup
+	}
+	face.GPOS = font.GPOS{
+		Layout: font.Layout{Features: []font.Feature{
+			{Tag: tagLfbd, Feature: tables.Feature{LookupListIndices: []uint16{0}}},
+			{Tag: tagRtbd, Feature: tables.Feature{LookupListIndices: []uint16{1}}},
+		}},
+		Lookups: []font.GPOSLookup{
+			{Subtables: []tables.GPOSLookup{single(tGlyph, -100, -100)}},
+			{Subtables: []tables.GPOSLookup{single(dotGlyph, 0, -200)}},
+		},
+	}
+	lines = wrapLines(config, fixed.I(100), text, out)
+	tu.Assert(t, lines[0].StartOverhang == unit && lines[0].EndOverhang == 2*unit)
+
+	// for right-to-left paragraphs, the start edge is the right one
+	config.Direction = di.DirectionRTL
+	lines = wrapLines(config, fixed.I(100), text, out)
+	tu.Assert(t, lines[0].StartOverhang == 2*unit && lines[0].EndOverhang == unit)
+
+	// AAT 'opbd' table takes precedence
+	config.Direction = di.DirectionLTR
+	face.Opbd, _, err = tables.ParseOpbd([]byte{
+		0, 1, 0, 0, 0, 0, // version, format
+		0, 8, byte(tGlyph >> 8), byte(tGlyph), 0, 1, 0, 14, // lookup
+		0, 50, 0, 0, 0, 0, 0, 0, // left 50
+	}, 1000)
+	tu.AssertNoErr(t, err)
+	lines = wrapLines(config, fixed.I(100), text, out)
+	tu.Assert(t, lines[0].StartOverhang == unit/2 && lines[0].EndOverhang == 2*unit)
+
+	// hanging punctuation takes precedence
+	config.HangingPunctuation = HangForceEnd
+	lines = wrapLines(config, fixed.I(100), text, out)
+	tu.Assert(t, lines[0].EndOverhang == out.Glyphs[3].Advance)
+}
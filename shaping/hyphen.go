@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sort"
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/language"
+	"golang.org/x/image/math/fixed"
+)
+
+// softHyphen is U+00AD SOFT HYPHEN, marking an hyphenation point
+// which is only visible when the line is broken there.
+const softHyphen = '\u00AD'
+
+// Hyphenator provides the hyphenation points of words, for instance using the
+// patterns of the [github.com/go-text/typesetting/hyphenation] package.
+//
+// When a line is broken at an hyphenation point, an hyphen is inserted
+// at the end of the line, as an additional [Output], with an empty rune range starting
+// after the last rune of the line. The hyphen is shaped with the face of the
+// preceding run, using the font hyphen-minus glyph, or the U+2010 HYPHEN glyph
+// if the font does not support U+002D.
+//
+// Soft hyphens (U+00AD) found in the text are always used as hyphenation points,
+// and the words containing them are not hyphenated by the [Hyphenator].
+type Hyphenator interface {
+	// Hyphenate returns the positions where [word] may be hyphenated,
+	// in increasing order. A position i means that an hyphen may
+	// be inserted between word[i-1] and word[i].
+	// The word only contains letters and combining marks.
+	Hyphenate(word []rune) []int
+}
+
+// hasSoftHyphen returns true if [text] contains a soft hyphen.
+func hasSoftHyphen(text []rune) bool {
+	for _, r := range text {
+		if r == softHyphen {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordRune returns true for the runes hyphenated together.
+func isWordRune(r rune) bool { return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) }
+
+// appendHyphenationPoints appends the hyphenation points of the words of [segment],
+// which starts at [offset].
+func appendHyphenationPoints(dst []breakOption, hyphenator Hyphenator, offset int, segment []rune) []breakOption {
+	for start := 0; start < len(segment); {
+		if !isWordRune(segment[start]) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(segment) && isWordRune(segment[end]) {
+			end++
+		}
+		for _, p := range hyphenator.Hyphenate(segment[start:end]) {
+			if 0 < p && p < end-start {
+				dst = append(dst, breakOption{breakAtRune: offset + start + p - 1, hyphen: true})
+			}
+		}
+		start = end
+	}
+	return dst
+}
+
+// languageSpan is a part of the text with the same language,
+// ending at the start of the next span.
+type languageSpan struct {
+	start int
+	lang  language.Language
+}
+
+// runLanguages returns the language spans of the text shaped by [runs],
+// leaving the iterator unchanged.
+func runLanguages(runs RunIterator) []languageSpan {
+	var out []languageSpan
+	runs.Save()
+	for {
+		_, run, ok := runs.Next()
+		if !ok {
+			break
+		}
+		if len(out) == 0 || out[len(out)-1].lang != run.Language {
+			out = append(out, languageSpan{start: run.Runes.Offset, lang: run.Language})
+		}
+	}
+	runs.Restore()
+	return out
+}
+
+// hyphenatorOf returns the hyphenator to use for [lang], or nil.
+func (b *breaker) hyphenatorOf(lang language.Language) Hyphenator {
+	if b.hyphenatorFor == nil {
+		return b.hyphenator
+	}
+	if !b.hasLast || lang != b.lastLanguage {
+		b.lastLanguage, b.lastHyphenator, b.hasLast = lang, b.hyphenatorFor(lang), true
+	}
+	return b.lastHyphenator
+}
+
+// appendHyphenationPoints adds the hyphenation points of [segment], which starts
+// at [offset], to the pending break options, using the hyphenator of each language span.
+func (b *breaker) appendHyphenationPoints(offset int, segment []rune) {
+	if b.hyphenatorFor == nil {
+		if b.hyphenator != nil {
+			b.pendingWordBreaks = appendHyphenationPoints(b.pendingWordBreaks, b.hyphenator, offset, segment)
+		}
+		return
+	}
+	end := offset + len(segment)
+	// index of the span containing offset
+	i := sort.Search(len(b.languages), func(i int) bool { return b.languages[i].start > offset }) - 1
+	for start := offset; start < end; i++ {
+		spanEnd := end
+		if i+1 < len(b.languages) && b.languages[i+1].start < end {
+			spanEnd = b.languages[i+1].start
+		}
+		var lang language.Language
+		if i >= 0 {
+			lang = b.languages[i].lang
+		}
+		if h := b.hyphenatorOf(lang); h != nil {
+			b.pendingWordBreaks = appendHyphenationPoints(b.pendingWordBreaks, h, start, segment[start-offset:spanEnd-offset])
+		}
+		start = spanEnd
+	}
+}
+
+// hyphenRune returns the rune used to render an hyphen with [face]
+func hyphenRune(face *font.Face) rune {
+	if face != nil {
+		if _, ok := face.NominalGlyph('-'); !ok {
+			return '\u2010'
+		}
+	}
+	return '-'
+}
+
+// shapeHyphen returns the hyphen glyph for [run], using [shaper].
+func shapeHyphen(shaper *HarfbuzzShaper, run Output) Output {
+	dir := run.Direction
+	if dir.IsSideways() {
+		dir = dir.SwitchAxis()
+	}
+	input := Input{
+		Text:      []rune{hyphenRune(run.Face)},
+		RunEnd:    1,
+		Direction: dir,
+		Face:      run.Face,
+		Size:      run.Size,
+		Script:    language.Common,
+	}
+	out := shaper.Shape(input)
+	if run.Direction.IsSideways() {
+		out.sideways()
+	}
+	return out
+}
+
+// shapeHyphen returns the hyphen glyph for the run at [index], shaped
+// with the settings of its input, except the ranged font features.
+func (r *reshapingRunSlice) shapeHyphen(index int) Output {
+	input := r.inputs[index]
+	input.Text = []rune{hyphenRune(input.Face)}
+	input.RunStart, input.RunEnd = 0, 1
+	input.FontFeatures = nil
+	for _, feature := range r.inputs[index].FontFeatures {
+		if !feature.isRanged() {
+			input.FontFeatures = append(input.FontFeatures, feature)
+		}
+	}
+	return r.shaper.Shape(input)
+}
+
+// hyphenKey identifies a cached hyphen
+type hyphenKey struct {
+	// runIndex is the index of the run for hyphens shaped
+	// with the input settings, or -1
+	runIndex  int
+	face      *font.Face
+	size      fixed.Int26_6
+	direction di.Direction
+}
+
+// hyphenFor returns the (cached) hyphen to insert after [run], with index [runIndex].
+// When possible, the hyphen is shaped with the same font features as [run].
+func (l *LineWrapper) hyphenFor(runIndex int, run Output) Output {
+	reshaper, withInputs := l.glyphRuns.(*reshapingRunSlice)
+	if !withInputs || runIndex < 0 || runIndex >= len(reshaper.inputs) {
+		runIndex = -1
+	}
+	key := hyphenKey{runIndex, run.Face, run.Size, run.Direction}
+	if hyphen, ok := l.hyphens[key]; ok {
+		return hyphen
+	}
+	var hyphen Output
+	if runIndex != -1 {
+		hyphen = reshaper.shapeHyphen(runIndex)
+	} else if run.Face != nil {
+		hyphen = shapeHyphen(&l.shaper, run)
+	}
+	if l.hyphens == nil {
+		l.hyphens = make(map[hyphenKey]Output)
+	}
+	l.hyphens[key] = hyphen
+	return hyphen
+}
+
+// hyphenRun returns the hyphen run inserted after [run], which
+// ends with an hyphenation point.
+func hyphenRun(hyphen, run Output) Output {
+	end := run.Runes.Offset + run.Runes.Count
+	hyphen.Runes = Range{Offset: end}
+	// the glyphs refer to the last rune of the line
+	hyphen.Glyphs = append([]Glyph(nil), hyphen.Glyphs...)
+	for i := range hyphen.Glyphs {
+		hyphen.Glyphs[i].ClusterIndex = end - 1
+	}
+	return hyphen
+}
+
+// markCandidateBest marks the current line candidate, ending with [candidateRun],
+// as the best one, inserting an hyphen if required by [option].
+func (l *LineWrapper) markCandidateBest(option breakOption, candidateRun Output) {
+	l.bestHyphenated = option.hyphen
+	l.bestEdge = l.candidateEdge
+	if option.hyphen {
+		l.scratch.markCandidateBest(candidateRun, hyphenRun(l.candidateHyphen, candidateRun))
+	} else {
+		l.scratch.markCandidateBest(candidateRun)
+	}
+}
+
+// markBareCandidateBest marks the current line candidate as the best one,
+// without adding a run.
+func (l *LineWrapper) markBareCandidateBest() {
+	l.bestHyphenated = false
+	l.bestEdge = lineEdge{}
+	l.scratch.markCandidateBest()
+}
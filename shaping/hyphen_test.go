@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/hyphenation"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func testHyphenator(t *testing.T) *hyphenation.Hyphenator {
+	h := hyphenation.NewHyphenator()
+	for _, p := range []string{"hy3ph", "he2n", "hena4", "hen5at", "1na", "n2at", "1tio", "2io", "o2n"} {
+		tu.AssertNoErr(t, h.AddPattern(p))
+	}
+	return h
+}
+
+func TestHyphenationPoints(t *testing.T) {
+	h := testHyphenator(t)
+	text := []rune("(hyphenation), hy")
+	options := appendHyphenationPoints(nil, h, 10, text)
+	tu.Assert(t, reflect.DeepEqual(options, []breakOption{
+		{breakAtRune: 10 + 2, hyphen: true}, // hy-
+		{breakAtRune: 10 + 6, hyphen: true}, // phen-
+	}))
+}
+
+func TestWrapHyphenation(t *testing.T) {
+	text := []rune("hyphenation hyphenation hyphenation")
+	out := shapeLatin(text)
+	hyphenGlyph, _ := benchEnFace.NominalGlyph('-')
+
+	// "hyphenation" is about 80 pixels wide
+	for _, totalFit := range []bool{false, true} {
+		config := WrapConfig{Hyphenator: testHyphenator(t), TotalFit: TotalFit{Enabled: totalFit}}
+		lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(60), text, NewSliceIterator([]Output{out.copy()}))
+		checkRuneCounts(t, text, lines, 0)
+		tu.Assert(t, len(lines) > 3)
+		hyphenated := 0
+		for _, line := range lines {
+			tu.Assert(t, lineAdvance(line) <= fixed.I(60))
+			last := line[len(line)-1]
+			if last.Runes.Count != 0 {
+				continue
+			}
+			hyphenated++
+			// the hyphen is after an hyphenation point
+			tu.Assert(t, len(last.Glyphs) == 1 && last.Glyphs[0].GlyphID == hyphenGlyph)
+			tu.Assert(t, last.Advance > 0)
+			end := last.Runes.Offset
+			tu.Assert(t, text[end-1] == 'y' || text[end-1] == 'n')
+			tu.Assert(t, last.Glyphs[0].ClusterIndex == end-1)
+		}
+		tu.Assert(t, hyphenated > 0)
+	}
+
+	// without hyphenation, the words are broken at grapheme boundaries
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, fixed.I(60), text, NewSliceIterator([]Output{out.copy()}))
+	for _, line := range lines {
+		tu.Assert(t, line[len(line)-1].Runes.Count != 0)
+	}
+
+	// the font used to shape the hyphens is reused across paragraphs
+	var w LineWrapper
+	config := WrapConfig{Hyphenator: testHyphenator(t)}
+	for i := 0; i < 2; i++ {
+		w.WrapParagraphF(config, fixed.I(60), text, NewSliceIterator([]Output{out.copy()}))
+		tu.Assert(t, len(w.shaper.fonts.m) == 1)
+		tu.Assert(t, len(w.hyphens) == 1) // the hyphens are cached per paragraph
+	}
+	key, _ := newFontKey(Input{Face: benchEnFace})
+	font, _ := w.shaper.fonts.Get(key)
+	w.WrapParagraphF(config, fixed.I(60), text, NewSliceIterator([]Output{out.copy()}))
+	font2, _ := w.shaper.fonts.Get(key)
+	tu.Assert(t, font != nil && font == font2)
+}
+
+func TestWrapHyphenationLanguages(t *testing.T) {
+	text := []rune("hyphenation hyphenation hyphenation hyphenation")
+	var (
+		shaper HarfbuzzShaper
+		runs   []Output
+	)
+	for i, lang := range []string{"en", "fr"} {
+		runs = append(runs, shaper.Shape(Input{
+			Text: text, RunStart: 24 * i, RunEnd: 24*i + 24 - i, Direction: di.DirectionLTR,
+			Face: benchEnFace, Size: fixed.I(16), Script: language.Latin, Language: language.NewLanguage(lang),
+		}))
+	}
+	tu.Assert(t, runs[1].Language == language.NewLanguage("fr"))
+
+	// only the English run has hyphenation patterns
+	dicts := hyphenation.Dictionaries{language.NewLanguage("en"): testHyphenator(t)}
+	var calls []language.Language
+	config := WrapConfig{HyphenatorFor: func(lang language.Language) Hyphenator {
+		calls = append(calls, lang)
+		if h := dicts.Lookup(lang); h != nil {
+			return h
+		}
+		return nil
+	}}
+	for _, totalFit := range []bool{false, true} {
+		calls = calls[:0]
+		config.TotalFit.Enabled = totalFit
+		lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(60), text, NewSliceIterator([]Output{runs[0].copy(), runs[1].copy()}))
+		checkRuneCounts(t, text, lines, 0)
+		for _, line := range lines {
+			if last := line[len(line)-1]; last.Runes.Count == 0 { // hyphen
+				tu.Assert(t, last.Runes.Offset < 24)
+			}
+		}
+		tu.Assert(t, len(lines[0]) == 2) // hyphenated
+		tu.Assert(t, len(calls) != 0 && len(calls) <= 4)
+	}
+}
+
+func TestWrapSoftHyphen(t *testing.T) {
+	text := []rune("hyphena\u00ADtion hyphena\u00ADtion hyphena\u00ADtion")
+	out := shapeLatin(text)
+	hyphenGlyph, _ := benchEnFace.NominalGlyph('-')
+
+	// soft hyphens are invisible
+	for _, g := range out.Glyphs {
+		if text[g.ClusterIndex] == '\u00AD' {
+			tu.Assert(t, g.Advance == 0)
+		}
+	}
+
+	// "hyphena-" is about 70 pixels wide
+	for _, totalFit := range []bool{false, true} {
+		// words with soft hyphens are not hyphenated automatically
+		config := WrapConfig{Hyphenator: testHyphenator(t), TotalFit: TotalFit{Enabled: totalFit}}
+		lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(80), text, NewSliceIterator([]Output{out.copy()}))
+		checkRuneCounts(t, text, lines, 0)
+		hyphenated := 0
+		for _, line := range lines {
+			tu.Assert(t, lineAdvance(line) <= fixed.I(80))
+			last := line[len(line)-1]
+			if last.Runes.Count != 0 {
+				tu.Assert(t, text[last.Runes.Offset+last.Runes.Count-1] != '\u00AD')
+				continue
+			}
+			hyphenated++
+			tu.Assert(t, len(last.Glyphs) == 1 && last.Glyphs[0].GlyphID == hyphenGlyph)
+			tu.Assert(t, text[last.Runes.Offset-1] == '\u00AD')
+		}
+		tu.Assert(t, hyphenated == 3)
+	}
+
+	// when the line fits, no hyphen is inserted
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, fixed.I(1000), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(lines) == 1 && len(lines[0]) == 1)
+	tu.Assert(t, lineAdvance(lines[0]) == out.Advance)
+}
+
+func TestSoftHyphenTruncation(t *testing.T) {
+	text := []rune("aaaaaa\u00ADbbbbbbbbbb")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	hyphen := (&LineWrapper{}).hyphenFor(-1, out)
+
+	// leave room for the hyphen, but not for the truncator
+	aWidth := out.Glyphs[0].Advance * 6
+	maxWidth := aWidth + hyphen.Advance
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, maxWidth, text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(lines) > 1)
+	tu.Assert(t, lines[0][len(lines[0])-1].Runes == Range{Offset: 7})
+
+	// the hyphen is replaced by the truncator
+	maxWidth = aWidth + truncator.Advance
+	config := WrapConfig{TruncateAfterLines: 1, Truncator: truncator}
+	lines, truncated := (&LineWrapper{}).WrapParagraphF(config, maxWidth, text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(lines) == 1 && truncated == 10)
+	checkRuneCounts(t, text, lines, truncated)
+	line := lines[0]
+	tu.Assert(t, len(line) == 2)
+	tu.Assert(t, line[1].Runes == Range{Offset: 7, Count: 10})
+	tu.Assert(t, lineAdvance(line) <= maxWidth)
+}
+
+func TestSoftHyphenReshaping(t *testing.T) {
+	text := []rune("aaaaaa\u00ADbbbbbbbbbb")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin,
+		FontFeatures: []FontFeature{{Tag: ot.MustNewTag("liga"), Value: 0}, {Tag: ot.MustNewTag("smcp"), Value: 1, Start: 0, End: 2}},
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	runs := NewReshapingIterator(&HarfbuzzShaper{}, []Input{input}, []Output{out})
+
+	var wrapper LineWrapper
+	wrapper.Prepare(WrapConfig{}, text, runs)
+	hyphen := wrapper.hyphenFor(0, out)
+	_, ok := wrapper.hyphens[hyphenKey{0, out.Face, out.Size, out.Direction}]
+	tu.Assert(t, len(wrapper.hyphens) == 1 && ok)
+	tu.Assert(t, len(hyphen.Glyphs) == 1 && hyphen.Size == out.Size)
+
+	lines, _ := wrapper.WrapParagraphF(WrapConfig{}, out.Glyphs[0].Advance*8, text, runs)
+	checkRuneCounts(t, text, lines, 0)
+	last := lines[0][len(lines[0])-1]
+	tu.Assert(t, last.Runes == Range{Offset: 7} && last.Glyphs[0].GlyphID == hyphen.Glyphs[0].GlyphID)
+}
@@ -27,9 +27,30 @@ type Input struct {
 
 	// FontFeatures activates or deactivates optional features
 	// provided by the font.
-	// The settings are applied to the whole [Text].
+	// The settings are applied to the whole [Text], unless
+	// a range is specified (see [FontFeature.Start]).
+	// If two features have the same tag but overlapping ranges,
+	// the value of the feature with the higher index takes precedence.
 	FontFeatures []FontFeature
 
+	// Variations are font variation settings (in design units), applied
+	// on top of the settings of [Face], for this shaping call only :
+	// the axes not specified keep the value set on [Face], which is not modified.
+	// The resulting [Output.Face] is then a face with the variations applied.
+	Variations []font.Variation
+
+	// AutoOpticalSize, if true, sets the 'opsz' axis of variable fonts to match [Size],
+	// as the CSS 'font-optical-sizing: auto' property does.
+	// As in CSS, [Size] is then expected to be expressed in points (or CSS px).
+	// An 'opsz' value specified in [Variations] takes precedence.
+	AutoOpticalSize bool
+
+	// GlyphFlags selects the optional flags computed during shaping, among
+	// [GlyphUnsafeToConcat] and [GlyphSafeToInsertTatweel] ([GlyphUnsafeToBreak] is always computed).
+	// Note that requesting [GlyphSafeToInsertTatweel] also marks the
+	// corresponding positions as unsafe to break.
+	GlyphFlags GlyphFlags
+
 	// Size is the requested size of the font.
 	// More generally, it is a scale factor applied to the resulting metrics.
 	// For instance, given a device resolution (in dpi) and a point size (like 14), the `Size` to
@@ -60,8 +81,21 @@ type Input struct {
 type FontFeature struct {
 	Tag   ot.Tag
 	Value uint32
+
+	// Start and End, if End > Start, restrict the feature
+	// to the runes Text[Start:End], where Text is the [Input.Text] slice.
+	// Since the indices refer to the whole text, and not to the run,
+	// ranged features are preserved by [Segmenter.Split], and shaping is
+	// not interrupted at the range boundaries (kerning and contextual substitutions
+	// still apply across them).
+	//
+	// The default zero values apply the feature to the whole text.
+	Start, End int
 }
 
+// isRanged returns true if the feature only applies to a range of the text.
+func (ff FontFeature) isRanged() bool { return ff.End > ff.Start }
+
 // Fontmap provides a general mechanism to select
 // a face to use when shaping text.
 type Fontmap interface {
@@ -195,10 +229,12 @@ func (seg *Segmenter) reset() {
 	for i := range seg.input {
 		seg.input[i].Text = nil
 		seg.input[i].FontFeatures = nil
+		seg.input[i].Variations = nil
 	}
 	for i := range seg.output {
 		seg.output[i].Text = nil
 		seg.output[i].FontFeatures = nil
+		seg.output[i].Variations = nil
 	}
 	seg.input = seg.input[:0]
 	seg.output = seg.output[:0]
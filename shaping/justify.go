@@ -0,0 +1,368 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sort"
+
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/segmenter"
+	"golang.org/x/image/math/fixed"
+)
+
+// Justification configures how wrapped lines are expanded
+// to fill the maximum width.
+//
+// Every line is justified, except the last line of the paragraph, the lines ending
+// with a mandatory break, and the truncated line, which are left unchanged.
+//
+// The space is distributed across the word separators found between two words
+// (according to UAX#29 word boundaries). For scripts which do not use word separators,
+// like Chinese, Japanese or Thai, the space is also distributed between characters.
+// If the line has no such justification opportunity (or if [MaxStretch] is reached),
+// the remaining space is distributed between all the clusters, as letter spacing.
+//
+// Trailing whitespace, which, for bidirectional text, may be visually placed
+// at the start of the line, is never expanded.
+type Justification struct {
+	// Enabled turns on justification.
+	Enabled bool
+	// MaxStretch is the maximum space added at each justification opportunity,
+	// expressed in em (that is, as a ratio of the font size).
+	// A zero value means no limit.
+	MaxStretch float32
+	// MinStretch is the (negative) space that may be added to word separators
+	// to shrink a line wider than the maximum width, which happens
+	// when it can't be broken (see for instance the [Never] break policy).
+	// It is expressed in em, and a zero value disables shrinking.
+	// Word separators are never shrunk below a zero advance.
+	MinStretch float32
+	// DisableLetterSpacing prevents expanding the space between clusters
+	// when the regular justification opportunities are not sufficient.
+	DisableLetterSpacing bool
+	// Kashida, if true, first expands the Arabic words by inserting tatweel (kashida) glyphs,
+	// before using the other justification opportunities.
+	// Tatweels are only inserted in runs shaped with the [GlyphSafeToInsertTatweel] flag
+	// (see [Input.GlyphFlags]), at cluster boundaries where inserting a tatweel would not
+	// change the shaping result. For each word, only the position with the highest priority is used,
+	// according to the following rules :
+	//   - after a Seen or Sad form (or after an existing tatweel)
+	//   - before the final form of Teh Marbuta, Heh or Dal
+	//   - before the final form of Alef, Tah, Lam, Kaf or Gaf
+	//   - before the final form of Reh or Yeh, preceded by a Beh form
+	//   - before the final form of Waw, Ain, Qaf or Feh
+	//   - before the final form of any other letter
+	//
+	// If the runs are provided by [NewReshapingIterator], the Arabic runs are also
+	// re-shaped with the 'jalt' (justification alternates) feature, for fonts supporting it,
+	// before inserting tatweels.
+	Kashida bool
+}
+
+// isCharacterJustified returns true for the scripts which do not
+// use word separators, and are justified between characters.
+func isCharacterJustified(r rune) bool {
+	switch language.LookupScript(r) {
+	case language.Han, language.Hiragana, language.Katakana, language.Bopomofo, language.Yi,
+		language.Thai, language.Lao, language.Khmer, language.Myanmar:
+		return true
+	}
+	return false
+}
+
+// isMandatoryBreak returns true for the runes ending a line
+func isMandatoryBreak(r rune) bool {
+	switch r {
+	case '\n', '\r', '\v', '\f', '\u0085', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+// isWordSeparator matches the separators expanded by [Output.AddWordSpacing]
+func isWordSeparator(r rune) bool {
+	switch r {
+	case '\u0020', '\u00A0', '\u1361', '\U00010100', '\U00010101', '\U0001039F', '\U0001091F':
+		return true
+	}
+	return false
+}
+
+// justifier stores the paragraph information used to justify lines.
+type justifier struct {
+	config Justification
+	text   []rune
+	// words are the word boundaries of the paragraph, sorted
+	words []Range
+
+	// clusters is a scratch buffer used when justifying a line
+	clusters []visualCluster
+	// kashida inserts tatweels, if enabled
+	kashida kashidaJustifier
+}
+
+// prepare segments the paragraph, which must have been used to initialize [seg].
+func (j *justifier) prepare(config Justification, seg *segmenter.Segmenter, paragraph []rune) {
+	j.config = config
+	j.text = paragraph
+	j.words = j.words[:0]
+	iter := seg.WordIterator()
+	for iter.Next() {
+		word := iter.Word()
+		j.words = append(j.words, Range{Offset: word.Offset, Count: len(word.Text)})
+	}
+}
+
+// innerRange returns the range of runes strictly between the first and
+// the last words overlapping [start, end).
+func (j *justifier) innerRange(start, end int) (innerStart, innerEnd int) {
+	// first word ending after start
+	i := sort.Search(len(j.words), func(i int) bool { return j.words[i].Offset+j.words[i].Count > start })
+	if i == len(j.words) || j.words[i].Offset >= end {
+		return end, end
+	}
+	innerStart = j.words[i].Offset + j.words[i].Count
+	// last word starting before end
+	i = sort.Search(len(j.words), func(i int) bool { return j.words[i].Offset >= end }) - 1
+	innerEnd = j.words[i].Offset
+	return innerStart, innerEnd
+}
+
+// visualCluster is a cluster of a line, in visual order
+type visualCluster struct {
+	run                  int // index in the line
+	glyphStart, glyphEnd int // in the run
+	// separator is true for word separators between two words
+	separator bool
+	// characterJustified is true for clusters in scripts justified between characters
+	characterJustified bool
+}
+
+// lineRunes returns the runes range covered by [line]
+func lineRunes(line Line) (start, end int) {
+	start, end = line[0].Runes.Offset, line[0].Runes.Offset+line[0].Runes.Count
+	for _, run := range line[1:] {
+		if run.Runes.Offset < start {
+			start = run.Runes.Offset
+		}
+		if e := run.Runes.Offset + run.Runes.Count; e > end {
+			end = e
+		}
+	}
+	return start, end
+}
+
+// shouldJustify returns false for empty lines and lines
+// ending with a mandatory break.
+func (j *justifier) shouldJustify(line Line) bool {
+	if len(line) == 0 {
+		return false
+	}
+	_, end := lineRunes(line)
+	return end > 0 && end <= len(j.text) && !isMandatoryBreak(j.text[end-1])
+}
+
+// visualClusters walks the line in visual order, ignoring
+// the trailing whitespace.
+func (j *justifier) visualClusters(line Line) []visualCluster {
+	start, end := lineRunes(line)
+	trailingStart := end
+	for trailingStart > start && isWhitespace(j.text[trailingStart-1]) {
+		trailingStart--
+	}
+	innerStart, innerEnd := j.innerRange(start, trailingStart)
+
+	visualOrder := make([]int, len(line))
+	for i, run := range line {
+		visualOrder[run.VisualIndex] = i
+	}
+
+	clusters := j.clusters[:0]
+	for _, runIndex := range visualOrder {
+		glyphs := line[runIndex].Glyphs
+		for glyphStart := 0; glyphStart < len(glyphs); {
+			glyphEnd := glyphStart + 1
+			for glyphEnd < len(glyphs) && glyphs[glyphEnd].ClusterIndex == glyphs[glyphStart].ClusterIndex {
+				glyphEnd++
+			}
+			cl := visualCluster{run: runIndex, glyphStart: glyphStart, glyphEnd: glyphEnd}
+			glyphStart = glyphEnd
+
+			runeIndex := glyphs[cl.glyphStart].ClusterIndex
+			if runeIndex >= trailingStart || runeIndex >= len(j.text) {
+				continue
+			}
+			r := j.text[runeIndex]
+			cl.separator = isWordSeparator(r) && innerStart <= runeIndex && runeIndex < innerEnd
+			cl.characterJustified = isCharacterJustified(r)
+			clusters = append(clusters, cl)
+		}
+	}
+	j.clusters = clusters
+	return clusters
+}
+
+func isWhitespace(r rune) bool {
+	return isWordSeparator(r) || isMandatoryBreak(r) || r == '\t' || r == '\u3000' || ('\u2000' <= r && r <= '\u200A')
+}
+
+// expansion is a justification opportunity
+type expansion struct {
+	run, glyph int
+	// centered is true for word separators, whose glyph
+	// is centered in the expanded advance
+	centered bool
+	// max is the maximum (absolute) expansion, or a negative value for no limit
+	max fixed.Int26_6
+}
+
+func abs(v fixed.Int26_6) fixed.Int26_6 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// emRatio returns [ratio] em for the given run, or -1 if ratio is 0
+func emRatio(run *Output, ratio float32) fixed.Int26_6 {
+	if ratio == 0 {
+		return -1
+	}
+	if ratio < 0 {
+		ratio = -ratio
+	}
+	return fixed.Int26_6(ratio * float32(run.Size))
+}
+
+// justify expands (or shrinks) the line to [width], modifying the runs in place,
+// with new glyph slices.
+func (j *justifier) justify(line Line, width fixed.Int26_6) {
+	var lineWidth fixed.Int26_6
+	for _, run := range line {
+		lineWidth += abs(run.Advance)
+	}
+	extra := width - lineWidth
+	if extra > 0 && j.config.Kashida {
+		extra = j.kashida.justify(line, j.text, extra)
+	}
+	if extra == 0 || (extra < 0 && j.config.MinStretch == 0) {
+		return
+	}
+
+	clusters := j.visualClusters(line)
+	var opportunities, letters []expansion
+	for i, cl := range clusters {
+		run := &line[cl.run]
+		if cl.separator {
+			exp := expansion{run: cl.run, glyph: cl.glyphStart, centered: true, max: emRatio(run, j.config.MaxStretch)}
+			if extra < 0 {
+				exp.max = emRatio(run, j.config.MinStretch)
+				if adv := abs(run.Glyphs[cl.glyphStart].Advance); exp.max < 0 || exp.max > adv {
+					exp.max = adv
+				}
+			}
+			opportunities = append(opportunities, exp)
+			continue
+		}
+		if i == len(clusters)-1 || extra < 0 {
+			continue
+		}
+		// gap after the cluster (in visual order)
+		next := clusters[i+1]
+		if next.separator {
+			continue
+		}
+		gap := expansion{run: cl.run, glyph: cl.glyphEnd - 1, max: -1}
+		letters = append(letters, gap)
+		if cl.characterJustified || next.characterJustified {
+			gap.max = emRatio(run, j.config.MaxStretch)
+			opportunities = append(opportunities, gap)
+		}
+	}
+
+	modified := make([]bool, len(line))
+	remaining := distribute(line, modified, opportunities, extra)
+	if remaining > 0 && !j.config.DisableLetterSpacing {
+		distribute(line, modified, letters, remaining)
+	}
+	for i, m := range modified {
+		if m {
+			line[i].RecomputeAdvance()
+		}
+	}
+}
+
+// distribute evenly distributes [extra] across [expansions], and
+// returns the space which could not be distributed.
+// The space refused by the expansions reaching their maximum is
+// distributed again on the other ones.
+func distribute(line Line, modified []bool, expansions []expansion, extra fixed.Int26_6) fixed.Int26_6 {
+	if len(expansions) == 0 {
+		return extra
+	}
+	sign := fixed.Int26_6(1)
+	if extra < 0 {
+		sign, extra = -1, -extra
+	}
+	isFull := func(exp expansion, amount fixed.Int26_6) bool { return exp.max >= 0 && amount >= exp.max }
+	amounts := make([]fixed.Int26_6, len(expansions))
+	for extra > 0 {
+		var active fixed.Int26_6
+		for i, exp := range expansions {
+			if !isFull(exp, amounts[i]) {
+				active++
+			}
+		}
+		if active == 0 {
+			break
+		}
+		share := extra / active
+		// the rounding error is distributed on the first expansions
+		rest := extra - share*active
+		var k fixed.Int26_6
+		for i, exp := range expansions {
+			if isFull(exp, amounts[i]) {
+				continue
+			}
+			amount := share
+			if k < rest {
+				amount++
+			}
+			k++
+			if exp.max >= 0 && amounts[i]+amount > exp.max {
+				amount = exp.max - amounts[i]
+			}
+			amounts[i] += amount
+			extra -= amount
+		}
+	}
+
+	for i, exp := range expansions {
+		amount := amounts[i]
+		if amount == 0 {
+			continue
+		}
+		run := &line[exp.run]
+		if !modified[exp.run] {
+			run.Glyphs = append([]Glyph(nil), run.Glyphs...)
+			modified[exp.run] = true
+		}
+		g := &run.Glyphs[exp.glyph]
+		amount *= sign
+		if run.Direction.IsVertical() {
+			// vertical advances are negative
+			g.Advance -= amount
+			g.YAdvance -= amount
+			if exp.centered {
+				g.YOffset -= amount / 2
+			}
+		} else {
+			g.Advance += amount
+			g.XAdvance += amount
+			if exp.centered {
+				g.XOffset += amount / 2
+			}
+		}
+	}
+	return sign * extra
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/segmenter"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func lineAdvance(line Line) fixed.Int26_6 {
+	var advance fixed.Int26_6
+	for _, run := range line {
+		advance += run.Advance
+	}
+	return advance
+}
+
+func TestJustifyLatin(t *testing.T) {
+	text := []rune("The quick brown fox jumps over the lazy dog. Pack my box with five dozen liquor jugs.")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	original := out.copy()
+
+	var wrapper LineWrapper
+	ragged, _ := wrapper.WrapParagraphF(WrapConfig{}, fixed.I(120), text, NewSliceIterator([]Output{original}))
+	raggedAdvances := make([]fixed.Int26_6, len(ragged))
+	for i, line := range ragged {
+		raggedAdvances[i] = lineAdvance(line)
+	}
+
+	config := WrapConfig{Justification: Justification{Enabled: true}}
+	lines, _ := wrapper.WrapParagraphF(config, fixed.I(120), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) == len(ragged) && len(lines) > 2)
+	for i, line := range lines {
+		if i == len(lines)-1 { // last line is not justified
+			tu.Assert(t, lineAdvance(line) == raggedAdvances[i])
+		} else {
+			tu.Assert(t, lineAdvance(line) == fixed.I(120))
+		}
+	}
+	// the input is not modified (besides the trimmed whitespace)
+	for i := range out.Glyphs {
+		tu.Assert(t, out.Glyphs[i] == original.Glyphs[i])
+	}
+
+	// only inner spaces are expanded
+	for _, line := range lines[:len(lines)-1] {
+		run := line[0]
+		for i, g := range run.Glyphs {
+			if g.Advance == original.Glyphs[g.ClusterIndex].Advance {
+				continue
+			}
+			tu.Assert(t, text[g.ClusterIndex] == ' ')
+			tu.Assert(t, i != len(run.Glyphs)-1) // trailing space
+		}
+	}
+}
+
+func TestJustifyMandatoryBreak(t *testing.T) {
+	text := []rune("aaa bbb\nccc ddd eee fff")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+
+	config := WrapConfig{Justification: Justification{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(200), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) == 2)
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) < fixed.I(200))
+	}
+}
+
+func TestJustifyLetterSpacing(t *testing.T) {
+	text := []rune("abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+
+	config := WrapConfig{BreakPolicy: Always, Justification: Justification{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(100), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(lines) > 2)
+	for _, line := range lines[:len(lines)-1] {
+		tu.Assert(t, lineAdvance(line) == fixed.I(100))
+		// the last glyph is not expanded
+		run := line[0]
+		last := run.Glyphs[len(run.Glyphs)-1]
+		tu.Assert(t, last.Advance == out.Glyphs[last.ClusterIndex].Advance)
+	}
+
+	config.Justification.DisableLetterSpacing = true
+	lines, _ = (&LineWrapper{}).WrapParagraphF(config, fixed.I(100), text, NewSliceIterator([]Output{out.copy()}))
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) < fixed.I(100))
+	}
+}
+
+func TestJustifyMaxStretch(t *testing.T) {
+	text := []rune("aaaaaaa b cc dddddddddddddd")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(10), Script: language.Latin,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+
+	justify := func(config Justification) Output {
+		var (
+			seg segmenter.Segmenter
+			j   justifier
+		)
+		seg.Init(text)
+		j.prepare(config, &seg, text)
+		line := Line{out}
+		j.justify(line, out.Advance+fixed.I(100))
+		return line[0]
+	}
+
+	run := justify(Justification{Enabled: true, MaxStretch: 1, DisableLetterSpacing: true})
+	// three inner spaces, each expanded by one em
+	tu.Assert(t, run.Advance == out.Advance+fixed.I(30))
+	tu.Assert(t, run.Glyphs[7].Advance == out.Glyphs[7].Advance+fixed.I(10))
+	tu.Assert(t, run.Glyphs[7].XOffset == fixed.I(5))
+
+	run = justify(Justification{Enabled: true, MaxStretch: 1})
+	tu.Assert(t, run.Advance == out.Advance+fixed.I(100))
+
+	// shrinking
+	var (
+		seg segmenter.Segmenter
+		j   justifier
+	)
+	seg.Init(text)
+	j.prepare(Justification{Enabled: true, MinStretch: -0.1}, &seg, text)
+	line := Line{out}
+	j.justify(line, out.Advance-fixed.I(100))
+	tu.Assert(t, line[0].Advance == out.Advance-fixed.I(3))
+}
+
+func TestDistribute(t *testing.T) {
+	out := shapeLatin([]rune("a b c d"))
+	expansions := []expansion{
+		{glyph: 1, max: fixed.I(2)},
+		{glyph: 3, max: fixed.I(10)},
+		{glyph: 5, max: fixed.I(10)},
+	}
+
+	// the space refused by the first expansion is given to the others
+	line := Line{out.copy()}
+	tu.Assert(t, distribute(line, make([]bool, 1), expansions, fixed.I(18)) == 0)
+	line[0].RecomputeAdvance()
+	tu.Assert(t, line[0].Advance == out.Advance+fixed.I(18))
+	tu.Assert(t, line[0].Glyphs[1].Advance == out.Glyphs[1].Advance+fixed.I(2))
+	tu.Assert(t, line[0].Glyphs[3].Advance == out.Glyphs[3].Advance+fixed.I(8))
+	tu.Assert(t, line[0].Glyphs[5].Advance == out.Glyphs[5].Advance+fixed.I(8))
+
+	// all the expansions are full
+	line = Line{out.copy()}
+	tu.Assert(t, distribute(line, make([]bool, 1), expansions, fixed.I(30)) == fixed.I(8))
+
+	// shrinking
+	line = Line{out.copy()}
+	tu.Assert(t, distribute(line, make([]bool, 1), expansions, -fixed.I(18)) == 0)
+	tu.Assert(t, line[0].Glyphs[3].Advance == out.Glyphs[3].Advance-fixed.I(8))
+}
+
+func TestJustifyCharacters(t *testing.T) {
+	text := []rune("漢字かな漢字 abc")
+	var (
+		seg segmenter.Segmenter
+		j   justifier
+	)
+	seg.Init(text)
+	j.prepare(Justification{Enabled: true}, &seg, text)
+
+	out := Output{Direction: di.DirectionLTR, Runes: Range{Count: len(text)}, Size: fixed.I(10)}
+	for i := range text {
+		out.Glyphs = append(out.Glyphs, Glyph{ClusterIndex: i, GlyphCount: 1, RuneCount: 1, Advance: fixed.I(10), XAdvance: fixed.I(10)})
+	}
+	out.RecomputeAdvance()
+	clusters := j.visualClusters(Line{out})
+	tu.Assert(t, len(clusters) == len(text))
+	for i, cl := range clusters {
+		tu.AssertC(t, cl.characterJustified == (i < 6), string(text[i]))
+		tu.AssertC(t, cl.separator == (i == 6), string(text[i]))
+	}
+
+	line := Line{out}
+	j.justify(line, out.Advance+fixed.I(6))
+	// 5 gaps between CJK characters, one space
+	for i, g := range line[0].Glyphs {
+		expected := fixed.I(10)
+		if i <= 4 || i == 6 {
+			expected = fixed.I(11)
+		}
+		tu.AssertC(t, g.Advance == expected, string(text[i]))
+	}
+}
+
+func TestJustifyBidi(t *testing.T) {
+	text := []rune("مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL,
+		Face: benchArFace, Size: fixed.I(16), Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+
+	config := WrapConfig{Direction: di.DirectionRTL, Justification: Justification{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(120), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) > 1)
+	for _, line := range lines[:len(lines)-1] {
+		tu.Assert(t, lineAdvance(line) == fixed.I(120))
+		// the trailing space is visually first, and stays trimmed
+		run := line[0]
+		tu.Assert(t, text[run.Glyphs[0].ClusterIndex] == ' ')
+		tu.Assert(t, run.Glyphs[0].Advance == 0)
+	}
+}
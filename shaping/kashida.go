@@ -0,0 +1,423 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sort"
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"golang.org/x/image/math/fixed"
+)
+
+var tagJalt = ot.MustNewTag("jalt")
+
+const tatweel = 0x0640
+
+// kashidaJustifier expands the Arabic runs of a line by inserting
+// tatweel (kashida) glyphs, see [Justification.Kashida].
+type kashidaJustifier struct {
+	// shaper is used to shape the tatweel glyphs
+	shaper *HarfbuzzShaper
+	// reshaper, if not nil, is used to re-shape the Arabic runs
+	// with the 'jalt' (justification alternates) feature
+	reshaper Reshaper
+	// sourceIndex returns the index of the run a line run is cut from
+	sourceIndex func(run Output) (int, bool)
+	// tatweels caches the tatweel glyph of each face and size,
+	// including the faces without usable tatweel
+	tatweels map[tatweelKey]tatweelEntry
+}
+
+type tatweelKey struct {
+	face *font.Face
+	size fixed.Int26_6
+}
+
+type tatweelEntry struct {
+	glyph Glyph
+	ok    bool
+}
+
+// prepare sets the shaper used for tatweels, and the run iterator of the paragraph,
+// used to apply the 'jalt' feature if it implements [Reshaper]. [sourceIndex] returns
+// the index in [runs] of the run a line run is cut from.
+func (kj *kashidaJustifier) prepare(shaper *HarfbuzzShaper, runs RunIterator, sourceIndex func(run Output) (int, bool)) {
+	kj.shaper = shaper
+	kj.reshaper, _ = runs.(Reshaper)
+	kj.sourceIndex = sourceIndex
+}
+
+// kashida priorities, from the highest (0) to the lowest
+const (
+	kashidaAfterSeen uint8 = iota
+	kashidaBeforeFinalHeh
+	kashidaBeforeFinalAlef
+	kashidaBeforeFinalReh
+	kashidaBeforeFinalWaw
+	kashidaBeforeFinal
+	kashidaNone
+)
+
+func isSeenOrSad(r rune) bool {
+	switch r {
+	case tatweel, 0x0633, 0x0634, 0x0635, 0x0636:
+		return true
+	}
+	return false
+}
+
+// isRightJoining returns true for Arabic letters which
+// only join with the preceding letter (or none, for Hamza)
+func isRightJoining(r rune) bool {
+	switch {
+	case r == 0x0621, // Hamza
+		0x0622 <= r && r <= 0x0625, r == 0x0627, 0x0671 <= r && r <= 0x0673, r == 0x0675, // Alef
+		r == 0x0629, r == 0x06C0, r == 0x06D3, r == 0x06D5, // Teh Marbuta, Heh Goal
+		r == 0x062F, r == 0x0630, 0x0688 <= r && r <= 0x0690, // Dal
+		r == 0x0631, r == 0x0632, 0x0691 <= r && r <= 0x0699, // Reh
+		r == 0x0648, r == 0x0676, r == 0x0677, 0x06C4 <= r && r <= 0x06CB, r == 0x06CF, // Waw
+		r == 0x06D2: // Yeh Barree
+		return true
+	}
+	return false
+}
+
+// isDualJoining returns true for Arabic letters which join with the following one
+func isDualJoining(r rune) bool {
+	return r == tatweel || unicode.Is(unicode.Arabic, r) && unicode.IsLetter(r) && !isRightJoining(r)
+}
+
+func isBeh(r rune) bool {
+	switch r {
+	case 0x0628, 0x062A, 0x062B, 0x0646, 0x064A, 0x067E, 0x0679, 0x06CC:
+		return true
+	}
+	return false
+}
+
+// kashidaPriority returns the priority of a kashida inserted
+// between the letters [prev] and [cur], the latter being in final form if [curIsFinal] is true
+func kashidaPriority(prev, cur rune, curIsFinal bool) uint8 {
+	if isSeenOrSad(prev) {
+		return kashidaAfterSeen
+	}
+	if !curIsFinal {
+		return kashidaNone
+	}
+	switch cur {
+	case 0x0629, 0x0647, 0x062F, 0x0630: // Teh Marbuta, Heh, Dal, Thal
+		return kashidaBeforeFinalHeh
+	case 0x0627, 0x0622, 0x0623, 0x0625, 0x0671, // Alef
+		0x0637, 0x0638, // Tah, Zah
+		0x0644,                 // Lam
+		0x0643, 0x06A9, 0x06AF: // Kaf, Gaf
+		return kashidaBeforeFinalAlef
+	case 0x0631, 0x0632, 0x0698, 0x064A, 0x0649, 0x06CC: // Reh, Yeh
+		if isBeh(prev) {
+			return kashidaBeforeFinalReh
+		}
+	case 0x0648, 0x0624, 0x0639, 0x063A, 0x0641, 0x0642, 0x06A4: // Waw, Ain, Feh, Qaf
+		return kashidaBeforeFinalWaw
+	}
+	return kashidaBeforeFinal
+}
+
+// glyphCluster is a range of glyphs sharing the same cluster
+type glyphCluster struct {
+	start, end int // in the glyph slice
+}
+
+// logicalClusters returns the clusters of [run], in logical order
+func logicalClusters(run Output) []glyphCluster {
+	var out []glyphCluster
+	for i := 0; i < len(run.Glyphs); {
+		end := i + 1
+		for end < len(run.Glyphs) && run.Glyphs[end].ClusterIndex == run.Glyphs[i].ClusterIndex {
+			end++
+		}
+		out = append(out, glyphCluster{i, end})
+		i = end
+	}
+	if run.Direction.Progression() == di.TowardTopLeft {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
+
+func (run Output) clusterHasFlag(cl glyphCluster, flag GlyphFlags) bool {
+	for _, g := range run.Glyphs[cl.start:cl.end] {
+		if g.Flags&flag != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// kashidaPosition is a candidate position in a line
+type kashidaPosition struct {
+	run      int          // index of the run in the line
+	prev     glyphCluster // the cluster extended by the tatweel
+	index    int          // insertion index in the run glyphs
+	priority uint8
+	extra    fixed.Int26_6 // space to fill
+}
+
+// kashidaPositions returns the best position for each word of [line]
+func kashidaPositions(line Line, text []rune) []kashidaPosition {
+	var (
+		out      []kashidaPosition
+		best     = kashidaPosition{priority: kashidaNone}
+		flushPos = func() {
+			if best.priority != kashidaNone {
+				out = append(out, best)
+			}
+			best = kashidaPosition{priority: kashidaNone}
+		}
+	)
+	for runIndex, run := range line {
+		if run.Direction.IsVertical() {
+			continue
+		}
+		clusters := logicalClusters(run)
+		for k, cl := range clusters {
+			firstRune := run.Glyphs[cl.start].ClusterIndex
+			if firstRune >= len(text) {
+				continue
+			}
+			if unicode.IsSpace(text[firstRune]) {
+				flushPos()
+				continue
+			}
+			if k == 0 || !run.clusterHasFlag(cl, GlyphSafeToInsertTatweel) {
+				continue
+			}
+			// find the base letter of the previous cluster
+			prev := clusters[k-1]
+			var prevLetter rune
+			for r := run.Glyphs[prev.start].ClusterIndex; r < firstRune && r < len(text); r++ {
+				if !unicode.Is(unicode.Mn, text[r]) {
+					prevLetter = text[r]
+				}
+			}
+			// find the next letter, to check if the current one has a final form
+			var nextLetter rune
+			for r := firstRune + 1; r < len(text); r++ {
+				if !unicode.Is(unicode.Mn, text[r]) {
+					nextLetter = text[r]
+					break
+				}
+			}
+			isFinal := !isDualJoining(text[firstRune]) || !(isDualJoining(nextLetter) || isRightJoining(nextLetter) && nextLetter != 0x0621)
+			priority := kashidaPriority(prevLetter, text[firstRune], isFinal)
+			// for equal priorities, the last position is used
+			if priority <= best.priority {
+				index := cl.start
+				if run.Direction.Progression() == di.TowardTopLeft {
+					index = cl.end
+				}
+				best = kashidaPosition{run: runIndex, prev: prev, index: index, priority: priority}
+			}
+		}
+	}
+	flushPos()
+	return out
+}
+
+// justify expands [line] by [extra], which must be positive, using the 'jalt' feature
+// and tatweel glyphs, and returns the space which could not be distributed.
+// [text] is the paragraph text, used to select the kashida positions.
+// For each word, only the position with the highest priority is used, and the space to fill
+// is evenly distributed between the positions, with tatweel glyphs
+// overlapping as needed to match [extra] exactly.
+//
+// The modified runs use new glyph slices, so that the original glyphs are not modified.
+func (kj *kashidaJustifier) justify(line Line, text []rune, extra fixed.Int26_6) fixed.Int26_6 {
+	if kj.reshaper != nil && kj.sourceIndex != nil {
+		extra = kj.applyJalt(line, text, extra)
+		if extra <= 0 {
+			return extra
+		}
+	}
+
+	// only keep the positions where a tatweel is available
+	positions := kashidaPositions(line, text)
+	filtered := positions[:0]
+	for _, pos := range positions {
+		if _, ok := kj.tatweelGlyph(&line[pos.run]); ok {
+			filtered = append(filtered, pos)
+		}
+	}
+	positions = filtered
+	if len(positions) == 0 {
+		return extra
+	}
+
+	// distribute the extra space, using the positions with the highest
+	// priority if it is too small to be shared between all of them
+	if int(extra) < len(positions) {
+		sort.SliceStable(positions, func(i, j int) bool { return positions[i].priority < positions[j].priority })
+		positions = positions[:extra]
+	}
+	share := extra / fixed.Int26_6(len(positions))
+	for i := range positions {
+		positions[i].extra = share
+	}
+	positions[len(positions)-1].extra += extra - share*fixed.Int26_6(len(positions))
+
+	// insert the glyphs, run by run
+	for runIndex := range line {
+		var runPositions []kashidaPosition
+		for _, pos := range positions {
+			if pos.run == runIndex {
+				runPositions = append(runPositions, pos)
+			}
+		}
+		if len(runPositions) != 0 {
+			tatweel, _ := kj.tatweelGlyph(&line[runIndex])
+			line[runIndex] = insertTatweels(line[runIndex], tatweel, runPositions)
+		}
+	}
+	return 0
+}
+
+// isArabicRun returns true if [run] contains Arabic letters.
+func isArabicRun(run Output, text []rune) bool {
+	for i := run.Runes.Offset; i < run.Runes.Offset+run.Runes.Count && i < len(text); i++ {
+		if unicode.IsLetter(text[i]) && language.LookupScript(text[i]) == language.Arabic {
+			return true
+		}
+	}
+	return false
+}
+
+// applyJalt re-shapes the Arabic runs of [line] with the 'jalt' feature, as long as the
+// line is not expanded by more than [extra], and returns the remaining space.
+func (kj *kashidaJustifier) applyJalt(line Line, text []rune, extra fixed.Int26_6) fixed.Int26_6 {
+	for i, run := range line {
+		if run.Face == nil || run.Direction.IsVertical() {
+			continue
+		}
+		if _, hasJalt := run.Face.GSUB.FindFeatureIndex(tagJalt); !hasJalt {
+			continue
+		}
+		index, ok := kj.sourceIndex(run)
+		if !ok || !isArabicRun(run, text) {
+			continue
+		}
+		reshaped := kj.reshaper.Reshape(index, run.Runes, FontFeature{Tag: tagJalt, Value: 1})
+
+		// preserve the trimmed whitespace
+		for _, g := range run.Glyphs {
+			if g.Advance != 0 || g.ClusterIndex >= len(text) || !unicode.IsSpace(text[g.ClusterIndex]) {
+				continue
+			}
+			for j := range reshaped.Glyphs {
+				if reshaped.Glyphs[j].ClusterIndex == g.ClusterIndex {
+					reshaped.Glyphs[j].Advance, reshaped.Glyphs[j].XAdvance = 0, 0
+				}
+			}
+			reshaped.RecomputeAdvance()
+		}
+
+		if growth := reshaped.Advance - run.Advance; growth <= extra {
+			reshaped.VisualIndex = run.VisualIndex
+			line[i] = reshaped
+			extra -= growth
+		}
+	}
+	return extra
+}
+
+// tatweelGlyph returns the (cached) tatweel glyph of the run font, or false
+// if it is not supported.
+// Since fonts may substitute the tatweel depending on its context,
+// it is shaped between two Beh.
+func (kj *kashidaJustifier) tatweelGlyph(run *Output) (Glyph, bool) {
+	if run.Face == nil {
+		return Glyph{}, false
+	}
+	key := tatweelKey{run.Face, run.Size}
+	if entry, ok := kj.tatweels[key]; ok {
+		return entry.glyph, entry.ok
+	}
+	if kj.tatweels == nil || len(kj.tatweels) >= defaultFontCacheSize {
+		kj.tatweels = make(map[tatweelKey]tatweelEntry)
+	}
+	var entry tatweelEntry
+	entry.glyph, entry.ok = shapeTatweel(kj.shaper, run.Face, run.Size)
+	kj.tatweels[key] = entry
+	return entry.glyph, entry.ok
+}
+
+// shapeTatweel returns the tatweel glyph of [face], shaped with [shaper].
+func shapeTatweel(shaper *HarfbuzzShaper, face *font.Face, size fixed.Int26_6) (Glyph, bool) {
+	if _, ok := face.NominalGlyph(tatweel); !ok {
+		return Glyph{}, false
+	}
+	out := shaper.Shape(Input{
+		Text:      []rune{0x0628, tatweel, 0x0628},
+		RunEnd:    3,
+		Direction: di.DirectionRTL,
+		Face:      face,
+		Size:      size,
+		Script:    language.Arabic,
+	})
+	var (
+		glyph Glyph
+		found int
+	)
+	for _, g := range out.Glyphs {
+		if g.ClusterIndex == 1 {
+			glyph = g
+			found++
+		}
+	}
+	if found != 1 || glyph.Advance <= 0 {
+		return Glyph{}, false
+	}
+	glyph.Flags = 0
+	return glyph, true
+}
+
+// insertTatweels returns a copy of [run] with the tatweels inserted.
+func insertTatweels(run Output, tatweel Glyph, positions []kashidaPosition) Output {
+	sort.Slice(positions, func(i, j int) bool { return positions[i].index < positions[j].index })
+
+	glyphs := make([]Glyph, 0, len(run.Glyphs)+len(positions))
+	last := 0
+	for _, pos := range positions {
+		glyphs = append(glyphs, run.Glyphs[last:pos.index]...)
+		last = pos.index
+
+		// use as many tatweels as needed, overlapping them if required
+		count := int((pos.extra + tatweel.Advance - 1) / tatweel.Advance)
+		if count == 0 {
+			count = 1
+		}
+		advance := pos.extra / fixed.Int26_6(count)
+		remain := pos.extra - advance*fixed.Int26_6(count)
+		for c := 0; c < count; c++ {
+			g := tatweel
+			// tatweels are part of the extended cluster
+			g.ClusterIndex = run.Glyphs[pos.prev.start].ClusterIndex
+			g.Advance = advance
+			if c == count-1 {
+				g.Advance += remain
+			}
+			g.XAdvance = g.Advance
+			glyphs = append(glyphs, g)
+		}
+	}
+	glyphs = append(glyphs, run.Glyphs[last:]...)
+
+	run.Glyphs = glyphs
+	countClusters(run.Glyphs, run.Runes.Offset+run.Runes.Count, run.Direction.Progression())
+	run.RecalculateAll()
+	return run
+}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestKashidaPriority(t *testing.T) {
+	for _, test := range []struct {
+		prev, cur  rune
+		curIsFinal bool
+		expected   uint8
+	}{
+		{'س', 'م', false, kashidaAfterSeen},
+		{'ص', 'ر', true, kashidaAfterSeen},
+		{'ل', 'ه', true, kashidaBeforeFinalHeh},
+		{'ل', 'ا', true, kashidaBeforeFinalAlef},
+		{'ب', 'ر', true, kashidaBeforeFinalReh},
+		{'م', 'ر', true, kashidaBeforeFinal},
+		{'م', 'و', true, kashidaBeforeFinalWaw},
+		{'ح', 'م', true, kashidaBeforeFinal},
+		{'ح', 'م', false, kashidaNone},
+	} {
+		tu.AssertC(t, kashidaPriority(test.prev, test.cur, test.curIsFinal) == test.expected, string([]rune{test.prev, test.cur}))
+	}
+}
+
+func shapeArabic(text []rune, flags GlyphFlags) Output {
+	input := Input{
+		Text:       text,
+		RunEnd:     len(text),
+		Direction:  di.DirectionRTL,
+		Face:       benchArFace,
+		Size:       fixed.I(16),
+		Script:     language.Arabic,
+		Language:   language.NewLanguage("ar"),
+		GlyphFlags: flags,
+	}
+	return (&HarfbuzzShaper{}).Shape(input)
+}
+
+// tatweelCount returns the number of tatweel glyphs of [line]
+func tatweelCount(t *testing.T, line Line) int {
+	var kj kashidaJustifier
+	kj.shaper = &HarfbuzzShaper{}
+	count := 0
+	for i, run := range line {
+		tatweel, ok := kj.tatweelGlyph(&line[i])
+		if !ok {
+			continue
+		}
+		for _, g := range run.Glyphs {
+			if g.GlyphID == tatweel.GlyphID {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestKashidaJustify(t *testing.T) {
+	text := []rune("بسم الله الرحمن الرحيم بسم الله الرحمن الرحيم")
+	out := shapeArabic(text, GlyphSafeToInsertTatweel)
+	original := out.copy()
+	maxWidth := out.Advance * 2 / 3
+
+	var w LineWrapper
+	config := WrapConfig{Justification: Justification{Enabled: true, Kashida: true}}
+	lines, _ := w.WrapParagraphF(config, maxWidth, text, NewSliceIterator([]Output{out}))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, lineAdvance(lines[0]) == maxWidth)
+	tu.Assert(t, tatweelCount(t, lines[0]) > 0)
+	// the spaces are not expanded
+	spaceAdvance := make(map[int]fixed.Int26_6)
+	for _, g := range original.Glyphs {
+		if text[g.ClusterIndex] == ' ' {
+			spaceAdvance[g.ClusterIndex] = g.Advance
+		}
+	}
+	_, lineEnd := lineRunes(lines[0])
+	for _, g := range lines[0][0].Glyphs {
+		if adv, isSpace := spaceAdvance[g.ClusterIndex]; isSpace && g.ClusterIndex != lineEnd-1 {
+			tu.Assert(t, g.Advance == adv)
+		}
+	}
+	// the last line is not justified
+	tu.Assert(t, tatweelCount(t, lines[1]) == 0)
+	// the input run is not modified
+	tu.Assert(t, len(out.Glyphs) == len(original.Glyphs))
+	for i := range out.Glyphs {
+		tu.Assert(t, out.Glyphs[i] == original.Glyphs[i])
+	}
+
+	// with a re-shaping iterator (the font has no 'jalt' feature)
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL, Face: benchArFace, Size: fixed.I(16),
+		Script: language.Arabic, Language: language.NewLanguage("ar"), GlyphFlags: GlyphSafeToInsertTatweel,
+	}
+	lines, _ = w.WrapParagraphF(config, maxWidth, text, NewReshapingIterator(&HarfbuzzShaper{}, []Input{input}, []Output{out.copy()}))
+	tu.Assert(t, len(lines) == 2 && lineAdvance(lines[0]) == maxWidth)
+	tu.Assert(t, tatweelCount(t, lines[0]) > 0)
+
+	// flags not requested : no position available, word spacing is used
+	out = shapeArabic(text, 0)
+	lines, _ = w.WrapParagraphF(config, maxWidth, text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, lineAdvance(lines[0]) == maxWidth)
+	tu.Assert(t, tatweelCount(t, lines[0]) == 0)
+
+	// kashida disabled
+	out = shapeArabic(text, GlyphSafeToInsertTatweel)
+	config.Justification.Kashida = false
+	lines, _ = w.WrapParagraphF(config, maxWidth, text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, lineAdvance(lines[0]) == maxWidth)
+	tu.Assert(t, tatweelCount(t, lines[0]) == 0)
+
+	// no Arabic text : word spacing is used
+	enText := []rune("Hello world, hello world")
+	enOut := (&HarfbuzzShaper{}).Shape(Input{
+		Text: enText, RunEnd: len(enText), Direction: di.DirectionLTR, Face: benchEnFace,
+		Size: fixed.I(16), Script: language.Latin, GlyphFlags: GlyphSafeToInsertTatweel,
+	})
+	config.Justification.Kashida = true
+	lines, _ = w.WrapParagraphF(config, enOut.Advance*2/3, enText, NewSliceIterator([]Output{enOut}))
+	tu.Assert(t, len(lines) == 2 && lineAdvance(lines[0]) == enOut.Advance*2/3)
+}
+
+func TestKashidaJustifyPositions(t *testing.T) {
+	text := []rune("بسم الله الرحمن الرحيم")
+	out := shapeArabic(text, GlyphSafeToInsertTatweel)
+	kj := kashidaJustifier{shaper: &HarfbuzzShaper{}}
+	line := Line{out.copy()}
+	tu.Assert(t, kj.justify(line, text, fixed.I(30)) == 0)
+	tu.Assert(t, line[0].Advance == out.Advance+fixed.I(30))
+	tu.Assert(t, line[0].Runes == out.Runes)
+
+	tatweel, ok := kj.tatweelGlyph(&out)
+	tu.Assert(t, ok)
+	var (
+		sum       fixed.Int26_6
+		nbTatweel int
+	)
+	for _, g := range line[0].Glyphs {
+		sum += g.Advance
+		if g.GlyphID == tatweel.GlyphID {
+			nbTatweel++
+		}
+	}
+	tu.Assert(t, sum == out.Advance+fixed.I(30))
+	// at most one position per word, with overlapping tatweels
+	tu.Assert(t, nbTatweel >= 1 && len(kashidaPositions(Line{out}, text)) <= 4)
+	// the input run is not modified
+	tu.Assert(t, len(out.Glyphs) < len(line[0].Glyphs))
+
+	// flags not requested : no position available
+	out = shapeArabic(text, 0)
+	line = Line{out}
+	tu.Assert(t, kj.justify(line, text, fixed.I(30)) == fixed.I(30))
+	tu.Assert(t, line[0].Advance == out.Advance)
+
+	// no Arabic text
+	enText := []rune("Hello world")
+	enOut := (&HarfbuzzShaper{}).Shape(Input{
+		Text: enText, RunEnd: len(enText), Direction: di.DirectionLTR, Face: benchEnFace,
+		Size: fixed.I(16), Script: language.Latin, GlyphFlags: GlyphSafeToInsertTatweel,
+	})
+	tu.Assert(t, kj.justify(Line{enOut}, enText, fixed.I(10)) == fixed.I(10))
+}
+
+func TestTatweelCache(t *testing.T) {
+	kj := kashidaJustifier{shaper: &HarfbuzzShaper{}}
+	arOut := shapeArabic([]rune("بسم"), GlyphSafeToInsertTatweel)
+	enText := []rune("abc")
+	enOut := (&HarfbuzzShaper{}).Shape(Input{
+		Text: enText, RunEnd: len(enText), Direction: di.DirectionLTR, Face: benchEnFace,
+		Size: fixed.I(16), Script: language.Latin,
+	})
+
+	g1, ok := kj.tatweelGlyph(&arOut)
+	tu.Assert(t, ok)
+	_, ok = kj.tatweelGlyph(&enOut)
+	tu.Assert(t, !ok)
+	tu.Assert(t, len(kj.tatweels) == 2)
+	tu.Assert(t, len(kj.shaper.fonts.m) == 1) // the Latin face has no tatweel
+
+	// failures and successes are cached
+	kj.shaper = nil
+	g2, ok := kj.tatweelGlyph(&arOut)
+	tu.Assert(t, ok && g1 == g2)
+	_, ok = kj.tatweelGlyph(&enOut)
+	tu.Assert(t, !ok)
+
+	// another size
+	arOut.Size = fixed.I(20)
+	kj.shaper = &HarfbuzzShaper{}
+	g3, ok := kj.tatweelGlyph(&arOut)
+	tu.Assert(t, ok && g3.Advance > g1.Advance)
+	tu.Assert(t, len(kj.tatweels) == 3)
+}
+
+func TestKashidaSmallExtra(t *testing.T) {
+	text := []rune("مرحبا بالعالم مرحبا بالعالم")
+	out := shapeArabic(text, GlyphSafeToInsertTatweel)
+	kj := kashidaJustifier{shaper: &HarfbuzzShaper{}}
+	tatweel, _ := kj.tatweelGlyph(&out)
+	tu.Assert(t, len(kashidaPositions(Line{out}, text)) > 2)
+
+	// the space is smaller than the number of positions
+	line := Line{out.copy()}
+	tu.Assert(t, kj.justify(line, text, 2) == 0)
+	tu.Assert(t, line[0].Advance == out.Advance+2)
+	nbTatweel := 0
+	for _, g := range line[0].Glyphs {
+		if g.GlyphID == tatweel.GlyphID {
+			nbTatweel++
+			tu.Assert(t, g.Advance > 0)
+		}
+	}
+	tu.Assert(t, nbTatweel == 2)
+}
+
+func TestKashidaReshaper(t *testing.T) {
+	text := []rune("بسم الله الرحمن الرحيم بسم الله الرحمن الرحيم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL, Face: benchArFace, Size: fixed.I(16),
+		Script: language.Arabic, Language: language.NewLanguage("ar"), GlyphFlags: GlyphSafeToInsertTatweel,
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+
+	// any Reshaper is used
+	runs := &countingReshaper{RunIterator: NewReshapingIterator(&HarfbuzzShaper{}, []Input{input}, []Output{out})}
+	var w LineWrapper
+	config := WrapConfig{Justification: Justification{Enabled: true, Kashida: true}}
+	lines, _ := w.WrapParagraphF(config, out.Advance*2/3, text, runs)
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, w.justifier.kashida.reshaper == runs)
+	index, ok := w.sourceIndex(lines[0][0])
+	tu.Assert(t, ok && index == 0)
+
+	// features are applied when re-shaping
+	enText := []rune("office")
+	enInput := Input{
+		Text: enText, RunEnd: len(enText), Direction: di.DirectionLTR, Face: loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf"),
+		Size: fixed.I(16), Script: language.Latin, Language: language.NewLanguage("en"),
+	}
+	reshaper := NewReshapingIterator(&HarfbuzzShaper{}, []Input{enInput}, []Output{{}}).(Reshaper)
+	withLiga := reshaper.Reshape(0, Range{Count: len(enText)})
+	noLiga := reshaper.Reshape(0, Range{Count: len(enText)}, FontFeature{Tag: ot.MustNewTag("liga"), Value: 0})
+	tu.Assert(t, len(noLiga.Glyphs) > len(withLiga.Glyphs))
+}
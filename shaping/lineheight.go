@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"golang.org/x/image/math/fixed"
+)
+
+// LineHeightPolicy selects how the height of a line is computed
+// from the runs it contains.
+type LineHeightPolicy uint8
+
+const (
+	// LineHeightFontMetrics uses the line bounds suggested by the fonts (see [Output.LineBounds]) :
+	// the line ascent (and gap) is the maximum ascent (and gap) of the runs, and the line
+	// descent their minimum descent.
+	LineHeightFontMetrics LineHeightPolicy = iota
+	// LineHeightFixed uses [LineHeight.Fixed] as the height of each run.
+	LineHeightFixed
+	// LineHeightMultiplier uses [LineHeight.Multiplier] times the size of each run
+	// as its height, as the CSS 'line-height: <number>' property does.
+	LineHeightMultiplier
+)
+
+// LineHeight configures how the height of a line is computed,
+// see [Line.Metrics].
+type LineHeight struct {
+	Policy LineHeightPolicy
+	// Fixed is the height of the runs for the [LineHeightFixed] policy.
+	Fixed fixed.Int26_6
+	// Multiplier is the factor applied to the size of the runs
+	// for the [LineHeightMultiplier] policy.
+	Multiplier float32
+}
+
+// runHeight returns the height used for [run], or false
+// for the [LineHeightFontMetrics] policy.
+func (lh LineHeight) runHeight(run *Output) (fixed.Int26_6, bool) {
+	switch lh.Policy {
+	case LineHeightFixed:
+		return lh.Fixed, true
+	case LineHeightMultiplier:
+		return fixed.Int26_6(lh.Multiplier * float32(run.Size)), true
+	default:
+		return 0, false
+	}
+}
+
+// Metrics returns the ascent, descent and gap of the line, aggregated from
+// the line bounds of all its runs (see [Output.LineBounds]), so that runs using
+// different fonts and sizes are taken into account. The height of the line is then
+// given by [Bounds.LineThickness].
+//
+// For the [LineHeightFixed] and [LineHeightMultiplier] policies, the difference between
+// the height of each run and its font height (the leading) is split evenly above and below the run, as in
+// the CSS inline layout model, and the returned gap is zero. As a consequence, a line
+// mixing fonts with different metrics may be taller than the requested height.
+func (l Line) Metrics(height LineHeight) Bounds {
+	var out Bounds
+	for i := range l {
+		run := &l[i]
+		ascent, descent, gap := run.LineBounds.Ascent, run.LineBounds.Descent, run.LineBounds.Gap
+		if h, ok := height.runHeight(run); ok {
+			halfLeading := (h - (ascent - descent)) / 2
+			ascent += halfLeading
+			descent = ascent - h
+			gap = 0
+		}
+		if i == 0 {
+			out = Bounds{Ascent: ascent, Descent: descent, Gap: gap}
+			continue
+		}
+		if ascent > out.Ascent {
+			out.Ascent = ascent
+		}
+		if descent < out.Descent {
+			out.Descent = descent
+		}
+		if gap > out.Gap {
+			out.Gap = gap
+		}
+	}
+	return out
+}
+
+// linesInHeight returns the number of [lines] whose accumulated height,
+// computed using [height], does not exceed [maxHeight]. At least one line is counted.
+func linesInHeight(lines []Line, height LineHeight, maxHeight fixed.Int26_6) int {
+	var total fixed.Int26_6
+	for i, line := range lines {
+		total += line.Metrics(height).LineThickness()
+		if total > maxHeight {
+			if i == 0 {
+				return 1
+			}
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// replayRuns is a [RunIterator] replaying the runs collected from another iterator.
+type replayRuns struct {
+	shapedRunSlice
+	indices []int
+}
+
+// Next implements [RunIterator.Next].
+func (r *replayRuns) Next() (int, Output, bool) {
+	idx, run, ok := r.Peek()
+	if ok {
+		r.idx++
+	}
+	return idx, run, ok
+}
+
+// Peek implements [RunIterator.Peek].
+func (r *replayRuns) Peek() (int, Output, bool) {
+	_, run, ok := r.shapedRunSlice.Peek()
+	if !ok {
+		return r.idx, run, false
+	}
+	return r.indices[r.idx], run, true
+}
+
+// reshapingReplayRuns is a [replayRuns] for a source also implementing [Reshaper].
+type reshapingReplayRuns struct {
+	replayRuns
+	Reshaper
+}
+
+// restartable returns a function providing iterators over the remaining
+// runs of [runs], which may be called several times.
+func restartable(runs RunIterator) func() RunIterator {
+	switch it := runs.(type) {
+	case *shapedRunSlice:
+		start := *it
+		return func() RunIterator {
+			iter := start
+			iter.savedIdx = iter.idx
+			return &iter
+		}
+	case *reshapingRunSlice:
+		start := *it
+		return func() RunIterator {
+			iter := start
+			iter.savedIdx = iter.idx
+			return &iter
+		}
+	}
+
+	var replay replayRuns
+	for {
+		index, run, ok := runs.Next()
+		if !ok {
+			break
+		}
+		replay.runs = append(replay.runs, run)
+		replay.indices = append(replay.indices, index)
+	}
+	reshaper, isReshaper := runs.(Reshaper)
+	return func() RunIterator {
+		iter := replay
+		if isReshaper {
+			return &reshapingReplayRuns{iter, reshaper}
+		}
+		return &iter
+	}
+}
+
+// wrapParagraphHeight implements [WrapParagraphF] for configurations with a [WrapConfig.MaxHeight] :
+// the paragraph is first wrapped without height limit, and then wrapped again, truncated after the last
+// line fitting in the height, if needed.
+func (l *LineWrapper) wrapParagraphHeight(config WrapConfig, maxWidth fixed.Int26_6, paragraph []rune, runs RunIterator) (_ []Line, truncated int) {
+	iterator := restartable(runs)
+	maxHeight := config.MaxHeight
+	config.MaxHeight = 0
+
+	lines, truncated := l.WrapParagraphF(config, maxWidth, paragraph, iterator())
+	count := linesInHeight(lines, config.LineHeight, maxHeight)
+	if count == len(lines) {
+		return lines, truncated
+	}
+	config.TruncateAfterLines = count
+	return l.WrapParagraphF(config, maxWidth, paragraph, iterator())
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestLineMetrics(t *testing.T) {
+	small := shapeLatin([]rune("small"))
+	text := []rune("big")
+	big := (&HarfbuzzShaper{}).Shape(Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(32), Script: language.Latin,
+	})
+	tu.Assert(t, big.LineBounds.Ascent > small.LineBounds.Ascent)
+
+	tu.Assert(t, Line{}.Metrics(LineHeight{}) == Bounds{})
+	tu.Assert(t, Line{small}.Metrics(LineHeight{}) == small.LineBounds)
+	tu.Assert(t, Line{small, big}.Metrics(LineHeight{}) == big.LineBounds)
+
+	fixedHeight := LineHeight{Policy: LineHeightFixed, Fixed: fixed.I(20)}
+	metrics := Line{small}.Metrics(fixedHeight)
+	tu.Assert(t, metrics.LineThickness() == fixed.I(20) && metrics.Gap == 0)
+	// the leading is split evenly
+	above, below := metrics.Ascent-small.LineBounds.Ascent, small.LineBounds.Descent-metrics.Descent
+	tu.Assert(t, above-below <= 1 && below-above <= 1)
+	// the baselines of runs with different metrics are aligned
+	tu.Assert(t, Line{small, big}.Metrics(fixedHeight).LineThickness() >= fixed.I(20))
+
+	multiplier := LineHeight{Policy: LineHeightMultiplier, Multiplier: 1.5}
+	tu.Assert(t, Line{small}.Metrics(multiplier).LineThickness() == fixed.I(24))
+	tu.Assert(t, Line{small, big}.Metrics(multiplier).LineThickness() == fixed.I(48))
+}
+
+// opaqueIterator hides the concrete type of the wrapped iterator
+type opaqueIterator struct{ RunIterator }
+
+func TestWrapMaxHeight(t *testing.T) {
+	text := []rune("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt.")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	lineHeight := LineHeight{Policy: LineHeightFixed, Fixed: fixed.I(20)}
+	unlimited, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, fixed.I(150), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(unlimited) > 3)
+
+	for _, iterator := range []func() RunIterator{
+		func() RunIterator { return NewSliceIterator([]Output{out.copy()}) },
+		func() RunIterator { return opaqueIterator{NewSliceIterator([]Output{out.copy()})} },
+	} {
+		config := WrapConfig{MaxHeight: fixed.I(50), LineHeight: lineHeight, Truncator: truncator}
+		lines, truncated := (&LineWrapper{}).WrapParagraphF(config, fixed.I(150), text, iterator())
+		tu.Assert(t, len(lines) == 2 && truncated > 0)
+		checkRuneCounts(t, text, lines, truncated)
+		last := lines[1][len(lines[1])-1]
+		tu.Assert(t, last.Runes == Range{Offset: len(text) - truncated, Count: truncated})
+
+		// same as the equivalent line count limit
+		config2 := WrapConfig{TruncateAfterLines: 2, Truncator: truncator}
+		expected, expectedTruncated := (&LineWrapper{}).WrapParagraphF(config2, fixed.I(150), text, iterator())
+		tu.Assert(t, truncated == expectedTruncated)
+		tu.Assert(t, lineAdvance(lines[1]) == lineAdvance(expected[1]))
+
+		// at least one line is kept
+		config.MaxHeight = fixed.I(5)
+		lines, _ = (&LineWrapper{}).WrapParagraphF(config, fixed.I(150), text, iterator())
+		tu.Assert(t, len(lines) == 1)
+
+		// the paragraph fits
+		config.MaxHeight = fixed.I(20 * len(unlimited))
+		lines, truncated = (&LineWrapper{}).WrapParagraphF(config, fixed.I(150), text, iterator())
+		tu.Assert(t, len(lines) == len(unlimited) && truncated == 0)
+	}
+}
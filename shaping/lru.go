@@ -1,7 +1,6 @@
 package shaping
 
 import (
-	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/harfbuzz"
 )
 
@@ -12,18 +11,18 @@ const defaultFontCacheSize = 32
 // fontEntry holds a single key-value pair for an LRU cache.
 type fontEntry struct {
 	next, prev *fontEntry
-	key        *font.Font
+	key        fontKey
 	v          *harfbuzz.Font
 }
 
 // fontLRU is a least-recently-used cache for harfbuzz fonts built from
-// font.Fonts. It uses a doubly-linked list to track how recently elements have
+// font.Faces (see [fontKey]). It uses a doubly-linked list to track how recently elements have
 // been used and a map to store element data for quick access.
 type fontLRU struct {
 	// This implementation is derived from the one here under the terms of the UNLICENSE:
 	//
 	// https://git.sr.ht/~eliasnaur/gio/tree/e768fe347a732056031100f2c66987d6db258ea4/item/text/lru.go
-	m          map[*font.Font]*fontEntry
+	m          map[fontKey]*fontEntry
 	head, tail *fontEntry
 
 	// the actual cache size is [maxSizeOffset] + [defaultFontCacheSize]
@@ -31,7 +30,7 @@ type fontLRU struct {
 }
 
 // Get fetches the value associated with the given key, if any.
-func (l *fontLRU) Get(k *font.Font) (*harfbuzz.Font, bool) {
+func (l *fontLRU) Get(k fontKey) (*harfbuzz.Font, bool) {
 	if lt, ok := l.m[k]; ok {
 		l.remove(lt)
 		l.insert(lt)
@@ -42,9 +41,9 @@ func (l *fontLRU) Get(k *font.Font) (*harfbuzz.Font, bool) {
 
 // Put inserts the given value with the given key, evicting old
 // cache entries if necessary.
-func (l *fontLRU) Put(k *font.Font, v *harfbuzz.Font) {
+func (l *fontLRU) Put(k fontKey, v *harfbuzz.Font) {
 	if l.m == nil {
-		l.m = make(map[*font.Font]*fontEntry)
+		l.m = make(map[fontKey]*fontEntry)
 		l.head = new(fontEntry)
 		l.tail = new(fontEntry)
 		l.head.prev = l.tail
@@ -60,6 +59,18 @@ func (l *fontLRU) Put(k *font.Font, v *harfbuzz.Font) {
 	}
 }
 
+// font returns the harfbuzz font to use for [input], creating
+// and caching it if needed.
+func (l *fontLRU) font(input Input) *harfbuzz.Font {
+	key, coords := newFontKey(input)
+	font, ok := l.Get(key)
+	if !ok { // create a new font and cache it
+		font = harfbuzz.NewFont(key.newFace(coords))
+		l.Put(key, font)
+	}
+	return font
+}
+
 // remove cuts e out of the lru linked list.
 func (l *fontLRU) remove(e *fontEntry) {
 	e.next.prev = e.prev
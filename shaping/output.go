@@ -5,6 +5,7 @@ package shaping
 import (
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/language"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -64,15 +65,47 @@ type Glyph struct {
 	// Deprecated: Use GlyphsCount() instead.
 	GlyphCount int
 	GlyphID    font.GID
-	Mask       uint32
-
-	// startLetterSpacing and endLetterSpacing are set when letter spacing is applied,
+	// Mask is the raw harfbuzz glyph mask, which also contains
+	// internal shaping values : [Flags] should be preferred.
+	Mask uint32
+	// Flags provides information computed during shaping,
+	// such as the positions where the text may be broken
+	// without re-shaping.
+	Flags GlyphFlags
+
+	// startLetterSpacing and endLetterSpacing are set when letter spacing (or autospace, see [TextSpacing]) is applied,
 	// measuring the whitespace added on one side (half of the user provided letter spacing)
 	// The line wrapper will ignore [endLetterSpacing] when deciding where to break,
 	// and will trim [startLetterSpacing] at the start of the lines
 	startLetterSpacing, endLetterSpacing fixed.Int26_6
 }
 
+// GlyphFlags are set by the shaper on the output glyphs.
+type GlyphFlags uint8
+
+const (
+	// GlyphUnsafeToBreak indicates that if input text is broken at the
+	// beginning of the cluster this glyph is part of, then both sides need to be
+	// re-shaped, as the result might be different.
+	// When this flag is not set, it is safe to cut the glyph run
+	// at the beginning of the cluster, and the two sides will be identical
+	// to the ones obtained by shaping separately.
+	// All the glyphs of a cluster share the same value.
+	GlyphUnsafeToBreak GlyphFlags = 1 << iota
+	// GlyphUnsafeToConcat indicates that if input text is changed on one side of the
+	// beginning of the cluster this glyph is part of, then the shaping results
+	// for the other side might change. It is always set with [GlyphUnsafeToBreak].
+	//
+	// Note that this flag is only computed if requested in [Input.GlyphFlags].
+	GlyphUnsafeToConcat
+	// GlyphSafeToInsertTatweel signifies that it is safe to insert a
+	// U+0640 TATWEEL character before this cluster for elongation,
+	// in scripts that use elongation (Arabic, Syriac, etc.).
+	//
+	// Note that this flag is only computed if requested in [Input.GlyphFlags].
+	GlyphSafeToInsertTatweel
+)
+
 // TextIndex is the lowest rune index of all runes shaped into
 // this glyph cluster. All glyphs sharing the same cluster value
 // are part of the same cluster and will have identical RunesCount
@@ -169,6 +202,10 @@ type Output struct {
 	// Runes describes the runes this output represents from the input text.
 	Runes Range
 
+	// Language is the language used to shape the text,
+	// as provided in the Input.
+	Language language.Language
+
 	// Face is the font face that this output is rendered in. This is needed in
 	// the output in order to render each run in a multi-font sequence in the
 	// correct font.
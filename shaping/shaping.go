@@ -10,13 +10,22 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
+// maxPlanFonts is the number of fonts for which the shaping plans are cached
+const maxPlanFonts = 4 * defaultFontCacheSize
+
 // HarfbuzzShaper implements the Shaper interface using harfbuzz.
 // Reusing this shaper type across multiple shaping operations is
 // faster and more memory-efficient than creating a new shaper
 // for each operation.
+//
+// A HarfbuzzShaper is not safe for concurrent use : see [ConcurrentShaper]
+// for an alternative. Shaping plans are cached by each shaper, and
+// released with it.
 type HarfbuzzShaper struct {
 	buf *harfbuzz.Buffer
 
+	plans *harfbuzz.ShapePlanCache
+
 	fonts fontLRU
 
 	features []harfbuzz.Feature
@@ -64,10 +73,22 @@ func (t *HarfbuzzShaper) shape(input Input, skipExtents bool) Output {
 	// Prepare to shape the text.
 	if t.buf == nil {
 		t.buf = harfbuzz.NewBuffer()
+		t.plans = &harfbuzz.ShapePlanCache{MaxFonts: maxPlanFonts}
+		t.buf.PlanCache = t.plans
 	} else {
 		t.buf.Clear()
 	}
 
+	// reuse font when possible : the cache is keyed by face (and per-input variations),
+	// since the face settings (variations, synthesis) are used by the font
+	font := t.fonts.font(input)
+
+	return shapeWith(t.buf, font, &t.features, input, skipExtents)
+}
+
+// shapeWith does the actual shaping, using the given (cleared) buffer and font.
+// [features] is used as scratch storage.
+func shapeWith(buf *harfbuzz.Buffer, font *harfbuzz.Font, features *[]harfbuzz.Feature, input Input, skipExtents bool) Output {
 	runes, start, end := input.Text, input.RunStart, input.RunEnd
 	if end < start {
 		// Try to guess what the caller actually wanted.
@@ -75,7 +96,7 @@ func (t *HarfbuzzShaper) shape(input Input, skipExtents bool) Output {
 	}
 	start = clamp(start, 0, len(runes))
 	end = clamp(end, 0, len(runes))
-	t.buf.AddRunes(runes, start, end-start)
+	buf.AddRunes(runes, start, end-start)
 
 	// handle vertical sideways text
 	isSideways := false
@@ -85,57 +106,65 @@ func (t *HarfbuzzShaper) shape(input Input, skipExtents bool) Output {
 		isSideways = true
 	}
 
-	t.buf.Props.Direction = input.Direction.Harfbuzz()
-	t.buf.Props.Language = input.Language
-	t.buf.Props.Script = input.Script
-
-	// reuse font when possible
-	font, ok := t.fonts.Get(input.Face.Font)
-	if !ok { // create a new font and cache it
-		font = harfbuzz.NewFont(input.Face)
-		t.fonts.Put(input.Face.Font, font)
+	buf.Props.Direction = input.Direction.Harfbuzz()
+	buf.Props.Language = input.Language
+	buf.Props.Script = input.Script
+	if input.GlyphFlags&GlyphUnsafeToConcat != 0 {
+		buf.Flags |= harfbuzz.ProduceUnsafeToConcat
+	}
+	if input.GlyphFlags&GlyphSafeToInsertTatweel != 0 {
+		buf.Flags |= harfbuzz.ProduceSafeToInsertTatweel
 	}
+
 	// adjust the user provided fields
 	font.XScale = int32(input.Size.Ceil()) << scaleShift
 	font.YScale = font.XScale
 
-	if L := len(input.FontFeatures); cap(t.features) < L {
-		t.features = make([]harfbuzz.Feature, L)
-	} else {
-		t.features = t.features[0:L]
-	}
-	for i, f := range input.FontFeatures {
-		t.features[i] = harfbuzz.Feature{
+	*features = (*features)[:0]
+	for _, f := range input.FontFeatures {
+		feature := harfbuzz.Feature{
 			Tag:   f.Tag,
 			Value: f.Value,
 			Start: harfbuzz.FeatureGlobalStart,
 			End:   harfbuzz.FeatureGlobalEnd,
 		}
+		if f.isRanged() {
+			if f.End <= start || f.Start >= end { // not in the run
+				continue
+			}
+			// the cluster values are the indices into the whole text;
+			// features covering the whole run are simply marked as global
+			if f.Start > start || f.End < end {
+				feature.Start, feature.End = f.Start, f.End
+			}
+		}
+		*features = append(*features, feature)
 	}
 
 	// Actually use harfbuzz to shape the text.
-	t.buf.Shape(font, t.features)
+	buf.Shape(font, *features)
 
 	// Convert the shaped text into an Output.
 	isVertical := input.Direction.IsVertical()
-	glyphs := make([]Glyph, len(t.buf.Info))
+	glyphs := make([]Glyph, len(buf.Info))
 	for i := range glyphs {
-		g := t.buf.Info[i].Glyph
+		g := buf.Info[i].Glyph
 		glyphs[i] = Glyph{
-			ClusterIndex: t.buf.Info[i].Cluster,
+			ClusterIndex: buf.Info[i].Cluster,
 			GlyphID:      g,
-			Mask:         t.buf.Info[i].Mask,
+			Mask:         buf.Info[i].Mask,
+			Flags:        glyphFlags(buf.Info[i].Mask),
 		}
 
 		if isVertical {
-			glyphs[i].YAdvance = fixed.I(int(t.buf.Pos[i].YAdvance)) >> scaleShift
+			glyphs[i].YAdvance = fixed.I(int(buf.Pos[i].YAdvance)) >> scaleShift
 			glyphs[i].Advance = glyphs[i].YAdvance
 		} else {
-			glyphs[i].XAdvance = fixed.I(int(t.buf.Pos[i].XAdvance)) >> scaleShift
+			glyphs[i].XAdvance = fixed.I(int(buf.Pos[i].XAdvance)) >> scaleShift
 			glyphs[i].Advance = glyphs[i].XAdvance
 		}
-		glyphs[i].XOffset = fixed.I(int(t.buf.Pos[i].XOffset)) >> scaleShift
-		glyphs[i].YOffset = fixed.I(int(t.buf.Pos[i].YOffset)) >> scaleShift
+		glyphs[i].XOffset = fixed.I(int(buf.Pos[i].XOffset)) >> scaleShift
+		glyphs[i].YOffset = fixed.I(int(buf.Pos[i].YOffset)) >> scaleShift
 
 		if skipExtents {
 			continue
@@ -153,8 +182,9 @@ func (t *HarfbuzzShaper) shape(input Input, skipExtents bool) Output {
 	out := Output{
 		Glyphs:    glyphs,
 		Direction: input.Direction,
-		Face:      input.Face,
+		Face:      font.Face(),
 		Size:      input.Size,
+		Language:  input.Language,
 	}
 	out.Runes.Offset = input.RunStart
 	out.Runes.Count = input.RunEnd - input.RunStart
@@ -179,6 +209,21 @@ func (t *HarfbuzzShaper) shape(input Input, skipExtents bool) Output {
 	return out
 }
 
+// glyphFlags extracts the public flags from an harfbuzz glyph mask
+func glyphFlags(mask harfbuzz.GlyphMask) GlyphFlags {
+	var flags GlyphFlags
+	if mask&harfbuzz.GlyphUnsafeToBreak != 0 {
+		flags |= GlyphUnsafeToBreak
+	}
+	if mask&harfbuzz.GlyphUnsafeToConcat != 0 {
+		flags |= GlyphUnsafeToConcat
+	}
+	if mask&harfbuzz.GlyphSafeToInsertTatweel != 0 {
+		flags |= GlyphSafeToInsertTatweel
+	}
+	return flags
+}
+
 // Shape turns an input into an output.
 func (t *HarfbuzzShaper) Shape(input Input) Output { return t.shape(input, false) }
 
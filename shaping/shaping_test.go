@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -493,6 +494,142 @@ func TestFeatures(t *testing.T) {
 	tu.Assert(t, len(out.Glyphs) == 1)
 }
 
+func TestRangedFeatures(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/UbuntuMono-R.ttf")
+	frac := ot.MustNewTag("frac")
+	glyphs := func(out Output) (gids []font.GID) {
+		for _, g := range out.Glyphs {
+			gids = append(gids, g.GlyphID)
+		}
+		return gids
+	}
+
+	text := []rune("1/2 1/2")
+	input := Input{
+		Text:      text,
+		RunStart:  0,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper HarfbuzzShaper
+	for _, test := range []struct {
+		start, end int
+		expected   []font.GID
+	}{
+		{0, 0, []font.GID{152, 3, 152}}, // whole text
+		{0, 7, []font.GID{152, 3, 152}},
+		{0, 3, []font.GID{152, 3, 20, 18, 21}},
+		{4, 7, []font.GID{20, 18, 21, 3, 152}},
+		{1, 2, []font.GID{20, 366, 21, 3, 20, 18, 21}}, // fraction slash only
+		{3, 4, []font.GID{20, 18, 21, 3, 20, 18, 21}},
+	} {
+		input.FontFeatures = []FontFeature{{Tag: frac, Value: 1, Start: test.start, End: test.end}}
+		tu.AssertC(t, reflect.DeepEqual(glyphs(shaper.Shape(input)), test.expected), fmt.Sprint(test.start, test.end))
+	}
+
+	// later features take precedence
+	input.FontFeatures = []FontFeature{{Tag: frac, Value: 1}, {Tag: frac, Value: 0, Start: 4, End: 7}}
+	tu.Assert(t, reflect.DeepEqual(glyphs(shaper.Shape(input)), []font.GID{152, 3, 20, 18, 21}))
+
+	// features straddling run boundaries are preserved by Split
+	text = []rune("1/2 عربي 1/2")
+	input.Text, input.RunEnd = text, len(text)
+	input.FontFeatures = []FontFeature{{Tag: frac, Value: 1, Start: 2, End: len(text)}}
+	runs := (&Segmenter{}).Split(input, fixedFontmap{face, benchArFace})
+	tu.Assert(t, len(runs) == 3)
+	for _, run := range runs {
+		tu.Assert(t, reflect.DeepEqual(run.FontFeatures, input.FontFeatures))
+	}
+	// only the '2' of the first run is in the range
+	tu.Assert(t, reflect.DeepEqual(glyphs(shaper.Shape(runs[0])), []font.GID{20, 18, 21, 3}))
+	tu.Assert(t, runs[1].Face == benchArFace && len(shaper.Shape(runs[1]).Glyphs) == 5)
+	tu.Assert(t, reflect.DeepEqual(glyphs(shaper.Shape(runs[2])), []font.GID{152}))
+}
+
+func TestInputVariations(t *testing.T) {
+	b, err := td.Files.ReadFile("common/SourceSans-VF.ttf")
+	tu.AssertNoErr(t, err)
+	face, err := font.ParseTTF(bytes.NewReader(b))
+	tu.AssertNoErr(t, err)
+	wght := ot.MustNewTag("wght")
+
+	text := []rune("Hello world")
+	input := Input{
+		Text:      text,
+		RunStart:  0,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper HarfbuzzShaper
+	regular := shaper.Shape(input)
+	tu.Assert(t, regular.Face == face)
+
+	input.Variations = []font.Variation{{Tag: wght, Value: 900}}
+	bold := shaper.Shape(input)
+	tu.Assert(t, len(face.Coords()) == 0) // face is not modified
+	tu.Assert(t, bold.Face != face && bold.Face.Font == face.Font)
+	tu.Assert(t, bold.Advance > regular.Advance)
+	// the derived face is cached
+	tu.Assert(t, shaper.Shape(input).Face == bold.Face)
+
+	// same result as a face with the variations applied
+	boldFace := font.NewFace(face.Font)
+	boldFace.SetVariations(input.Variations)
+	tu.Assert(t, reflect.DeepEqual(bold.Face.Coords(), boldFace.Coords()))
+	input.Face, input.Variations = boldFace, nil
+	ref := shaper.Shape(input)
+	tu.Assert(t, ref.Face == boldFace && ref.Advance == bold.Advance)
+
+	// variations matching the face settings use the face
+	input.Variations = []font.Variation{{Tag: wght, Value: 900}}
+	tu.Assert(t, shaper.Shape(input).Face == boldFace)
+
+	// not variable font
+	input.Face = benchEnFace
+	tu.Assert(t, shaper.Shape(input).Face == benchEnFace)
+}
+
+func TestAutoOpticalSize(t *testing.T) {
+	b, err := td.Files.ReadFile("toys/Var1.ttf")
+	tu.AssertNoErr(t, err)
+	face, err := font.ParseTTF(bytes.NewReader(b))
+	tu.AssertNoErr(t, err)
+	opsz := ot.MustNewTag("opsz")
+
+	input := Input{
+		Text:      []rune("a"),
+		RunStart:  0,
+		RunEnd:    1,
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      fixed.I(72),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper ConcurrentShaper
+	tu.Assert(t, shaper.Shape(input).Face == face) // opt-in
+
+	input.AutoOpticalSize = true
+	out := shaper.Shape(input)
+	tu.Assert(t, reflect.DeepEqual(out.Face.Coords(), face.VariationCoords([]font.Variation{{Tag: opsz, Value: 72}})))
+	tu.Assert(t, out.Face.Coords()[2] == 1<<14) // max value
+
+	input.Size = fixed.I(10)
+	tu.Assert(t, shaper.Shape(input).Face.Coords()[2] == -1<<14) // min value
+
+	// explicit settings take precedence
+	input.Variations = []font.Variation{{Tag: opsz, Value: 14}}
+	tu.Assert(t, shaper.Shape(input).Face == face) // default value
+}
+
 func TestShapeVertical(t *testing.T) {
 	// consistency check on the internal axis switch
 	// for sideways vertical text
@@ -746,3 +883,38 @@ func TestSpaceReplacement(t *testing.T) {
 	tu.Assert(t, out.Glyphs[2].Width.Round() == 0)
 	tu.Assert(t, out.Glyphs[2].GlyphID == font.EmptyGlyph)
 }
+
+func TestShapeSynthesis(t *testing.T) {
+	regular := benchEnFace
+	// a synthetic bold face sharing the same font
+	bold := font.NewFace(regular.Font)
+	bold.SetSynthesis(font.Synthesis{Embolden: 0.02, Slant: 0.2})
+
+	text := []rune("Hello")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      regular,
+		Size:      fixed.I(100),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("en"),
+	}
+	shaper := HarfbuzzShaper{}
+	regularOut := shaper.Shape(input)
+	input.Face = bold
+	boldOut := shaper.Shape(input)
+
+	tu.Assert(t, len(regularOut.Glyphs) == len(boldOut.Glyphs))
+	strength := fixed.I(2) // 0.02 * 100
+	for i, g := range boldOut.Glyphs {
+		rg := regularOut.Glyphs[i]
+		tu.Assert(t, g.Advance-rg.Advance >= strength-1 && g.Advance-rg.Advance <= strength+1)
+		tu.Assert(t, g.Width > rg.Width)
+	}
+	tu.Assert(t, boldOut.Advance > regularOut.Advance)
+
+	// the regular face is not affected by the cache
+	input.Face = regular
+	tu.Assert(t, shaper.Shape(input).Advance == regularOut.Advance)
+}
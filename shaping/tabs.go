@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"math"
+
+	"github.com/go-text/typesetting/di"
+	"golang.org/x/image/math/fixed"
+)
+
+// TabAlignment specifies how the text following a tab is aligned
+// on a tab stop.
+type TabAlignment uint8
+
+const (
+	// TabStart aligns the start of the text with the tab stop :
+	// the text is placed after the stop.
+	TabStart TabAlignment = iota
+	// TabEnd aligns the end of the text with the tab stop :
+	// the text is placed before the stop.
+	TabEnd
+	// TabCenter centers the text on the tab stop.
+	TabCenter
+	// TabDecimal aligns the first decimal separator of the text
+	// with the tab stop, which is useful for columns of numbers.
+	// If the text has no separator, it is aligned as [TabEnd].
+	TabDecimal
+)
+
+// TabStop is a position on the line where tabs end.
+type TabStop struct {
+	// Position is the distance between the tab stop and the start edge of the line,
+	// which is the left edge for left-to-right paragraphs, the right edge for right-to-left
+	// paragraphs, and the top edge for vertical paragraphs.
+	Position fixed.Int26_6
+	// Alignment specifies how the text following the tab,
+	// up to the next tab or the end of the line, is aligned on the stop.
+	Alignment TabAlignment
+	// Decimal is the separator used by [TabDecimal] stops.
+	// The zero value means '.'.
+	Decimal rune
+}
+
+// TabStops configures how U+0009 CHARACTER TABULATION is expanded
+// during line wrapping.
+//
+// When enabled, the advance of each tab is adjusted so that the following
+// text is aligned on the next tab stop, whose position is measured from the start edge
+// of the line. Since the start edge depends on the paragraph direction
+// (see [WrapConfig.Direction]), tab stops are measured from the right edge of
+// right-to-left paragraphs. For lines mixing directions, the tabs are
+// expanded once the line is visually ordered.
+//
+// A tab found after the last explicit stop advances to the next default stop,
+// placed at multiples of [TabStops.Interval] after the last explicit stop.
+//
+// The widths of the expanded tabs are taken into account when choosing the line breaks,
+// except when [TotalFit] is enabled, which is then ignored for paragraphs containing tabs.
+type TabStops struct {
+	// Enabled turns on tab expansion. If false, the tabs keep the advance
+	// provided by the font.
+	Enabled bool
+	// Stops are the explicit tab stops, sorted by increasing position.
+	Stops []TabStop
+	// Interval is the distance between the default tab stops, expressed
+	// as a multiple of the advance of the space in the font of the tab.
+	// The zero value means 8 spaces.
+	Interval float32
+}
+
+// nextStop returns the first tab stop strictly after [pos], for a tab in [run].
+func (ts *TabStops) nextStop(pos fixed.Int26_6, run *Output) TabStop {
+	var last fixed.Int26_6
+	for _, stop := range ts.Stops {
+		if stop.Position > pos {
+			return stop
+		}
+		last = stop.Position
+	}
+	interval := ts.Interval
+	if interval == 0 {
+		interval = 8
+	}
+	step := fixed.Int26_6(interval * float32(spaceAdvance(run)))
+	if step <= 0 {
+		return TabStop{Position: pos}
+	}
+	n := (pos-last)/step + 1
+	return TabStop{Position: last + n*step}
+}
+
+// spaceAdvance returns the (absolute) advance of the space glyph
+// in the font of [run].
+func spaceAdvance(run *Output) fixed.Int26_6 {
+	if run.Face == nil {
+		return run.Size / 4
+	}
+	gid, ok := run.Face.NominalGlyph(' ')
+	if !ok {
+		return run.Size / 4
+	}
+	var advance float32
+	if run.Direction.IsVertical() {
+		advance = run.Face.VerticalAdvance(gid)
+	} else {
+		advance = run.Face.HorizontalAdvance(gid)
+	}
+	return abs(run.FromFontUnit(float32(math.Abs(float64(advance)))))
+}
+
+// tabber stores the paragraph information used to expand tabs.
+type tabber struct {
+	config TabStops
+	// direction is the paragraph direction
+	direction di.Direction
+	// backward is true if the start edge of the lines is the right (or bottom) one
+	backward bool
+	text     []rune
+
+	// scratch buffers
+	runs     []Output
+	order    []int
+	modified []bool
+}
+
+// prepare initializes the tabber for [paragraph], returning false if
+// the paragraph has no tab.
+func (t *tabber) prepare(config TabStops, direction di.Direction, paragraph []rune) bool {
+	t.config = config
+	t.direction = direction
+	t.backward = direction.Progression() == di.TowardTopLeft
+	t.text = paragraph
+	return hasTab(paragraph)
+}
+
+// hasTab returns true if [paragraph] contains U+0009
+func hasTab(paragraph []rune) bool {
+	for _, r := range paragraph {
+		if r == '\t' {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingTab is a tab whose advance depends on the following text
+type pendingTab struct {
+	run, glyph int
+	stop       TabStop
+	// start is the position of the tab
+	start fixed.Int26_6
+	// segment is the advance of the text following the tab
+	segment fixed.Int26_6
+	// decimal is the advance of the text before the decimal separator, or -1
+	decimal fixed.Int26_6
+}
+
+// layout walks the runs of [line] in the order given by [order] (and by [t.backward]
+// for the glyphs inside each run), and computes the tab advances.
+// It returns the difference between the expanded and the original advances.
+// If [apply] is true, the runs of [line] are updated (with new glyph slices).
+func (t *tabber) layout(line []Output, order []int, apply bool) (extra fixed.Int26_6) {
+	if apply {
+		t.modified = append(t.modified[:0], make([]bool, len(line))...)
+	}
+	var (
+		pos     fixed.Int26_6
+		pending pendingTab
+		waiting bool
+	)
+	setAdvance := func(runIndex, glyphIndex int, advance fixed.Int26_6) {
+		run := &line[runIndex]
+		g := run.Glyphs[glyphIndex]
+		extra += advance - abs(g.Advance)
+		if !apply {
+			return
+		}
+		if !t.modified[runIndex] {
+			run.Glyphs = append([]Glyph(nil), run.Glyphs...)
+			t.modified[runIndex] = true
+		}
+		gl := &run.Glyphs[glyphIndex]
+		if run.Direction.IsVertical() {
+			// vertical advances are negative
+			gl.Advance, gl.YAdvance = -advance, -advance
+		} else {
+			gl.Advance, gl.XAdvance = advance, advance
+		}
+	}
+	resolve := func() {
+		advance := pending.stop.Position - pending.start
+		switch pending.stop.Alignment {
+		case TabEnd:
+			advance -= pending.segment
+		case TabCenter:
+			advance -= pending.segment / 2
+		case TabDecimal:
+			if pending.decimal >= 0 {
+				advance -= pending.decimal
+			} else {
+				advance -= pending.segment
+			}
+		}
+		if advance < 0 {
+			advance = 0
+		}
+		setAdvance(pending.run, pending.glyph, advance)
+		pos = pending.start + advance + pending.segment
+		waiting = false
+	}
+
+	for _, runIndex := range order {
+		run := &line[runIndex]
+		for k := range run.Glyphs {
+			glyphIndex := k
+			if t.backward {
+				glyphIndex = len(run.Glyphs) - 1 - k
+			}
+			g := run.Glyphs[glyphIndex]
+			var r rune
+			if g.ClusterIndex >= 0 && g.ClusterIndex < len(t.text) {
+				r = t.text[g.ClusterIndex]
+			}
+			if r != '\t' {
+				if !waiting {
+					pos += abs(g.Advance)
+					continue
+				}
+				decimal := pending.stop.Decimal
+				if decimal == 0 {
+					decimal = '.'
+				}
+				if r == decimal && pending.decimal < 0 {
+					pending.decimal = pending.segment
+				}
+				pending.segment += abs(g.Advance)
+				continue
+			}
+
+			if waiting {
+				resolve()
+			}
+			stop := t.config.nextStop(pos, run)
+			if stop.Alignment == TabStart {
+				setAdvance(runIndex, glyphIndex, stop.Position-pos)
+				pos = stop.Position
+				continue
+			}
+			pending = pendingTab{run: runIndex, glyph: glyphIndex, stop: stop, start: pos, decimal: -1}
+			waiting = true
+		}
+	}
+	if waiting {
+		resolve()
+	}
+
+	if apply {
+		for i, m := range t.modified {
+			if m {
+				line[i].RecomputeAdvance()
+			}
+		}
+	}
+	return extra
+}
+
+// candidateExtra returns the additional advance of the tabs
+// of the line candidate made of [runs] followed by [last], in logical order.
+// The candidate is visually ordered as the final line is, so that
+// the result matches [expand].
+func (t *tabber) candidateExtra(runs []Output, last Output) fixed.Int26_6 {
+	t.runs = append(append(t.runs[:0], runs...), last)
+	computeBidiOrdering(t.direction, t.runs)
+	return t.layout(t.runs, t.visualOrder(t.runs), false)
+}
+
+// expand expands the tabs of [line], whose visual order must have been computed,
+// modifying the runs in place, with new glyph slices.
+func (t *tabber) expand(line Line) {
+	t.layout(line, t.visualOrder(line), true)
+}
+
+// visualOrder returns the indices of the runs of [line], from its start edge,
+// using their [Output.VisualIndex].
+func (t *tabber) visualOrder(line Line) []int {
+	t.order = append(t.order[:0], make([]int, len(line))...)
+	for i, run := range line {
+		index := int(run.VisualIndex)
+		if t.backward {
+			index = len(line) - 1 - index
+		}
+		t.order[index] = i
+	}
+	return t.order
+}
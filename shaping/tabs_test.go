@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+// glyphPositions returns the position of the first glyph of each rune cluster,
+// measured from the left edge of the line
+func glyphPositions(line Line) map[int]fixed.Int26_6 {
+	runs := append(Line(nil), line...)
+	sort.Slice(runs, func(i, j int) bool { return runs[i].VisualIndex < runs[j].VisualIndex })
+	out := map[int]fixed.Int26_6{}
+	var pos fixed.Int26_6
+	for _, run := range runs {
+		for _, g := range run.Glyphs {
+			if _, has := out[g.ClusterIndex]; !has {
+				out[g.ClusterIndex] = pos
+			}
+			pos += g.Advance
+		}
+	}
+	return out
+}
+
+func TestTabInterval(t *testing.T) {
+	text := []rune("a\tb\tc")
+	out := shapeLatin(text)
+	original := out.copy()
+	space := spaceAdvance(&out)
+
+	config := WrapConfig{TabStops: TabStops{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(1000), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) == 1)
+	positions := glyphPositions(lines[0])
+	tu.Assert(t, positions[2] == 8*space)
+	tu.Assert(t, positions[4] == 16*space)
+	// the input is not modified
+	for i := range out.Glyphs {
+		tu.Assert(t, out.Glyphs[i] == original.Glyphs[i])
+	}
+
+	config.TabStops.Interval = 4
+	lines, _ = (&LineWrapper{}).WrapParagraphF(config, fixed.I(1000), text, NewSliceIterator([]Output{out}))
+	positions = glyphPositions(lines[0])
+	tu.Assert(t, positions[2] == 4*space)
+	tu.Assert(t, positions[4] == 8*space)
+
+	// tabs are not expanded by default
+	lines, _ = (&LineWrapper{}).WrapParagraphF(WrapConfig{}, fixed.I(1000), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, lineAdvance(lines[0]) == out.Advance)
+}
+
+func TestTabAlignment(t *testing.T) {
+	text := []rune("a\tbb\t12.5\tcc\tdd")
+	out := shapeLatin(text)
+	stops := []TabStop{
+		{Position: fixed.I(50)},
+		{Position: fixed.I(100), Alignment: TabDecimal},
+		{Position: fixed.I(150), Alignment: TabEnd},
+		{Position: fixed.I(200), Alignment: TabCenter},
+	}
+	config := WrapConfig{TabStops: TabStops{Enabled: true, Stops: stops}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(1000), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) == 1)
+	positions := glyphPositions(lines[0])
+	tu.Assert(t, positions[2] == fixed.I(50))
+	tu.Assert(t, positions[7] == fixed.I(100))                                          // decimal separator
+	tu.Assert(t, positions[12] == fixed.I(150))                                         // end of "cc"
+	tu.Assert(t, positions[13]+(lineAdvance(lines[0])-positions[13])/2 == fixed.I(200)) // center of "dd"
+}
+
+func TestTabWrap(t *testing.T) {
+	text := []rune("aaa\tbbb\tccc\tddd")
+	out := shapeLatin(text)
+	config := WrapConfig{TabStops: TabStops{Enabled: true, Stops: []TabStop{{Position: fixed.I(60)}}, Interval: 12}}
+	maxWidth := out.Advance + fixed.I(20)
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, maxWidth, text, NewSliceIterator([]Output{out.copy()}))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) > 1)
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) <= maxWidth)
+	}
+	// tab stops are measured from the start of each line
+	second := glyphPositions(lines[1])
+	start := lines[1][0].Runes.Offset
+	tu.Assert(t, text[start-1] == '\t')
+	tu.Assert(t, second[start] == 0)
+}
+
+func TestTabBidi(t *testing.T) {
+	text := []rune("مرحبا\tبالعالم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL,
+		Face: benchArFace, Size: fixed.I(16), Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	config := WrapConfig{Direction: di.DirectionRTL, TabStops: TabStops{Enabled: true, Stops: []TabStop{{Position: fixed.I(80)}}}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(1000), text, NewSliceIterator([]Output{out}))
+	tu.Assert(t, len(lines) == 1)
+	// the stop is measured from the right edge : the second word
+	// starts 80 pixels from the right edge
+	line := lines[0]
+	positions := glyphPositions(line)
+	tu.Assert(t, lineAdvance(line)-positions[5] == fixed.I(80))
+}
+
+func TestTabCandidateBidi(t *testing.T) {
+	text := []rune("مرحبا ab\tcd")
+	var shaper HarfbuzzShaper
+	runs := []Output{
+		shaper.Shape(Input{
+			Text: text, RunStart: 0, RunEnd: 6, Direction: di.DirectionRTL,
+			Face: benchArFace, Size: fixed.I(16), Script: language.Arabic, Language: language.NewLanguage("ar"),
+		}),
+		shaper.Shape(Input{
+			Text: text, RunStart: 6, RunEnd: 9, Direction: di.DirectionLTR,
+			Face: benchEnFace, Size: fixed.I(16), Script: language.Latin, Language: language.NewLanguage("en"),
+		}),
+		shaper.Shape(Input{
+			Text: text, RunStart: 9, RunEnd: 11, Direction: di.DirectionLTR,
+			Face: benchEnFace, Size: fixed.I(16), Script: language.Latin, Language: language.NewLanguage("en"),
+		}),
+	}
+
+	// the Latin runs are visually reversed : the candidate
+	// is measured as the final line
+	var tb tabber
+	tb.prepare(TabStops{Enabled: true, Interval: 4}, di.DirectionRTL, text)
+	extra := tb.candidateExtra(runs[:2], runs[2])
+	line := append(Line(nil), runs...)
+	computeBidiOrdering(di.DirectionRTL, line)
+	before := lineAdvance(line)
+	tb.expand(line)
+	tu.Assert(t, lineAdvance(line)-before == extra)
+}
@@ -0,0 +1,373 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// SpacingTrim selects how the blank half of fullwidth punctuation
+// used in Chinese and Japanese text is removed, following the CSS
+// 'text-spacing-trim' property.
+//
+// See https://www.w3.org/TR/css-text-4/#text-spacing-trim-property
+type SpacingTrim uint8
+
+const (
+	// SpacingTrimSpaceAll keeps the spacing of all fullwidth punctuation.
+	SpacingTrimSpaceAll SpacingTrim = iota
+	// SpacingTrimNormal trims the punctuation adjacent to other punctuation
+	// (for instance the first bracket of '」「'), and the opening punctuation at the start of
+	// the lines, except for the first line of the paragraph.
+	SpacingTrimNormal
+	// SpacingTrimStart is the same as [SpacingTrimNormal], but also trims the opening
+	// punctuation at the start of the first line.
+	SpacingTrimStart
+	// SpacingTrimBoth is the same as [SpacingTrimStart], and also trims the closing
+	// punctuation at the end of the lines.
+	SpacingTrimBoth
+	// SpacingTrimAll trims all fullwidth punctuation.
+	SpacingTrimAll
+)
+
+func (st SpacingTrim) String() string {
+	switch st {
+	case SpacingTrimSpaceAll:
+		return "space-all"
+	case SpacingTrimNormal:
+		return "normal"
+	case SpacingTrimStart:
+		return "trim-start"
+	case SpacingTrimBoth:
+		return "trim-both"
+	case SpacingTrimAll:
+		return "trim-all"
+	default:
+		return "unknown spacing trim"
+	}
+}
+
+// trimsAdjacent returns true if the punctuation adjacent to other punctuation is trimmed.
+func (st SpacingTrim) trimsAdjacent() bool {
+	return st == SpacingTrimNormal || st == SpacingTrimStart || st == SpacingTrimBoth
+}
+
+// TextSpacing configures the spacing adjustments used for Chinese and Japanese text.
+//
+// It is applied in three steps : [TextSpacing.Features] returns the font features
+// to use when shaping, [TextSpacing.Apply] adjusts the shaped runs, and [TextSpacing.Configure]
+// adjusts the [WrapConfig] used to trim the punctuation at the edges of the lines.
+type TextSpacing struct {
+	// Trim selects the fullwidth punctuation whose blank half is removed.
+	//
+	// The 'chws' (or 'vchw' for vertical text) font feature is used to trim
+	// adjacent punctuation, and the 'halt' (or 'vhal') feature to trim all punctuation or,
+	// as a fallback, specific punctuation. For fonts without these features,
+	// the glyph advances are halved.
+	Trim SpacingTrim
+	// Autospace, if true, adds 1/8 em between ideographs and non-ideographic
+	// letters or digits, as the CSS 'text-autospace: normal' property does.
+	// The space is not added at the start of the lines.
+	Autospace bool
+}
+
+var (
+	tagHalt = ot.MustNewTag("halt")
+	tagVhal = ot.MustNewTag("vhal")
+	tagChws = ot.MustNewTag("chws")
+	tagVchw = ot.MustNewTag("vchw")
+)
+
+// isFullwidthOpening matches the opening brackets and quotes
+// which are fullwidth in CJK fonts
+func isFullwidthOpening(r rune) bool {
+	switch r {
+	case '‘', '“', '〈', '《', '「', '『', '【', '〔',
+		'〖', '〘', '〚', '〝', '（', '［', '｛', '｟':
+		return true
+	}
+	return false
+}
+
+// isFullwidthClosing matches the closing brackets and quotes, commas and stops
+// which are fullwidth in CJK fonts
+func isFullwidthClosing(r rune) bool {
+	switch r {
+	case '’', '”', '、', '。', '〉', '》', '」', '』',
+		'】', '〕', '〗', '〙', '〛', '〞', '〟', '）',
+		'，', '．', '］', '｝', '｠':
+		return true
+	}
+	return false
+}
+
+// isFullwidthMiddle matches the middle dots, colons and semicolons
+// which are fullwidth in CJK fonts
+func isFullwidthMiddle(r rune) bool {
+	return r == '・' || r == '：' || r == '；'
+}
+
+// trimsAdjacent returns true if text[i] is trimmed because of the adjacent punctuation :
+// opening punctuation after any punctuation or an ideographic space, and closing punctuation
+// before closing punctuation or a middle dot.
+func trimsAdjacent(text []rune, i int) bool {
+	r := text[i]
+	if isFullwidthOpening(r) && i > 0 {
+		p := text[i-1]
+		return isFullwidthOpening(p) || isFullwidthClosing(p) || isFullwidthMiddle(p) || p == '\u3000'
+	}
+	if isFullwidthClosing(r) && i+1 < len(text) {
+		n := text[i+1]
+		return isFullwidthClosing(n) || isFullwidthMiddle(n)
+	}
+	return false
+}
+
+// isIdeograph returns true for the ideographs separated from
+// letters and digits by the autospace.
+func isIdeograph(r rune) bool { return isWideScript(r) && unicode.In(r, unicode.L, unicode.Nl) }
+
+// isAutospaceAlnum returns true for the non-ideographic letters and digits,
+// excluding their fullwidth forms.
+func isAutospaceAlnum(r rune) bool {
+	if r >= '\uFF00' && r <= '\uFFEF' {
+		return false
+	}
+	return (unicode.IsLetter(r) && !isWideScript(r)) || unicode.Is(unicode.Nd, r)
+}
+
+// Features returns the font features to add to [input] for the punctuation trimming
+// of [ts.Trim] to be performed by the font, if it supports them. [input.Face] must be set.
+// The returned features may be ranged (see [FontFeature.Start]).
+func (ts TextSpacing) Features(input Input) []FontFeature {
+	if ts.Trim == SpacingTrimSpaceAll || input.Face == nil {
+		return nil
+	}
+	halt, chws := tagHalt, tagChws
+	if input.Direction.IsVertical() {
+		halt, chws = tagVhal, tagVchw
+	}
+	gpos := &input.Face.GPOS
+	_, hasHalt := gpos.FindFeatureIndex(halt)
+	if ts.Trim == SpacingTrimAll {
+		if hasHalt {
+			return []FontFeature{{Tag: halt, Value: 1}}
+		}
+		return nil
+	}
+	if _, hasChws := gpos.FindFeatureIndex(chws); hasChws {
+		return []FontFeature{{Tag: chws, Value: 1}}
+	}
+	if !hasHalt {
+		return nil
+	}
+	// use 'halt' on the punctuation to trim
+	var out []FontFeature
+	for i := input.RunStart; i < input.RunEnd; i++ {
+		if trimsAdjacent(input.Text, i) {
+			out = append(out, FontFeature{Tag: halt, Value: 1, Start: i, End: i + 1})
+		}
+	}
+	return out
+}
+
+// isFullwidth returns true if [g] is a fullwidth glyph
+// of [run], which may be trimmed.
+func isFullwidth(run *Output, g Glyph) bool {
+	return g.GlyphsCount() == 1 && g.RunesCount() == 1 && abs(g.Advance) >= run.Size*3/4
+}
+
+// halveGlyph removes the blank half of the fullwidth punctuation [g],
+// whose class is given by [r].
+func halveGlyph(g *Glyph, vertical bool, r rune) {
+	half := g.Advance / 2 // negative for vertical text
+	// the ink of opening punctuation is on the end side, and centered for middle dots
+	var shift fixed.Int26_6
+	if isFullwidthOpening(r) {
+		shift = half
+	} else if isFullwidthMiddle(r) {
+		shift = half / 2
+	}
+	g.Advance -= half
+	if vertical {
+		g.YAdvance -= half
+		g.YOffset -= shift
+	} else {
+		g.XAdvance -= half
+		g.XOffset -= shift
+	}
+}
+
+// Apply adjusts [runs], shaped from [text] (usually with the features returned by
+// [TextSpacing.Features]) and given in logical order :
+//   - the fullwidth punctuation to be trimmed (not including the punctuation at the
+//     edges of the lines, see [TextSpacing.Configure]) which has not been trimmed
+//     by the font has its advance halved
+//   - if [ts.Autospace] is true, 1/8 em is added between ideographs and
+//     non-ideographic letters or digits.
+//
+// Right-to-left runs are not modified.
+func (ts TextSpacing) Apply(runs []Output, text []rune) {
+	for i := range runs {
+		run := &runs[i]
+		if !run.Direction.IsVertical() && run.Direction.Progression() == di.TowardTopLeft {
+			continue
+		}
+		vertical := run.Direction.IsVertical()
+		modified := false
+		for j := range run.Glyphs {
+			g := &run.Glyphs[j]
+			c := g.ClusterIndex
+			if c < 0 || c >= len(text) {
+				continue
+			}
+			r := text[c]
+			if isPunct := isFullwidthOpening(r) || isFullwidthClosing(r) || isFullwidthMiddle(r); isPunct && isFullwidth(run, *g) {
+				if ts.Trim == SpacingTrimAll || ts.Trim.trimsAdjacent() && trimsAdjacent(text, c) {
+					halveGlyph(g, vertical, r)
+					modified = true
+				}
+			}
+			isClusterStart := j == 0 || run.Glyphs[j-1].ClusterIndex != c
+			if ts.Autospace && isClusterStart && c > 0 {
+				prev := text[c-1]
+				if isIdeograph(prev) && isAutospaceAlnum(r) || isAutospaceAlnum(prev) && isIdeograph(r) {
+					addStartSpacing(g, vertical, run.Size/8)
+					modified = true
+				}
+			}
+		}
+		if modified {
+			run.RecomputeAdvance()
+		}
+	}
+}
+
+// addStartSpacing adds [spacing] before [g], which is recorded as
+// letter spacing, so that it is trimmed at the start of the lines.
+func addStartSpacing(g *Glyph, vertical bool, spacing fixed.Int26_6) {
+	if vertical { // vertical advances are negative
+		spacing = -spacing
+	}
+	g.Advance += spacing
+	if vertical {
+		g.YAdvance += spacing
+		g.YOffset += spacing
+	} else {
+		g.XAdvance += spacing
+		g.XOffset += spacing
+	}
+	g.startLetterSpacing += spacing
+}
+
+// Configure updates [config] to trim the fullwidth punctuation at the edges of
+// the lines, as specified by [ts.Trim] (see [WrapConfig.SpacingTrim]).
+func (ts TextSpacing) Configure(config *WrapConfig) { config.SpacingTrim = ts.Trim }
+
+// trimmer stores the paragraph information used to trim the
+// fullwidth punctuation at the edges of the lines.
+type trimmer struct {
+	trim SpacingTrim
+	text []rune
+}
+
+func (t *trimmer) prepare(trim SpacingTrim, paragraph []rune) {
+	t.trim = trim
+	t.text = paragraph
+}
+
+// startRune returns true if the first rune of the line starting at [start] is trimmed.
+func (t *trimmer) startRune(start int) bool {
+	switch t.trim {
+	case SpacingTrimNormal:
+		if start == 0 {
+			return false
+		}
+	case SpacingTrimStart, SpacingTrimBoth:
+	default:
+		return false
+	}
+	return start < len(t.text) && isFullwidthOpening(t.text[start])
+}
+
+// endRune returns the index of the rune trimmed at the end of the line [start, end),
+// ignoring trailing whitespace, or -1.
+func (t *trimmer) endRune(start, end int) int {
+	if t.trim != SpacingTrimBoth {
+		return -1
+	}
+	if end > len(t.text) {
+		end = len(t.text)
+	}
+	e := end - 1
+	for e >= start && isWhitespace(t.text[e]) {
+		e--
+	}
+	if e < start || !isFullwidthClosing(t.text[e]) {
+		return -1
+	}
+	return e
+}
+
+// trimmableAdvance returns the advance which would be removed by trimming
+// the rune [index], or 0 if its glyph is not fullwidth.
+func trimmableAdvance(runs []Output, index int) fixed.Int26_6 {
+	for i := range runs {
+		run := &runs[i]
+		if index < run.Runes.Offset || index >= run.Runes.Offset+run.Runes.Count {
+			continue
+		}
+		for _, g := range run.Glyphs {
+			if g.ClusterIndex == index && isFullwidth(run, g) {
+				return abs(g.Advance / 2)
+			}
+		}
+	}
+	return 0
+}
+
+// candidateTrim returns the advance removed from the line candidate
+// made of [runs] followed by [last], starting at [start] and ending after [breakAtRune].
+func (t *trimmer) candidateTrim(start, breakAtRune int, runs []Output, last Output) fixed.Int26_6 {
+	var trim fixed.Int26_6
+	if t.startRune(start) {
+		trim += trimmableAdvance(runs, start) + trimmableAdvance([]Output{last}, start)
+	}
+	if e := t.endRune(start, breakAtRune+1); e != -1 && e != start {
+		trim += trimmableAdvance(runs, e) + trimmableAdvance([]Output{last}, e)
+	}
+	return trim
+}
+
+// trimEdges trims the punctuation at the edges of [line], modifying
+// the runs in place, with new glyph slices.
+func (t *trimmer) trimEdges(line Line) {
+	start, end := lineRunes(line)
+	trimRune := func(index int) {
+		for i := range line {
+			run := &line[i]
+			if index < run.Runes.Offset || index >= run.Runes.Offset+run.Runes.Count {
+				continue
+			}
+			for j, g := range run.Glyphs {
+				if g.ClusterIndex != index || !isFullwidth(run, g) {
+					continue
+				}
+				run.Glyphs = append([]Glyph(nil), run.Glyphs...)
+				halveGlyph(&run.Glyphs[j], run.Direction.IsVertical(), t.text[index])
+				run.RecomputeAdvance()
+				return
+			}
+		}
+	}
+	if t.startRune(start) {
+		trimRune(start)
+	}
+	if e := t.endRune(start, end); e != -1 && e != start {
+		trimRune(e)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"bytes"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func loadTestdataFont(t *testing.T, file string) *font.Face {
+	b, err := td.Files.ReadFile(file)
+	tu.AssertNoErr(t, err)
+	face, err := font.ParseTTF(bytes.NewReader(b))
+	tu.AssertNoErr(t, err)
+	return face
+}
+
+func shapeCJK(face *font.Face, text []rune, dir di.Direction, features []FontFeature) Output {
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: dir,
+		Face: face, Size: fixed.I(16), Script: language.Han, Language: language.NewLanguage("ja"),
+		FontFeatures: features,
+	}
+	return (&HarfbuzzShaper{}).Shape(input)
+}
+
+// glyphAdvance returns the (absolute) advance of the glyph for the rune [index]
+func glyphAdvance(runs []Output, index int) fixed.Int26_6 {
+	for _, run := range runs {
+		for _, g := range run.Glyphs {
+			if g.ClusterIndex == index {
+				return abs(g.Advance)
+			}
+		}
+	}
+	return -1
+}
+
+func TestSpacingTrimAdjacent(t *testing.T) {
+	// this font does not support 'halt' nor 'chws'
+	face := loadTestdataFont(t, "common/mplus-1p-regular.ttf")
+	text := []rune("「漢字」「かな」。")
+	em, half := fixed.I(16), fixed.I(8)
+
+	for _, dir := range []di.Direction{di.DirectionLTR, di.DirectionTTB} {
+		ts := TextSpacing{Trim: SpacingTrimNormal}
+		tu.Assert(t, ts.Features(Input{Face: face, Text: text, RunEnd: len(text), Direction: dir}) == nil)
+		out := shapeCJK(face, text, dir, nil)
+		for i := range text {
+			tu.Assert(t, glyphAdvance([]Output{out}, i) == em)
+		}
+		before := abs(out.Advance)
+		runs := []Output{out.copy()}
+		ts.Apply(runs, text)
+		tu.Assert(t, abs(runs[0].Advance) == before-2*half)
+		for i, expected := range []fixed.Int26_6{em, em, em, em, half, em, em, half, em} {
+			tu.Assert(t, glyphAdvance(runs, i) == expected)
+		}
+		// the opening bracket is moved toward the previous glyph
+		moved, original := runs[0].Glyphs[4], out.Glyphs[4]
+		if dir.IsVertical() {
+			tu.Assert(t, moved.YOffset == original.YOffset+half)
+		} else {
+			tu.Assert(t, moved.XOffset == original.XOffset-half)
+		}
+
+		ts.Trim = SpacingTrimAll
+		runs = []Output{shapeCJK(face, text, dir, nil)}
+		ts.Apply(runs, text)
+		tu.Assert(t, abs(runs[0].Advance) == before-5*half)
+	}
+}
+
+func TestSpacingTrimFeatures(t *testing.T) {
+	// this font supports 'halt' and 'vhal', but not 'chws'
+	face := loadTestdataFont(t, "common/NotoSansCJKjp-VF.otf")
+	text := []rune("「漢字」「かな」。")
+	half := fixed.I(8)
+
+	for _, dir := range []di.Direction{di.DirectionLTR, di.DirectionTTB} {
+		input := Input{Face: face, Text: text, RunEnd: len(text), Direction: dir}
+		ts := TextSpacing{Trim: SpacingTrimNormal}
+		features := ts.Features(input)
+		tu.Assert(t, len(features) == 2)
+		tu.Assert(t, features[0].Start == 4 && features[1].Start == 7)
+
+		runs := []Output{shapeCJK(face, text, dir, features)}
+		tu.Assert(t, glyphAdvance(runs, 4) == half && glyphAdvance(runs, 7) == half)
+		before := runs[0].Advance
+		// the punctuation trimmed by the font is not trimmed again
+		ts.Apply(runs, text)
+		tu.Assert(t, runs[0].Advance == before)
+
+		ts.Trim = SpacingTrimAll
+		features = ts.Features(input)
+		tu.Assert(t, len(features) == 1 && features[0].End == 0)
+	}
+}
+
+func TestAutospace(t *testing.T) {
+	face := loadTestdataFont(t, "common/mplus-1p-regular.ttf")
+	text := []rune("漢字abc漢字123")
+	for _, dir := range []di.Direction{di.DirectionLTR, di.DirectionTTB} {
+		out := shapeCJK(face, text, dir, nil)
+		before := abs(out.Advance)
+		runs := []Output{out}
+		TextSpacing{Autospace: true}.Apply(runs, text)
+		tu.Assert(t, abs(runs[0].Advance) == before+3*fixed.I(2))
+	}
+
+	// the space is trimmed at the start of the lines
+	text = []rune("漢字漢字abc")
+	out := shapeCJK(face, text, di.DirectionLTR, nil)
+	latin := advanceOf(out.Glyphs[4:])
+	runs := []Output{out}
+	TextSpacing{Autospace: true}.Apply(runs, text)
+	tu.Assert(t, runs[0].Advance == out.Advance+fixed.I(2))
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, fixed.I(16*4+4), text, NewSliceIterator(runs))
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, lineAdvance(lines[1]) == latin)
+}
+
+func TestSpacingTrimLineEdges(t *testing.T) {
+	face := loadTestdataFont(t, "common/mplus-1p-regular.ttf")
+	em, half := fixed.I(16), fixed.I(8)
+
+	text := []rune("「漢字」漢字「漢字」")
+	out := shapeCJK(face, text, di.DirectionLTR, nil)
+	for _, test := range []struct {
+		trim                SpacingTrim
+		firstStart, lineEnd fixed.Int26_6
+		secondStart         fixed.Int26_6
+	}{
+		{SpacingTrimSpaceAll, em, em, em},
+		{SpacingTrimNormal, em, em, half},
+		{SpacingTrimStart, half, em, half},
+		{SpacingTrimBoth, half, half, half},
+	} {
+		var config WrapConfig
+		TextSpacing{Trim: test.trim}.Configure(&config)
+		tu.Assert(t, config.SpacingTrim == test.trim)
+		lines, _ := (&LineWrapper{}).WrapParagraphF(config, 6*em, text, NewSliceIterator([]Output{out.copy()}))
+		tu.Assert(t, len(lines) == 2)
+		checkRuneCounts(t, text, lines, 0)
+		tu.Assert(t, lines[1][0].Runes.Offset == 6)
+		tu.Assert(t, glyphAdvance(lines[0], 0) == test.firstStart)
+		tu.Assert(t, glyphAdvance(lines[1], 6) == test.secondStart)
+		tu.Assert(t, glyphAdvance(lines[1], 9) == test.lineEnd)
+	}
+	// the input is not modified
+	tu.Assert(t, glyphAdvance([]Output{out}, 6) == em)
+
+	// the trimmed advance is used to choose the breaks
+	text = []rune("漢字」漢字")
+	out = shapeCJK(face, text, di.DirectionLTR, nil)
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{}, 2*em+half, text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, lines[0][0].Runes.Count == 1)
+	lines, _ = (&LineWrapper{}).WrapParagraphF(WrapConfig{SpacingTrim: SpacingTrimBoth}, 2*em+half, text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, lines[0][0].Runes.Count == 3)
+	tu.Assert(t, lineAdvance(lines[0]) == 2*em+half)
+}
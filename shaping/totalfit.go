@@ -0,0 +1,398 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"math"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// TotalFit configures the optimal (or "total-fit") line breaking algorithm
+// described by Knuth and Plass in "Breaking Paragraphs into Lines".
+//
+// Instead of filling each line as much as possible, the breaks of the whole paragraph
+// are chosen so that the lines are as even as possible, minimizing the sum of
+// the "demerits" of each line.
+//
+// The text is modeled as a sequence of boxes (the clusters), glue (the word separators)
+// and penalties (the UAX#14 line breaking opportunities).
+// The glue may stretch by half its width (or by [Justification.MaxStretch] if set), and
+// may only shrink if [Justification.MinStretch] is set.
+//
+// Only [LineWrapper.WrapParagraph] and [LineWrapper.WrapParagraphF] support this mode, since the whole
+// paragraph must be wrapped to the same width.
+//
+// The zero values of the numeric fields are replaced by the defaults (in parenthesis)
+// used by TeX.
+type TotalFit struct {
+	// Enabled turns on total-fit line breaking.
+	Enabled bool
+	// Tolerance is the maximum adjustment ratio of the lines, that is the
+	// ratio between the space added to a line and its total stretchability (2).
+	// Lines beyond this limit are only used when no better solution exists.
+	Tolerance float64
+	// LinePenalty is added to the badness of each line, favoring
+	// solutions with fewer lines (10).
+	LinePenalty float64
+	// HyphenPenalty is the penalty of a break after a hyphen (50).
+	HyphenPenalty float64
+	// AdjacentFitnessDemerits are added when two adjacent lines have very
+	// different spacing (3000).
+	AdjacentFitnessDemerits float64
+	// DoubleHyphenDemerits are added when two consecutive lines end with a hyphen (3000).
+	DoubleHyphenDemerits float64
+}
+
+func (tf TotalFit) withDefaults() TotalFit {
+	if tf.Tolerance == 0 {
+		tf.Tolerance = 2
+	}
+	if tf.LinePenalty == 0 {
+		tf.LinePenalty = 10
+	}
+	if tf.HyphenPenalty == 0 {
+		tf.HyphenPenalty = 50
+	}
+	if tf.AdjacentFitnessDemerits == 0 {
+		tf.AdjacentFitnessDemerits = 3000
+	}
+	if tf.DoubleHyphenDemerits == 0 {
+		tf.DoubleHyphenDemerits = 3000
+	}
+	return tf
+}
+
+const (
+	// emergencyDemerits are added to lines beyond the tolerance
+	emergencyDemerits = 1e10
+	// overfullDemerits are added to lines which can't fit the width
+	overfullDemerits = 1e12
+)
+
+// fitnessClass returns the fitness class of a line with the adjustment ratio [r] :
+// tight, decent, loose or very loose.
+func fitnessClass(r float64) int {
+	switch {
+	case r < -0.5:
+		return 0
+	case r <= 0.5:
+		return 1
+	case r <= 1:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// badness returns the TeX badness of an adjustment ratio
+func badness(r float64) float64 {
+	if math.IsInf(r, 0) {
+		return 10000
+	}
+	return math.Min(100*math.Abs(r*r*r), 10000)
+}
+
+// totalFitCandidate is a feasible break position
+type totalFitCandidate struct {
+	pos      int // the line ends before pos
+	required bool
+	hyphen   bool // the line ends with an hyphen
+	inserted bool // the hyphen is inserted at an hyphenation point
+}
+
+// totalFitNode stores the best way to reach a candidate
+// with a given fitness class
+type totalFitNode struct {
+	demerits    float64
+	prev        int // index of the previous candidate
+	prevFitness int
+	overfull    bool // the line ending at this node overflows
+	valid       bool
+}
+
+// totalFitMetrics stores the paragraph measures, per rune
+type totalFitMetrics struct {
+	// advance, stretch and shrink are prefix sums :
+	// the value for the runes [a, b) is v[b] - v[a]
+	advance, stretch, shrink []float64
+	// hyphen is the advance of the hyphen inserted after each rune
+	hyphen []float64
+	// boundary is true for the runes starting a cluster
+	boundary []bool
+}
+
+// measureTotalFit computes the metrics of the paragraph, consuming [runs].
+func (l *LineWrapper) measureTotalFit(config WrapConfig, paragraph []rune, runs RunIterator) totalFitMetrics {
+	n := len(paragraph)
+	m := totalFitMetrics{
+		advance:  make([]float64, n+1),
+		stretch:  make([]float64, n+1),
+		shrink:   make([]float64, n+1),
+		boundary: make([]bool, n+1),
+		hyphen:   make([]float64, n),
+	}
+	// hyphens are shaped with the inputs of [runs], if available
+	l.glyphRuns = runs
+	hasHyphens := config.Hyphenator != nil || config.HyphenatorFor != nil || hasSoftHyphen(paragraph)
+	m.boundary[n] = true
+	for {
+		runIndex, run, ok := runs.Next()
+		if !ok {
+			break
+		}
+		if run.Runes.Offset < n {
+			m.boundary[run.Runes.Offset] = true
+		}
+		if hasHyphens {
+			hyphen := float64(abs(l.hyphenFor(runIndex, run).Advance))
+			for i := run.Runes.Offset; i < run.Runes.Offset+run.Runes.Count && i < n; i++ {
+				m.hyphen[i] = hyphen
+			}
+		}
+		for start := 0; start < len(run.Glyphs); {
+			cluster := run.Glyphs[start].ClusterIndex
+			var advance fixed.Int26_6
+			end := start
+			for ; end < len(run.Glyphs) && run.Glyphs[end].ClusterIndex == cluster; end++ {
+				advance += abs(run.Glyphs[end].Advance)
+			}
+			start = end
+			if cluster < 0 || cluster >= n {
+				continue
+			}
+			m.boundary[cluster] = true
+			m.advance[cluster+1] += float64(advance)
+			if !isWordSeparator(paragraph[cluster]) {
+				continue
+			}
+			if maxStretch := config.Justification.MaxStretch; maxStretch > 0 {
+				m.stretch[cluster+1] += float64(maxStretch) * float64(run.Size)
+			} else {
+				m.stretch[cluster+1] += float64(advance) / 2
+			}
+			if minStretch := config.Justification.MinStretch; minStretch < 0 {
+				m.shrink[cluster+1] += math.Min(-float64(minStretch)*float64(run.Size), float64(advance))
+			}
+		}
+	}
+	for i := 1; i <= n; i++ {
+		m.advance[i] += m.advance[i-1]
+		m.stretch[i] += m.stretch[i-1]
+		m.shrink[i] += m.shrink[i-1]
+	}
+	return m
+}
+
+// totalFitCandidates returns the valid UAX#14 break options, starting with
+// the start of the paragraph (shaped by [runs]), and ending with its end.
+func (l *LineWrapper) totalFitCandidates(config WrapConfig, paragraph []rune, runs RunIterator, metrics totalFitMetrics) []totalFitCandidate {
+	out := []totalFitCandidate{{pos: 0}}
+	br := newBreaker(&l.seg, paragraph)
+	br.configure(config, runs)
+	for {
+		option, ok := br.nextWordRaw()
+		if !ok {
+			break
+		}
+		pos := option.breakAtRune + 1
+		if pos <= 0 || pos >= len(paragraph) || !metrics.boundary[pos] {
+			continue
+		}
+		last := paragraph[pos-1]
+		out = append(out, totalFitCandidate{
+			pos:      pos,
+			required: option.required,
+			hyphen:   option.hyphen || last == '-' || last == '\u2010',
+			inserted: option.hyphen,
+		})
+	}
+	return append(out, totalFitCandidate{pos: len(paragraph), required: true})
+}
+
+// totalFitter computes the cost of the lines
+type totalFitter struct {
+	TotalFit
+	candidates []totalFitCandidate
+	metrics    totalFitMetrics
+	width      float64
+	// trailingStart[j] is the start of the whitespace ending the line broken at candidate j
+	trailingStart []int
+}
+
+func newTotalFitter(tf TotalFit, paragraph []rune, candidates []totalFitCandidate, metrics totalFitMetrics, maxWidth fixed.Int26_6) totalFitter {
+	f := totalFitter{
+		TotalFit:      tf.withDefaults(),
+		candidates:    candidates,
+		metrics:       metrics,
+		width:         float64(maxWidth),
+		trailingStart: make([]int, len(candidates)),
+	}
+	for j, cand := range candidates {
+		t := cand.pos
+		for t > 0 && isWhitespace(paragraph[t-1]) {
+			t--
+		}
+		f.trailingStart[j] = t
+	}
+	return f
+}
+
+// line returns the demerits and the fitness class of the line between the candidates [i] and [j].
+// tooLong is true if the line is overfull, even with all its glue shrunk.
+func (f *totalFitter) line(i, j int) (demerits float64, fitness int, overfull, tooLong bool) {
+	start, end := f.candidates[i].pos, f.trailingStart[j]
+	if end < start {
+		end = start
+	}
+	natural := f.metrics.advance[end] - f.metrics.advance[start]
+	if f.candidates[j].inserted {
+		natural += f.metrics.hyphen[f.candidates[j].pos-1]
+	}
+	stretch := f.metrics.stretch[end] - f.metrics.stretch[start]
+	shrink := f.metrics.shrink[end] - f.metrics.shrink[start]
+
+	var r float64 // adjustment ratio
+	switch {
+	case natural == f.width:
+	case natural < f.width:
+		if f.candidates[j].required { // the last line is never stretched
+			r = 0
+		} else if stretch > 0 {
+			r = (f.width - natural) / stretch
+		} else {
+			r = math.Inf(1)
+		}
+	default:
+		if shrink > 0 {
+			r = (f.width - natural) / shrink
+		} else {
+			r = math.Inf(-1)
+		}
+	}
+
+	b := badness(r)
+	demerits = (f.LinePenalty + b) * (f.LinePenalty + b)
+	if f.candidates[j].hyphen {
+		demerits += f.HyphenPenalty * f.HyphenPenalty
+	}
+	overfull = r < -1
+	if overfull {
+		demerits += overfullDemerits
+	} else if r > f.Tolerance {
+		demerits += emergencyDemerits
+	}
+	return demerits, fitnessClass(r), overfull, natural-shrink > f.width
+}
+
+// transition returns the demerits added by two consecutive lines,
+// ending at candidates [i] and [j]
+func (f *totalFitter) transition(i, j, prevFitness, fitness int) float64 {
+	if i == 0 { // no previous line
+		return 0
+	}
+	var out float64
+	if d := fitness - prevFitness; d > 1 || d < -1 {
+		out += f.AdjacentFitnessDemerits
+	}
+	if f.candidates[i].hyphen && f.candidates[j].hyphen {
+		out += f.DoubleHyphenDemerits
+	}
+	return out
+}
+
+// breaks returns the chosen breaks, without the start of the paragraph, and
+// whether the lines ending at them overflow.
+func (f *totalFitter) breaks() (breaks []int, overfull []bool) {
+	candidates := f.candidates
+	nodes := make([][4]totalFitNode, len(candidates))
+	nodes[0][1] = totalFitNode{valid: true}
+	for j := 1; j < len(candidates); j++ {
+		for i := j - 1; i >= 0; i-- {
+			demerits, fitness, isOverfull, tooLong := f.line(i, j)
+			if tooLong && i < j-1 {
+				// previous candidates would only produce longer lines
+				break
+			}
+
+			for prevFitness, prev := range nodes[i] {
+				if !prev.valid {
+					continue
+				}
+				total := prev.demerits + demerits + f.transition(i, j, prevFitness, fitness)
+				if node := &nodes[j][fitness]; !node.valid || total < node.demerits {
+					*node = totalFitNode{demerits: total, prev: i, prevFitness: prevFitness, overfull: isOverfull, valid: true}
+				}
+			}
+
+			if candidates[i].required {
+				// lines can't skip a mandatory break
+				break
+			}
+		}
+	}
+
+	// select the best final node and walk back
+	last := len(candidates) - 1
+	bestFitness := -1
+	for fitness, node := range nodes[last] {
+		if node.valid && (bestFitness == -1 || node.demerits < nodes[last][bestFitness].demerits) {
+			bestFitness = fitness
+		}
+	}
+	for j, fitness := last, bestFitness; j > 0; {
+		node := nodes[j][fitness]
+		breaks = append(breaks, candidates[j].pos)
+		overfull = append(overfull, node.overfull)
+		j, fitness = node.prev, node.prevFitness
+	}
+	for i, j := 0, len(breaks)-1; i < j; i, j = i+1, j-1 {
+		breaks[i], breaks[j] = breaks[j], breaks[i]
+		overfull[i], overfull[j] = overfull[j], overfull[i]
+	}
+	return breaks, overfull
+}
+
+// wrapParagraphTotalFit implements [WrapParagraphF] for [TotalFit] configurations.
+// The breaks are chosen ahead of time, and then used as the only (mandatory) break options
+// of the regular line wrapping algorithm, so that the truncation and bidi reordering logic
+// is preserved.
+func (l *LineWrapper) wrapParagraphTotalFit(config WrapConfig, maxWidth fixed.Int26_6, paragraph []rune, runs RunIterator) (_ []Line, truncated int) {
+	runs.Save()
+	metrics := l.measureTotalFit(config, paragraph, runs)
+	runs.Restore()
+	candidates := l.totalFitCandidates(config, paragraph, runs, metrics)
+	fitter := newTotalFitter(config.TotalFit, paragraph, candidates, metrics, maxWidth)
+	breaks, overfull := fitter.breaks()
+
+	l.Prepare(config, paragraph, runs)
+	l.breaker.planned = breaks
+
+	var (
+		line WrappedLine
+		done bool
+	)
+	for !done {
+		lineWidth := fixed.Int26_6(math.MaxInt32)
+		if l.truncating && l.config.TruncateAfterLines == 1 {
+			// the last line is filled as usual
+			l.breaker.planned = nil
+			lineWidth = maxWidth
+		} else if l.config.BreakPolicy != Never {
+			// overflowing lines are broken as usual
+			for i, b := range breaks {
+				if b > l.lineStartRune {
+					if overfull[i] {
+						lineWidth = maxWidth
+					}
+					break
+				}
+			}
+		}
+		line, done = l.nextLine(lineWidth, maxWidth)
+		if line.Line != nil {
+			l.scratch.paragraphAppend(line.Line)
+		}
+	}
+	return l.scratch.finalParagraph(), line.Truncated
+}
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func shapeLatin(text []rune) Output {
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin,
+	}
+	return (&HarfbuzzShaper{}).Shape(input)
+}
+
+// demerits returns the total demerits of [lines], or false
+// if they use breaks which are not total-fit candidates
+func (f *totalFitter) demerits(lines []Line) (float64, bool) {
+	var (
+		total       float64
+		i           int
+		prevFitness = 1
+	)
+	for _, line := range lines {
+		_, end := lineRunes(line)
+		j := sort.Search(len(f.candidates), func(j int) bool { return f.candidates[j].pos >= end })
+		if j == len(f.candidates) || f.candidates[j].pos != end {
+			return 0, false
+		}
+		demerits, fitness, _, _ := f.line(i, j)
+		total += demerits + f.transition(i, j, prevFitness, fitness)
+		i, prevFitness = j, fitness
+	}
+	return total, true
+}
+
+func TestTotalFit(t *testing.T) {
+	text := []rune("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. " +
+		"Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.")
+	out := shapeLatin(text)
+
+	var wrapper LineWrapper
+	metrics := wrapper.measureTotalFit(WrapConfig{}, text, NewSliceIterator([]Output{out}))
+	candidates := wrapper.totalFitCandidates(WrapConfig{}, text, NewSliceIterator([]Output{out}), metrics)
+
+	for _, width := range []int{100, 120, 150, 200, 300} {
+		maxWidth := fixed.I(width)
+		greedy, _ := wrapper.WrapParagraphF(WrapConfig{}, maxWidth, text, NewSliceIterator([]Output{out.copy()}))
+		lines, truncated := wrapper.WrapParagraphF(WrapConfig{TotalFit: TotalFit{Enabled: true}}, maxWidth, text, NewSliceIterator([]Output{out.copy()}))
+		tu.Assert(t, truncated == 0)
+		checkRuneCounts(t, text, lines, truncated)
+		for _, line := range lines {
+			tu.Assert(t, lineAdvance(line) <= maxWidth)
+		}
+
+		fitter := newTotalFitter(TotalFit{}, text, candidates, metrics, maxWidth)
+		greedyDemerits, ok := fitter.demerits(greedy)
+		tu.Assert(t, ok)
+		demerits, ok := fitter.demerits(lines)
+		tu.Assert(t, ok)
+		tu.AssertC(t, demerits <= greedyDemerits, fmt.Sprint(width))
+	}
+}
+
+func TestTotalFitBreaks(t *testing.T) {
+	// each letter has an advance of 10, and each space an advance of 20
+	text := []rune("aa bbb c d eee fffff")
+	out := Output{Direction: di.DirectionLTR, Runes: Range{Count: len(text)}, Size: fixed.I(10)}
+	for i, r := range text {
+		g := Glyph{ClusterIndex: i, GlyphCount: 1, RuneCount: 1, Advance: fixed.I(10), XAdvance: fixed.I(10), Width: fixed.I(10)}
+		if r == ' ' {
+			g.Advance, g.XAdvance, g.Width = fixed.I(20), fixed.I(20), 0
+		}
+		out.Glyphs = append(out.Glyphs, g)
+	}
+	out.RecomputeAdvance()
+
+	runeCounts := func(lines []Line) (out []int) {
+		for _, line := range lines {
+			out = append(out, line[0].Runes.Count)
+		}
+		return out
+	}
+
+	var wrapper LineWrapper
+	// greedy breaks as "aa bbb c | d eee | fffff", with a very loose second line
+	greedy, _ := wrapper.WrapParagraphF(WrapConfig{}, fixed.I(100), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, reflect.DeepEqual(runeCounts(greedy), []int{9, 6, 5}))
+
+	// total-fit spreads the space : "aa bbb | c d eee | fffff"
+	lines, _ := wrapper.WrapParagraphF(WrapConfig{TotalFit: TotalFit{Enabled: true}}, fixed.I(100), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, reflect.DeepEqual(runeCounts(lines), []int{7, 8, 5}))
+}
+
+func TestTotalFitRequiredBreak(t *testing.T) {
+	text := []rune("aaa bbb ccc\nddd eee fff ggg hhh iii")
+	out := shapeLatin(text)
+	lines, _ := (&LineWrapper{}).WrapParagraphF(WrapConfig{TotalFit: TotalFit{Enabled: true}}, fixed.I(200), text, NewSliceIterator([]Output{out}))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) >= 2)
+	tu.Assert(t, lines[0][0].Runes.Count == len("aaa bbb ccc\n"))
+}
+
+func TestTotalFitMeasureHyphens(t *testing.T) {
+	var l LineWrapper
+	config := WrapConfig{TotalFit: TotalFit{Enabled: true}}
+	// hyphens are only shaped if the paragraph may be hyphenated
+	text := []rune("aaa bbb")
+	m := l.measureTotalFit(config, text, NewSliceIterator([]Output{shapeLatin(text)}))
+	tu.Assert(t, len(l.hyphens) == 0 && m.hyphen[0] == 0)
+
+	text = []rune("aaa b\u00ADbb")
+	m = l.measureTotalFit(config, text, NewSliceIterator([]Output{shapeLatin(text)}))
+	tu.Assert(t, len(l.hyphens) == 1 && m.hyphen[0] > 0)
+
+	l.hyphens = nil
+	text = []rune("aaa bbb")
+	config.Hyphenator = testHyphenator(t)
+	m = l.measureTotalFit(config, text, NewSliceIterator([]Output{shapeLatin(text)}))
+	tu.Assert(t, len(l.hyphens) == 1 && m.hyphen[0] > 0)
+}
+
+func TestTotalFitOverflow(t *testing.T) {
+	text := []rune("a veryveryveryverylongword b")
+	out := shapeLatin(text)
+	config := WrapConfig{BreakPolicy: Never, TotalFit: TotalFit{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(50), text, NewSliceIterator([]Output{out.copy()}))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) == 3)
+	tu.Assert(t, lineAdvance(lines[1]) > fixed.I(50))
+
+	// the long word is broken as usual
+	config.BreakPolicy = WhenNecessary
+	lines, _ = (&LineWrapper{}).WrapParagraphF(config, fixed.I(50), text, NewSliceIterator([]Output{out.copy()}))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) > 3)
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) <= fixed.I(50))
+	}
+}
+
+func TestTotalFitTruncation(t *testing.T) {
+	text := []rune("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua.")
+	out := shapeLatin(text)
+	truncator := shapeLatin([]rune("…"))
+	config := WrapConfig{TruncateAfterLines: 2, Truncator: truncator, TotalFit: TotalFit{Enabled: true}}
+	lines, truncated := (&LineWrapper{}).WrapParagraphF(config, fixed.I(100), text, NewSliceIterator([]Output{out.copy()}))
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, truncated > 0)
+	checkRuneCounts(t, text, lines, truncated)
+	last := lines[1]
+	tu.Assert(t, last[len(last)-1].Runes.Offset == len(text)-truncated)
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) <= fixed.I(100))
+	}
+}
+
+func TestTotalFitBidi(t *testing.T) {
+	text := []rune("مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL,
+		Face: benchArFace, Size: fixed.I(16), Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out := (&HarfbuzzShaper{}).Shape(input)
+	runs := cutRunInto(out, 3)
+
+	config := WrapConfig{Direction: di.DirectionRTL, TotalFit: TotalFit{Enabled: true}}
+	lines, _ := (&LineWrapper{}).WrapParagraphF(config, fixed.I(120), text, NewSliceIterator(runs))
+	checkRuneCounts(t, text, lines, 0)
+	tu.Assert(t, len(lines) > 1)
+	for _, line := range lines {
+		// runs are in reverse visual order
+		for i, run := range line {
+			tu.Assert(t, run.VisualIndex == int32(len(line)-1-i))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+var tagOpsz = ot.MustNewTag("opsz")
+
+// fontKey identifies the face used to shape an [Input]
+// in the font caches.
+type fontKey struct {
+	face *font.Face
+
+	// the following fields are only used when per-input
+	// variations are applied : they store the variation coordinates (as a string,
+	// to be comparable) and a copy of the face settings
+	coords    string
+	synthesis font.Synthesis
+	ppem      [2]uint16
+}
+
+// variations returns the variations to apply on top of the [Input.Face] settings,
+// including the automatic optical size.
+func (input *Input) variations() []font.Variation {
+	if !input.AutoOpticalSize {
+		return input.Variations
+	}
+	// explicit settings have precedence, since they are applied last
+	opsz := font.Variation{Tag: tagOpsz, Value: float32(input.Size) / 64}
+	return append([]font.Variation{opsz}, input.Variations...)
+}
+
+// newFontKey returns the key for [input], and the
+// coordinates to use, if they differ from the face ones.
+func newFontKey(input Input) (fontKey, []font.VarCoord) {
+	key := fontKey{face: input.Face}
+	if len(input.Variations) == 0 && !input.AutoOpticalSize {
+		return key, nil
+	}
+
+	coords := input.Face.VariationCoords(input.variations())
+	if coordsEqual(coords, input.Face.Coords()) {
+		return key, nil
+	}
+
+	buf := make([]byte, 2*len(coords))
+	for i, c := range coords {
+		buf[2*i], buf[2*i+1] = byte(c>>8), byte(c)
+	}
+	key.coords = string(buf)
+	key.synthesis = input.Face.Synthesis()
+	key.ppem[0], key.ppem[1] = input.Face.Ppem()
+	return key, coords
+}
+
+// coordsEqual returns true if the two coordinates are equal,
+// where missing values are interpreted as zero (default)
+func coordsEqual(c1, c2 []font.VarCoord) bool {
+	if len(c1) < len(c2) {
+		c1, c2 = c2, c1
+	}
+	for i, c := range c1 {
+		if i < len(c2) {
+			if c != c2[i] {
+				return false
+			}
+		} else if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newFace returns the face to use for the key : either the input face,
+// or a new face with the same settings and the given [coords].
+func (key fontKey) newFace(coords []font.VarCoord) *font.Face {
+	if key.coords == "" {
+		return key.face
+	}
+	out := font.NewFace(key.face.Font)
+	out.SetSynthesis(key.synthesis)
+	out.SetPpem(key.ppem[0], key.ppem[1])
+	out.SetCoords(coords)
+	return out
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"sort"
+
+	"github.com/go-text/typesetting/language"
+)
+
+// WhiteSpace selects how spaces, tabs and newlines are handled,
+// following the CSS 'white-space' property.
+//
+// It is applied in two steps : [WhiteSpace.Process] transforms the text
+// before it is segmented and shaped, and [WhiteSpace.Configure] adjusts
+// the [WrapConfig] used to wrap the resulting paragraph.
+//
+// See https://www.w3.org/TR/css-text-3/#white-space-property
+type WhiteSpace uint8
+
+const (
+	// WhiteSpaceNormal collapses sequences of spaces, tabs and newlines
+	// into one space, and wraps lines as needed.
+	WhiteSpaceNormal WhiteSpace = iota
+	// WhiteSpaceNowrap collapses white space as [WhiteSpaceNormal], but
+	// never wraps lines.
+	WhiteSpaceNowrap
+	// WhiteSpacePre preserves white space, and only breaks lines at newlines.
+	WhiteSpacePre
+	// WhiteSpacePreWrap preserves white space, and wraps lines as needed.
+	// The trailing spaces of the lines do not take space.
+	WhiteSpacePreWrap
+	// WhiteSpacePreLine collapses sequences of spaces and tabs, but
+	// preserves newlines, and wraps lines as needed.
+	WhiteSpacePreLine
+	// WhiteSpaceBreakSpaces is the same as [WhiteSpacePreWrap], except that
+	// the trailing spaces of the lines take space, and that lines may be broken after
+	// every space.
+	WhiteSpaceBreakSpaces
+)
+
+func (ws WhiteSpace) String() string {
+	switch ws {
+	case WhiteSpaceNormal:
+		return "normal"
+	case WhiteSpaceNowrap:
+		return "nowrap"
+	case WhiteSpacePre:
+		return "pre"
+	case WhiteSpacePreWrap:
+		return "pre-wrap"
+	case WhiteSpacePreLine:
+		return "pre-line"
+	case WhiteSpaceBreakSpaces:
+		return "break-spaces"
+	default:
+		return "unknown white-space"
+	}
+}
+
+// collapsesSpaces returns true if sequences of spaces and tabs are collapsed.
+func (ws WhiteSpace) collapsesSpaces() bool {
+	return ws == WhiteSpaceNormal || ws == WhiteSpaceNowrap || ws == WhiteSpacePreLine
+}
+
+// collapsesNewlines returns true if newlines are transformed into spaces.
+func (ws WhiteSpace) collapsesNewlines() bool {
+	return ws == WhiteSpaceNormal || ws == WhiteSpaceNowrap
+}
+
+// ProcessedText is the result of [WhiteSpace.Process].
+type ProcessedText struct {
+	// Text is the processed text, to be used for segmentation,
+	// shaping and line wrapping.
+	Text []rune
+	// Indices maps each rune of [Text] to the index of the
+	// corresponding rune in the original text.
+	// It has one more element than [Text], which is the length of the
+	// original text, so that the end of ranges may also be mapped.
+	Indices []int
+}
+
+// OriginalRange returns the range of the original text corresponding
+// to [r], which is a range in [Text].
+func (pt ProcessedText) OriginalRange(r Range) Range {
+	start, end := pt.Indices[r.Offset], pt.Indices[r.Offset+r.Count]
+	return Range{Offset: start, Count: end - start}
+}
+
+// isSegmentBreak returns true for the runes considered as newlines
+// by CSS, that is LF and CR.
+func isSegmentBreak(r rune) bool { return r == '\n' || r == '\r' }
+
+// isCollapsibleSpace returns true for spaces and tabs.
+func isCollapsibleSpace(r rune) bool { return r == ' ' || r == '\t' }
+
+// isWideScript returns true for the scripts for which a newline between
+// two characters is removed instead of being transformed into a space.
+func isWideScript(r rune) bool {
+	switch language.LookupScript(r) {
+	case language.Han, language.Hiragana, language.Katakana, language.Bopomofo, language.Yi:
+		return true
+	}
+	return false
+}
+
+// Process applies the white space processing rules of [ws] to [text],
+// which is typically a paragraph (or a whole text for modes preserving newlines).
+//
+// When spaces are collapsed, the spaces and tabs around newlines are removed,
+// as well as the spaces at the start of [text] and after preserved newlines.
+// Collapsed newlines (CR LF counting as one) are transformed into spaces, except
+// between two Chinese or Japanese characters, or next to a U+200B ZERO WIDTH SPACE,
+// where they are removed.
+//
+// For the modes preserving white space, [Text] is [text].
+func (ws WhiteSpace) Process(text []rune) ProcessedText {
+	out := ProcessedText{
+		Text:    make([]rune, 0, len(text)),
+		Indices: make([]int, 0, len(text)+1),
+	}
+	if !ws.collapsesSpaces() {
+		out.Text = append(out.Text, text...)
+		for i := range text {
+			out.Indices = append(out.Indices, i)
+		}
+		out.Indices = append(out.Indices, len(text))
+		return out
+	}
+
+	// lineStart is true at the start of the text, and after preserved newlines
+	lineStart := true
+	for i := 0; i < len(text); {
+		r := text[i]
+		if !isCollapsibleSpace(r) && !isSegmentBreak(r) {
+			out.Text = append(out.Text, r)
+			out.Indices = append(out.Indices, i)
+			lineStart = false
+			i++
+			continue
+		}
+
+		// find the sequence of white space
+		start, hasBreak := i, false
+		for ; i < len(text) && (isCollapsibleSpace(text[i]) || isSegmentBreak(text[i])); i++ {
+			hasBreak = hasBreak || isSegmentBreak(text[i])
+		}
+		if !hasBreak {
+			if !lineStart {
+				out.Text = append(out.Text, ' ')
+				out.Indices = append(out.Indices, start)
+			}
+			continue
+		}
+
+		// spaces around newlines are removed
+		if !ws.collapsesNewlines() {
+			// preserve the newlines, CR LF being kept as is
+			for j := start; j < i; j++ {
+				if isSegmentBreak(text[j]) {
+					out.Text = append(out.Text, text[j])
+					out.Indices = append(out.Indices, j)
+				}
+			}
+			lineStart = true
+			continue
+		}
+
+		// the newlines are collapsed into one space, mapped to the first one
+		firstBreak := start
+		for !isSegmentBreak(text[firstBreak]) {
+			firstBreak++
+		}
+		var before, after rune
+		if len(out.Text) != 0 {
+			before = out.Text[len(out.Text)-1]
+		}
+		if i < len(text) {
+			after = text[i]
+		}
+		if lineStart || before == '\u200B' || after == '\u200B' || (isWideScript(before) && isWideScript(after)) {
+			continue
+		}
+		out.Text = append(out.Text, ' ')
+		out.Indices = append(out.Indices, firstBreak)
+	}
+	out.Indices = append(out.Indices, len(text))
+	return out
+}
+
+// Configure updates [config] to wrap text processed by [ws] :
+//   - [WhiteSpaceNowrap] and [WhiteSpacePre] disable soft line breaks (see [WrapConfig.DisableSoftBreaks]),
+//     and use the [Never] break policy; the other values use the [WhenNecessary]
+//     break policy if [Never] was set, and keep [Always] otherwise
+//   - [WhiteSpacePre], [WhiteSpacePreWrap] and [WhiteSpaceBreakSpaces] enable tab stops
+//     (see [WrapConfig.TabStops]), which are disabled by the other values
+//   - [WhiteSpacePre] and [WhiteSpaceBreakSpaces] disable trailing whitespace trimming
+//   - [WhiteSpaceBreakSpaces] enables [WrapConfig.BreakAfterSpaces]
+//
+// The fields listed above are set for every value, so that the same
+// config may be reused for several [WhiteSpace] values. The other fields
+// of [config] (including the tab stops positions) are not modified.
+func (ws WhiteSpace) Configure(config *WrapConfig) {
+	noWrap := ws == WhiteSpaceNowrap || ws == WhiteSpacePre
+	config.DisableSoftBreaks = noWrap
+	if noWrap {
+		config.BreakPolicy = Never
+	} else if config.BreakPolicy == Never {
+		config.BreakPolicy = WhenNecessary
+	}
+	config.TabStops.Enabled = !ws.collapsesSpaces()
+	config.DisableTrailingWhitespaceTrim = ws == WhiteSpacePre || ws == WhiteSpaceBreakSpaces
+	config.BreakAfterSpaces = ws == WhiteSpaceBreakSpaces
+}
+
+// appendSpaceBreaks appends the break options after the spaces and tabs of [segment],
+// which starts at [offset], except its last rune. The options in [dst] are kept sorted.
+func appendSpaceBreaks(dst []breakOption, offset int, segment []rune) []breakOption {
+	L := len(dst)
+	for i, r := range segment[:len(segment)-1] {
+		if isCollapsibleSpace(r) {
+			dst = append(dst, breakOption{breakAtRune: offset + i})
+		}
+	}
+	if L != 0 && len(dst) != L {
+		sort.SliceStable(dst, func(i, j int) bool { return dst[i].breakAtRune < dst[j].breakAtRune })
+	}
+	return dst
+}
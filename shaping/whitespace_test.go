@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"reflect"
+	"testing"
+
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestWhiteSpaceProcess(t *testing.T) {
+	text := []rune("  a  b\t\n  c\r\n\r\nd 漢\n字 ")
+	for _, test := range []struct {
+		ws       WhiteSpace
+		expected string
+		indices  []int
+	}{
+		{WhiteSpaceNormal, "a b c d 漢字 ", []int{2, 3, 5, 7, 10, 11, 15, 16, 17, 19, 20, 21}},
+		{WhiteSpaceNowrap, "a b c d 漢字 ", []int{2, 3, 5, 7, 10, 11, 15, 16, 17, 19, 20, 21}},
+		{WhiteSpacePreLine, "a b\nc\r\n\r\nd 漢\n字 ", []int{2, 3, 5, 7, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21}},
+		{WhiteSpacePre, string(text), nil},
+		{WhiteSpacePreWrap, string(text), nil},
+		{WhiteSpaceBreakSpaces, string(text), nil},
+	} {
+		out := test.ws.Process(text)
+		tu.AssertC(t, string(out.Text) == test.expected, test.ws.String())
+		tu.Assert(t, len(out.Indices) == len(out.Text)+1)
+		if test.indices != nil {
+			tu.AssertC(t, reflect.DeepEqual(out.Indices, test.indices), test.ws.String())
+		}
+		for i, index := range out.Indices[:len(out.Text)] {
+			r := text[index]
+			tu.Assert(t, r == out.Text[i] || (out.Text[i] == ' ' && (r == '\t' || r == '\n' || r == '\r')))
+		}
+	}
+
+	pt := WhiteSpaceNormal.Process(text)
+	tu.Assert(t, pt.OriginalRange(Range{Offset: 2, Count: 3}) == Range{Offset: 5, Count: 6}) // "b c"
+}
+
+func TestWhiteSpaceWrap(t *testing.T) {
+	wrap := func(ws WhiteSpace, text string, maxWidth fixed.Int26_6) []Line {
+		pt := ws.Process([]rune(text))
+		out := shapeLatin(pt.Text)
+		var config WrapConfig
+		ws.Configure(&config)
+		lines, _ := (&LineWrapper{}).WrapParagraphF(config, maxWidth, pt.Text, NewSliceIterator([]Output{out}))
+		checkRuneCounts(t, pt.Text, lines, 0)
+		return lines
+	}
+	text := "Lorem ipsum dolor sit amet,\nconsectetur adipiscing elit."
+
+	lines := wrap(WhiteSpaceNormal, text, fixed.I(100))
+	tu.Assert(t, len(lines) > 2)
+	lines = wrap(WhiteSpaceNowrap, text, fixed.I(100))
+	tu.Assert(t, len(lines) == 1)
+	lines = wrap(WhiteSpacePre, text, fixed.I(100))
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, lineAdvance(lines[0]) > fixed.I(100))
+	lines = wrap(WhiteSpacePreLine, text, fixed.I(100))
+	tu.Assert(t, len(lines) > 2)
+	hasNewline := false
+	for _, line := range lines {
+		_, end := lineRunes(line)
+		hasNewline = hasNewline || end == len("Lorem ipsum dolor sit amet,\n")
+	}
+	tu.Assert(t, hasNewline)
+
+	// tabs are expanded
+	lines = wrap(WhiteSpacePre, "a\tb", fixed.I(100))
+	tu.Assert(t, lineAdvance(lines[0]) > shapeLatin([]rune("a\tb")).Advance)
+
+	// trailing spaces take space, and lines may be broken between spaces
+	text = "aaaa      bbbb"
+	out := shapeLatin([]rune(text))
+	maxWidth := out.Glyphs[0].Advance*4 + out.Glyphs[4].Advance*3
+	lines = wrap(WhiteSpacePreWrap, text, maxWidth)
+	tu.Assert(t, len(lines) == 2)
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) <= maxWidth)
+	}
+	lines = wrap(WhiteSpaceBreakSpaces, text, maxWidth)
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, lines[0][0].Runes.Count == 7) // "aaaa   "
+	for _, line := range lines {
+		tu.Assert(t, lineAdvance(line) <= maxWidth)
+	}
+
+	// the config may be reused
+	var config WrapConfig
+	WhiteSpacePre.Configure(&config)
+	WhiteSpaceNormal.Configure(&config)
+	tu.Assert(t, reflect.DeepEqual(config, WrapConfig{}))
+	config.BreakPolicy = Always
+	WhiteSpaceBreakSpaces.Configure(&config)
+	WhiteSpacePreLine.Configure(&config)
+	tu.Assert(t, reflect.DeepEqual(config, WrapConfig{BreakPolicy: Always}))
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 
 	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
 	"github.com/go-text/typesetting/segmenter"
 	"golang.org/x/image/math/fixed"
 )
@@ -251,6 +252,75 @@ func cutRun(run Output, mapping []glyphIndex, startRune, endRune int, trimStart
 	return run
 }
 
+// isSafeToBreak returns true if [run] may be cut before [runeIdx] (an index into the paragraph)
+// without re-shaping, that is if [runeIdx] starts a cluster whose glyphs are not
+// flagged with [GlyphUnsafeToBreak].
+func isSafeToBreak(run Output, mapping []glyphIndex, runeIdx int) bool {
+	idx := runeIdx - run.Runes.Offset
+	if idx <= 0 || idx >= len(mapping) { // run boundaries
+		return true
+	}
+	g := mapping[idx]
+	if g >= len(run.Glyphs) {
+		return false
+	}
+	glyph := run.Glyphs[g]
+	return glyph.ClusterIndex == runeIdx && glyph.Flags&GlyphUnsafeToBreak == 0
+}
+
+// reshapeEdges re-shapes, using [reshaper], the start and end of [cut], which is a sub-run of [run] (with
+// index [runIdx]), if they are not safe to break. Other glyphs are reused.
+func reshapeEdges(reshaper Reshaper, runIdx int, run Output, mapping []glyphIndex, cut Output) Output {
+	start, end := cut.Runes.Offset, cut.Runes.Offset+cut.Runes.Count
+	if start >= end {
+		return cut
+	}
+	// safe region is [safeStart, safeEnd)
+	safeStart, safeEnd := start, end
+	if !isSafeToBreak(run, mapping, start) {
+		safeStart++
+		for safeStart < end && !isSafeToBreak(run, mapping, safeStart) {
+			safeStart++
+		}
+	}
+	if !isSafeToBreak(run, mapping, end) {
+		safeEnd--
+		for safeEnd > safeStart && !isSafeToBreak(run, mapping, safeEnd) {
+			safeEnd--
+		}
+	}
+	if safeStart == start && safeEnd == end { // nothing to do
+		return cut
+	}
+	if safeStart >= safeEnd { // no glyph may be reused
+		out := reshaper.Reshape(runIdx, Range{Offset: start, Count: end - start})
+		out.VisualIndex = cut.VisualIndex
+		return out
+	}
+
+	var head, tail []Glyph
+	if safeStart > start {
+		head = reshaper.Reshape(runIdx, Range{Offset: start, Count: safeStart - start}).Glyphs
+	}
+	if safeEnd < end {
+		tail = reshaper.Reshape(runIdx, Range{Offset: safeEnd, Count: end - safeEnd}).Glyphs
+	}
+	middle := cutRun(run, mapping, safeStart, safeEnd-1, false).Glyphs
+	if cut.Direction.Progression() == di.TowardTopLeft {
+		// glyphs are in visual order
+		head, tail = tail, head
+	}
+
+	// do not modify the original run glyphs
+	glyphs := make([]Glyph, 0, len(head)+len(middle)+len(tail))
+	glyphs = append(glyphs, head...)
+	glyphs = append(glyphs, middle...)
+	glyphs = append(glyphs, tail...)
+	cut.Glyphs = glyphs
+	cut.RecalculateAll()
+	return cut
+}
+
 // breakOption represets a location within the rune slice at which
 // it may be safe to break a line of text.
 type breakOption struct {
@@ -258,6 +328,9 @@ type breakOption struct {
 	breakAtRune int
 	// required indicates that the break option is mandatory.
 	required bool
+	// hyphen indicates that the break option is an hyphenation point,
+	// requiring to insert an hyphen.
+	hyphen bool
 }
 
 // isValid returns whether a given option violates shaping rules (like breaking
@@ -300,6 +373,31 @@ type breaker struct {
 	isUnusedWord        bool
 	unusedGraphemeBreak breakOption
 	isUnusedGrapheme    bool
+	// planned, if not nil, restricts the UAX#14 candidates to the given
+	// (sorted) positions, which are then mandatory. It is used by [TotalFit].
+	planned []int
+
+	// hyphenator, if not nil, provides additional break options inside words.
+	hyphenator Hyphenator
+	// hyphenatorFor, if not nil, selects the hyphenator of each language span,
+	// instead of [hyphenator].
+	hyphenatorFor func(lang language.Language) Hyphenator
+	// languages are the language spans of the text, used with [hyphenatorFor]
+	languages []languageSpan
+	// lastLanguage and lastHyphenator cache the last result of [hyphenatorFor],
+	// if hasLast is true
+	lastLanguage   language.Language
+	lastHyphenator Hyphenator
+	hasLast        bool
+	// pendingWordBreaks are the hyphenation points of the current UAX#14 segment,
+	// followed by its break option.
+	pendingWordBreaks []breakOption
+	// afterSoftHyphen is true if the last UAX#14 segment ended with a soft hyphen
+	afterSoftHyphen bool
+	// mandatoryOnly restricts the UAX#14 candidates to the mandatory breaks.
+	mandatoryOnly bool
+	// breakSpaces adds break options after every space and tab.
+	breakSpaces bool
 }
 
 // newBreaker returns a breaker initialized to break the provided text.
@@ -313,23 +411,80 @@ func newBreaker(seg *segmenter.Segmenter, text []rune) *breaker {
 	return br
 }
 
-// nextWordRaw returns a naive break candidate on a uax#14 boundary which may be invalid.
+// configure applies the break settings of [config], for the text shaped by [runs].
+func (b *breaker) configure(config WrapConfig, runs RunIterator) {
+	b.hyphenator = config.Hyphenator
+	if config.HyphenatorFor != nil {
+		b.hyphenatorFor = config.HyphenatorFor
+		b.languages = runLanguages(runs)
+	}
+	b.mandatoryOnly = config.DisableSoftBreaks
+	b.breakSpaces = config.BreakAfterSpaces
+}
+
+// nextWordRaw returns a naive break candidate on a uax#14 boundary (or on an hyphenation point)
+// which may be invalid.
 func (b *breaker) nextWordRaw() (option breakOption, ok bool) {
-	if b.wordSegmenter.Next() {
-		currentSegment := b.wordSegmenter.Line()
-		// Note : we dont use penalties for Mandatory Breaks so far,
-		// we could add it with currentSegment.IsMandatoryBreak
-		breakAtRune := currentSegment.Offset + len(currentSegment.Text) - 1
-		option := breakOption{
-			breakAtRune: breakAtRune,
-			// Don't treat the EOF line break as special. We implicitly always break after
-			// the end of text input anyway.
-			required: currentSegment.IsMandatoryBreak && breakAtRune != b.totalRunes-1,
+	for {
+		option, ok := b.nextWordOption()
+		if !ok {
+			// Unicode rules impose to always break at the end
+			return breakOption{}, false
+		}
+		if b.mandatoryOnly && !option.required && option.breakAtRune != b.totalRunes-1 {
+			continue
+		}
+		if b.planned != nil && option.breakAtRune != b.totalRunes-1 {
+			for len(b.planned) != 0 && b.planned[0] <= option.breakAtRune {
+				b.planned = b.planned[1:]
+			}
+			if len(b.planned) == 0 || b.planned[0] != option.breakAtRune+1 {
+				continue
+			}
+			option.required = true
 		}
 		return option, true
 	}
-	// Unicode rules impose to always break at the end
-	return breakOption{}, false
+}
+
+// nextWordOption returns the next uax#14 boundary, preceded by the hyphenation
+// points of the segment, if any.
+func (b *breaker) nextWordOption() (breakOption, bool) {
+	if len(b.pendingWordBreaks) != 0 {
+		option := b.pendingWordBreaks[0]
+		b.pendingWordBreaks = b.pendingWordBreaks[1:]
+		return option, true
+	}
+	if !b.wordSegmenter.Next() {
+		return breakOption{}, false
+	}
+	currentSegment := b.wordSegmenter.Line()
+	// Note : we dont use penalties for Mandatory Breaks so far,
+	// we could add it with currentSegment.IsMandatoryBreak
+	breakAtRune := currentSegment.Offset + len(currentSegment.Text) - 1
+	option := breakOption{
+		breakAtRune: breakAtRune,
+		// Don't treat the EOF line break as special. We implicitly always break after
+		// the end of text input anyway.
+		required: currentSegment.IsMandatoryBreak && breakAtRune != b.totalRunes-1,
+	}
+	// the words containing soft hyphens are not hyphenated automatically
+	afterSoftHyphen := b.afterSoftHyphen
+	b.afterSoftHyphen = currentSegment.Text[len(currentSegment.Text)-1] == softHyphen
+	b.pendingWordBreaks = b.pendingWordBreaks[:0]
+	if b.afterSoftHyphen {
+		option.hyphen = breakAtRune != b.totalRunes-1
+	} else if !afterSoftHyphen {
+		b.appendHyphenationPoints(currentSegment.Offset, currentSegment.Text)
+	}
+	if b.breakSpaces {
+		b.pendingWordBreaks = appendSpaceBreaks(b.pendingWordBreaks, currentSegment.Offset, currentSegment.Text)
+	}
+	if len(b.pendingWordBreaks) != 0 {
+		b.pendingWordBreaks = append(b.pendingWordBreaks, option)
+		return b.nextWordOption()
+	}
+	return option, true
 }
 
 // nextGraphemeRaw returns a naive break candidate on a uax#29 boundary which may be invalid.
@@ -431,10 +586,18 @@ type WrapConfig struct {
 	// TruncateAfterLines is the number of lines of text to allow before truncating
 	// the text. A value of zero means no limit.
 	TruncateAfterLines int
-	// Truncator, if provided, will be inserted at the end of a truncated line. This
+	// Truncator, if provided, will be inserted at the end of a truncated line (or at
+	// the position selected by TruncatePosition). This
 	// feature is only active if TruncateAfterLines is nonzero. See the documentation
 	// for [LineWrapper.WrapNextLine] for details about how this works.
 	Truncator Output
+	// TruncatePosition selects where the text of the last line is removed when
+	// truncating. For [TruncateStart] and [TruncateMiddle], the last line holds the rest of the
+	// paragraph, from which whole graphemes (not splitting glyph clusters) are removed, including
+	// any mandatory line break. The truncator is then inserted, in logical order, at the position of the
+	// removed runes.
+	// This field has no effect if TruncateAfterLines is zero.
+	TruncatePosition TruncatePosition
 	// TextContinues indicates that the paragraph wrapped by this config is not the
 	// final paragraph in the text. This alters text truncation when filling the
 	// final line permitted by TruncateAfterLines. If the text of this paragraph
@@ -453,6 +616,61 @@ type WrapConfig struct {
 	// usually want this feature enabled, but for text editors it is frequently
 	// desirable to allow trailing whitespace to occupy space itself.
 	DisableTrailingWhitespaceTrim bool
+	// Justification, if enabled, expands the wrapped lines to fill the
+	// maximum width. See [Justification] for details.
+	Justification Justification
+	// TotalFit, if enabled, chooses the line breaks optimizing the whole paragraph,
+	// instead of filling each line. See [TotalFit] for details.
+	TotalFit TotalFit
+	// TabStops, if enabled, adjusts the advance of tabs so that the text is
+	// aligned on tab stops. See [TabStops] for details.
+	TabStops TabStops
+	// HangingPunctuation selects the punctuation placed outside of the line bounds.
+	// See [HangingPunctuation] for details.
+	HangingPunctuation HangingPunctuation
+	// OpticalMargins, if true, lets the glyphs at the edges of the lines extend beyond
+	// the line bounds, as specified by the font (using the AAT 'opbd' table, or the
+	// 'lfbd' and 'rtbd' GPOS features), so that the margins look straight.
+	// As for [HangingPunctuation], the glyph positions are not modified, and
+	// the overhangs are reported in [WrappedLine].
+	OpticalMargins bool
+	// SpacingTrim selects the fullwidth punctuation trimmed at the start and end
+	// of the lines (the other trimmings are performed by [TextSpacing.Apply]).
+	// See [SpacingTrim] and [TextSpacing.Configure] for details.
+	SpacingTrim SpacingTrim
+	// DisableSoftBreaks restricts the line breaks to the mandatory ones (like
+	// after '\n'). It is usually combined with the [Never] break policy, so
+	// that the lines are only broken at mandatory breaks.
+	DisableSoftBreaks bool
+	// BreakAfterSpaces adds a break opportunity after every space and tab,
+	// including between two spaces, as CSS 'white-space: break-spaces' does.
+	// The trailing spaces of the lines are then included in the line width
+	// when choosing the breaks.
+	BreakAfterSpaces bool
+	// MaxHeight, if positive, limits the height of the wrapped paragraph : the paragraph
+	// is truncated (as with TruncateAfterLines) after the last line which fits in MaxHeight,
+	// one line being always kept. The height of the paragraph is the sum of the thickness
+	// of its lines, computed by [Line.Metrics] using LineHeight.
+	// This field is only supported by [LineWrapper.WrapParagraph] and [LineWrapper.WrapParagraphF].
+	MaxHeight fixed.Int26_6
+	// LineHeight is used to compute the height of the lines when MaxHeight is set.
+	LineHeight LineHeight
+	// Hyphenator, if not nil, provides additional break opportunities inside words.
+	// When such a break is used, an hyphen is inserted at the end of the line, as
+	// an additional run : see [Hyphenator] for details.
+	Hyphenator Hyphenator
+	// HyphenatorFor, if not nil, is used instead of Hyphenator to select the [Hyphenator]
+	// of each run, according to its [Output.Language]. It may return nil
+	// for the languages which should not be hyphenated.
+	// For instance, with [github.com/go-text/typesetting/hyphenation.Dictionaries] :
+	//
+	//	config.HyphenatorFor = func(lang language.Language) shaping.Hyphenator {
+	//		if h := dicts.Lookup(lang); h != nil {
+	//			return h
+	//		}
+	//		return nil
+	//	}
+	HyphenatorFor func(lang language.Language) Hyphenator
 }
 
 // LineBreakPolicy specifies when considering a line break within a "word" or UAX#14
@@ -583,6 +801,56 @@ func (r *shapedRunSlice) Restore() {
 	r.idx = r.savedIdx
 }
 
+// Reshaper is an optional interface which may be implemented by a [RunIterator]
+// to let the [LineWrapper] re-shape the edges of runs cut at a position which is
+// not safe to break (see [GlyphUnsafeToBreak]), such as inside an Arabic word.
+// The glyphs between safe-to-break positions are always reused : only the start of the
+// lines, and the end of the line candidates which fit, are re-shaped, so that the lines
+// are measured with their final glyphs.
+//
+// Without it, runs are always cut without re-shaping.
+//
+// It is also used to apply the 'jalt' feature when justifying Arabic text with
+// [Justification.Kashida].
+type Reshaper interface {
+	// Reshape returns the shaped output for [runes] (expressed as indices into the paragraph),
+	// which are a subset of the run with the given [index].
+	// [features] are added to the font features used to shape the run.
+	Reshape(index int, runes Range, features ...FontFeature) Output
+}
+
+// reshapingRunSlice is a [shapedRunSlice] also implementing [Reshaper]
+type reshapingRunSlice struct {
+	shapedRunSlice
+	shaper Shaper
+	inputs []Input
+}
+
+var _ Reshaper = (*reshapingRunSlice)(nil)
+
+// NewReshapingIterator returns a [RunIterator] backed by an already-shaped slice of [Output]s,
+// which also implements [Reshaper], using [shaper] and the [inputs] used to produce [outs].
+//
+// Note that any post-processing applied to [outs] (like letter spacing)
+// will be missing in the re-shaped parts of the runs.
+func NewReshapingIterator(shaper Shaper, inputs []Input, outs []Output) RunIterator {
+	return &reshapingRunSlice{
+		shapedRunSlice: shapedRunSlice{runs: outs},
+		shaper:         shaper,
+		inputs:         inputs,
+	}
+}
+
+// Reshape implements [Reshaper.Reshape].
+func (r *reshapingRunSlice) Reshape(index int, runes Range, features ...FontFeature) Output {
+	input := r.inputs[index]
+	input.RunStart, input.RunEnd = runes.Offset, runes.Offset+runes.Count
+	if len(features) != 0 {
+		input.FontFeatures = append(append([]FontFeature(nil), input.FontFeatures...), features...)
+	}
+	return r.shaper.Shape(input)
+}
+
 // wrapBuffer provides reusable buffers for line wrapping. When using a
 // wrapBuffer, returned line wrapping results will use memory stored within
 // the buffer. This means that the same buffer cannot be reused for another
@@ -749,6 +1017,43 @@ type LineWrapper struct {
 
 	// mapper tracks rune->glyphCluster mappings.
 	mapper runMapper
+	// justifier is used to justify the lines, if requested.
+	justifier justifier
+	// hanger computes the line overhangs
+	hanger hanger
+	// trimmer trims the punctuation at the edges of the lines
+	trimmer trimmer
+	// tabber is used to expand the tabs, if requested.
+	tabber tabber
+	// hasTabs is true if the tabs of the paragraph are expanded
+	hasTabs bool
+	// hyphens caches the hyphens inserted at hyphenation points,
+	// for the current paragraph
+	hyphens map[hyphenKey]Output
+	// shaper shapes the hyphens which are not shaped by the run iterator,
+	// and the tatweels used for justification.
+	// Its font cache is kept across paragraphs.
+	shaper HarfbuzzShaper
+	// candidateHyphen is the hyphen for the last processed break option,
+	// if it is an hyphenation point.
+	candidateHyphen Output
+	// bestHyphenated is true if the best line candidate ends with an hyphen
+	bestHyphenated bool
+	// startRun is the first run of the line, cut (and re-shaped) from
+	// the run containing the line start, if startRunValid is true
+	startRun      Output
+	startRunValid bool
+	// candidateEdge is the run the last processed candidate run is cut from,
+	// if it has not been re-shaped yet
+	candidateEdge lineEdge
+	// bestEdge is the run the last run of the best line candidate is cut from, if any
+	bestEdge lineEdge
+	// sources are the indices of the runs read from [glyphRuns]
+	sources []runSource
+	// elider truncates the start or the middle of the last line
+	elider elider
+	// elided is the range of runes removed by [elider] from the current line
+	elided Range
 	// glyphRuns holds the runs of shaped text being wrapped.
 	glyphRuns RunIterator
 	// lineStartRune is the rune index of the first rune on the next line to
@@ -765,7 +1070,22 @@ func (l *LineWrapper) Prepare(config WrapConfig, paragraph []rune, runs RunItera
 	l.config = config
 	l.truncating = l.config.TruncateAfterLines > 0
 	l.breaker = newBreaker(&l.seg, paragraph)
+	l.breaker.configure(config, runs)
+	for key := range l.hyphens {
+		delete(l.hyphens, key)
+	}
+	if config.Justification.Enabled {
+		l.justifier.prepare(config.Justification, &l.seg, paragraph)
+		if config.Justification.Kashida {
+			l.justifier.kashida.prepare(&l.shaper, runs, l.sourceIndex)
+		}
+	}
+	l.hanger.prepare(config, paragraph)
+	l.trimmer.prepare(config.SpacingTrim, paragraph)
+	l.elider.text = paragraph
+	l.hasTabs = config.TabStops.Enabled && l.tabber.prepare(config.TabStops, config.Direction, paragraph)
 	l.glyphRuns = runs
+	l.sources = l.sources[:0]
 	l.lineStartRune = 0
 	l.more = true
 	l.mapper.valid = false
@@ -776,7 +1096,7 @@ func (l *LineWrapper) Prepare(config WrapConfig, paragraph []rune, runs RunItera
 // It is equivalent to iteratively invoking WrapLine with a constant maxWidth.
 // If the config has a non-zero TruncateAfterLines, WrapParagraph will return at most
 // that many lines. The truncated return value is the count of runes truncated from
-// the end of the text. The returned lines are only valid until the next call to
+// the end of the text (or from the position selected by [WrapConfig.TruncatePosition]). The returned lines are only valid until the next call to
 // [*LineWrapper.WrapParagraph] or [*LineWrapper.Prepare].
 //
 // See [(*LineWrapper).WrapNextLine] for a description of how [WrapConfig]'s truncation
@@ -796,7 +1116,8 @@ func (l *LineWrapper) WrapParagraph(config WrapConfig, maxWidth int, paragraph [
 func (l *LineWrapper) WrapParagraphF(config WrapConfig, maxWidth fixed.Int26_6, paragraph []rune, runs RunIterator) (_ []Line, truncated int) {
 	l.scratch.reset()
 	// Check whether we can skip line wrapping altogether for the simple single-run-that-fits case.
-	if !(config.TextContinues && config.TruncateAfterLines == 1) {
+	expandTabs := config.TabStops.Enabled && hasTab(paragraph)
+	if !(config.TextContinues && config.TruncateAfterLines == 1) && !expandTabs {
 		runs.Save()
 		// We can only skip wrapping if the text doesn't contain any forced line
 		// breaks that need to be evaluated by the real algorithm, so we need to
@@ -825,6 +1146,16 @@ func (l *LineWrapper) WrapParagraphF(config WrapConfig, maxWidth fixed.Int26_6,
 		runs.Restore()
 	}
 
+	if config.MaxHeight > 0 {
+		return l.wrapParagraphHeight(config, maxWidth, paragraph, runs)
+	}
+
+	// the tab advances depend on the line start, which is not
+	// supported by the total-fit algorithm
+	if config.TotalFit.Enabled && !expandTabs {
+		return l.wrapParagraphTotalFit(config, maxWidth, paragraph, runs)
+	}
+
 	l.Prepare(config, paragraph, runs)
 	var (
 		line WrappedLine
@@ -844,6 +1175,7 @@ func (l *LineWrapper) WrapParagraphF(config WrapConfig, maxWidth fixed.Int26_6,
 func (l *LineWrapper) fillUntil(runs RunIterator, option breakOption) {
 	currRunIndex, run, more := runs.Peek()
 	for more && option.breakAtRune >= run.Runes.Count+run.Runes.Offset {
+		l.recordRun(currRunIndex, run)
 		if l.lineStartRune >= run.Runes.Offset+run.Runes.Count {
 			// Consume the run we peeked (which we know is valid)
 			_, _, _ = runs.Next()
@@ -854,8 +1186,11 @@ func (l *LineWrapper) fillUntil(runs RunIterator, option breakOption) {
 			// If part of this run has already been used on a previous line, trim
 			// the runes corresponding to those glyphs off.
 			l.mapper.mapRun(currRunIndex, run)
-			isFirstInLine := l.scratch.candidateLen() == 0
-			run = cutRun(run, l.mapper.mapping, l.lineStartRune, run.Runes.Count+run.Runes.Offset, isFirstInLine)
+			if l.scratch.candidateLen() == 0 {
+				run = l.lineStart(currRunIndex, run)
+			} else {
+				run = cutRun(run, l.mapper.mapping, l.lineStartRune, run.Runes.Count+run.Runes.Offset, false)
+			}
 		}
 		// While the run being processed doesn't contain the current line breaking
 		// candidate, just append it to the candidate line.
@@ -867,6 +1202,81 @@ func (l *LineWrapper) fillUntil(runs RunIterator, option breakOption) {
 	}
 }
 
+// runSource is the index of a run in the run iterator,
+// with the runes it covers.
+type runSource struct {
+	index int
+	runes Range
+}
+
+// recordRun records the index of [run], so that the runs of the lines
+// may be re-shaped (see [LineWrapper.sourceIndex]).
+func (l *LineWrapper) recordRun(index int, run Output) {
+	if n := len(l.sources); n != 0 && l.sources[n-1].index >= index {
+		return
+	}
+	l.sources = append(l.sources, runSource{index: index, runes: run.Runes})
+}
+
+// sourceIndex returns the index in the run iterator of the run [run] is cut from.
+func (l *LineWrapper) sourceIndex(run Output) (int, bool) {
+	for _, src := range l.sources {
+		if src.runes.Offset <= run.Runes.Offset && run.Runes.Offset+run.Runes.Count <= src.runes.Offset+src.runes.Count {
+			return src.index, true
+		}
+	}
+	return 0, false
+}
+
+// lineEdge records the run from which the first or the last run of a line
+// candidate has been cut, so that its edges may be re-shaped once the line is chosen.
+type lineEdge struct {
+	index int
+	run   Output
+	valid bool
+}
+
+// reshapeCut re-shapes the edges of [cut] which are not safe to break, if the
+// run iterator implements [Reshaper] and [cut] is a sub-run of [edge].
+func (l *LineWrapper) reshapeCut(edge lineEdge, cut Output) Output {
+	reshaper, ok := l.glyphRuns.(Reshaper)
+	if !ok || !edge.valid {
+		return cut
+	}
+	if cut.Runes.Offset < edge.run.Runes.Offset || cut.Runes.Offset+cut.Runes.Count > edge.run.Runes.Offset+edge.run.Runes.Count {
+		return cut
+	}
+	l.mapper.mapRun(edge.index, edge.run)
+	return reshapeEdges(reshaper, edge.index, edge.run, l.mapper.mapping, cut)
+}
+
+// lineStart returns the first run of the line, cut from [run] (with index [index]),
+// which contains the line start, and whose start is re-shaped if needed.
+// Since every line candidate starts with it, it is only computed once per line.
+func (l *LineWrapper) lineStart(index int, run Output) Output {
+	if !l.startRunValid {
+		l.mapper.mapRun(index, run)
+		cut := cutRun(run, l.mapper.mapping, l.lineStartRune, run.Runes.Count+run.Runes.Offset, true)
+		l.startRun = l.reshapeCut(lineEdge{index: index, run: run, valid: true}, cut)
+		l.startRunValid = true
+	}
+	return l.startRun
+}
+
+// reshapeLineEdges re-shapes the end of the chosen [line], if its last run
+// has been measured using the glyphs of the run it is cut from.
+// This only happens for lines which do not fit anyway.
+func (l *LineWrapper) reshapeLineEdges(line Line) {
+	last := len(line) - 1
+	if l.bestHyphenated {
+		last--
+	}
+	if last < 0 || !l.bestEdge.valid {
+		return
+	}
+	line[last] = l.reshapeCut(l.bestEdge, line[last])
+}
+
 // lineConfig tracks settings for line wrapping a single line of text.
 type lineConfig struct {
 	// truncating indicates whether this line is being truncated (if sufficiently long).
@@ -882,8 +1292,8 @@ type lineConfig struct {
 type WrappedLine struct {
 	// Line is the content of the line, as a slice of shaped runs
 	Line Line
-	// Truncated is the count of runes truncated from the end of the line,
-	// if this line was truncated.
+	// Truncated is the count of runes truncated from the end of the line (or from
+	// the position selected by [WrapConfig.TruncatePosition]), if this line was truncated.
 	Truncated int
 	// NextLine is the indice (in the input text slice) of the begining
 	// of the next line. It will equal len(text) if all the text
@@ -895,6 +1305,19 @@ type WrappedLine struct {
 	// It is zero if [DisableTrailingWhitespaceTrim] is set to true,
 	// or if there is no whitespace at the end of the line.
 	TrimmedTrailingWhitespace fixed.Int26_6
+
+	// StartOverhang and EndOverhang are the distances by which the line extends
+	// beyond its start and end edges (as defined by the paragraph direction), because of
+	// hanging punctuation or optical margins (see [WrapConfig.HangingPunctuation] and
+	// [WrapConfig.OpticalMargins]). They are usually positive.
+	// For instance, a left-to-right line aligned on the left edge should be drawn
+	// with an horizontal offset of -StartOverhang.
+	StartOverhang, EndOverhang fixed.Int26_6
+
+	// Elided is the range of runes removed by truncation, if this line was truncated.
+	// It is the end of the paragraph for [TruncateEnd] (with a Count of [Truncated]),
+	// and a part of the line for [TruncateStart] and [TruncateMiddle].
+	Elided Range
 }
 
 // swapVisualOrder inverts the visual index of runs in [subline], by swapping pairs of visual indices across the midpoint
@@ -930,10 +1353,16 @@ func computeBidiOrdering(dir di.Direction, finalLine Line) {
 	}
 }
 
-func (l *LineWrapper) postProcessLine(finalLine Line, done bool) (WrappedLine, bool) {
+func (l *LineWrapper) postProcessLine(finalLine Line, maxWidth fixed.Int26_6, done bool) (WrappedLine, bool) {
 	var trimmed fixed.Int26_6
 	if len(finalLine) > 0 {
 		computeBidiOrdering(l.config.Direction, finalLine)
+		if l.hasTabs {
+			l.tabber.expand(finalLine)
+		}
+		if l.config.SpacingTrim != SpacingTrimSpaceAll {
+			l.trimmer.trimEdges(finalLine)
+		}
 		if !l.config.DisableTrailingWhitespaceTrim {
 			// Here we find the last visual run in the line.
 			goalIdx := len(finalLine) - 1
@@ -985,30 +1414,55 @@ func (l *LineWrapper) postProcessLine(finalLine Line, done bool) (WrappedLine, b
 
 	// Implement truncation if needed.
 	truncated := 0
+	truncatorInserted := false
+	var elided Range
 	if l.truncating {
 		l.config.TruncateAfterLines--
 		insertTruncator := false
 		if l.config.TruncateAfterLines == 0 {
 			done = true
-			truncated = l.breaker.totalRunes - l.lineStartRune
-			insertTruncator = truncated > 0 || l.config.TextContinues
+			if l.elided.Count != 0 {
+				// the truncator has already been inserted
+				truncated = l.elided.Count
+				elided = l.elided
+				truncatorInserted = true
+			} else {
+				truncated = l.breaker.totalRunes - l.lineStartRune
+				insertTruncator = truncated > 0 || l.config.TextContinues
+				elided = Range{Offset: l.lineStartRune, Count: truncated}
+			}
 		}
 		if insertTruncator {
+			if l.bestHyphenated {
+				// the hyphen is replaced by the truncator
+				finalLine = finalLine[:len(finalLine)-1]
+			}
 			truncator := l.config.Truncator
 			truncator.Runes.Count = truncated
 			truncator.Runes.Offset = l.lineStartRune
 			finalLine = append(finalLine, truncator)
 			// We've just modified the line, we need to recompute the bidi ordering.
 			computeBidiOrdering(l.config.Direction, finalLine)
+			truncatorInserted = true
 		}
 	}
 
+	var startOverhang, endOverhang fixed.Int26_6
+	if l.config.HangingPunctuation != 0 || l.config.OpticalMargins {
+		startOverhang, endOverhang = l.hanger.overhangs(finalLine, maxWidth, truncatorInserted)
+	}
+
+	// The last line of the paragraph (including the truncated one) is not justified.
+	if l.config.Justification.Enabled && !done && l.justifier.shouldJustify(finalLine) {
+		l.justifier.justify(finalLine, maxWidth+startOverhang+endOverhang)
+	}
+
 	// Mark the paragraph as complete if needed.
 	if done {
 		l.more = false
 	}
 
-	return WrappedLine{finalLine, truncated, l.lineStartRune, trimmed}, done
+	return WrappedLine{finalLine, truncated, l.lineStartRune, trimmed, startOverhang, endOverhang, elided}, done
 }
 
 // WrapNextLine wraps the shaped glyphs of a paragraph to a particular max width.
@@ -1027,6 +1481,8 @@ func (l *LineWrapper) postProcessLine(finalLine Line, done bool) (WrappedLine, b
 // [Output]s before the final [Output] will represent the input runes that are still
 // visible before truncation, and the final [Output] will be a copy of the Truncator
 // with its Runes.Count set to the quantity of runes truncated during line wrapping.
+// For the [TruncateStart] and [TruncateMiddle] positions, the Truncator is instead
+// inserted at the position of the removed runes, which are reported in [WrappedLine].Elided.
 //
 // See also [WrapNextLineF] which supports a decimal [maxWidth].
 func (l *LineWrapper) WrapNextLine(maxWidth int) (out WrappedLine, done bool) {
@@ -1039,13 +1495,19 @@ func (l *LineWrapper) WrapNextLine(maxWidth int) (out WrappedLine, done bool) {
 
 // WrapNextLineF is the same as [WrapNextLine], but accepts a non integer [maxWidth].
 func (l *LineWrapper) WrapNextLineF(maxWidth fixed.Int26_6) (out WrappedLine, done bool) {
+	return l.nextLine(maxWidth, maxWidth)
+}
+
+// nextLine implements [WrapNextLineF], using [justifiedWidth] instead
+// of [maxWidth] for justification.
+func (l *LineWrapper) nextLine(maxWidth, justifiedWidth fixed.Int26_6) (out WrappedLine, done bool) {
 	// If we've already finished the paragraph, don't do any more work.
 	if !l.more {
 		return WrappedLine{NextLine: l.lineStartRune}, true
 	}
 
 	defer func() {
-		out, done = l.postProcessLine(out.Line, done)
+		out, done = l.postProcessLine(out.Line, justifiedWidth, done)
 	}()
 
 	// If the iterator is empty, return early.
@@ -1054,14 +1516,21 @@ func (l *LineWrapper) WrapNextLineF(maxWidth fixed.Int26_6) (out WrappedLine, do
 		return WrappedLine{}, true
 	}
 	l.scratch.startLine()
+	l.bestHyphenated = false
+	l.startRunValid, l.bestEdge = false, lineEdge{}
+	l.elided = Range{}
 
 	config := lineConfig{
 		truncating:        l.config.TruncateAfterLines == 1,
 		maxWidth:          maxWidth,
 		truncatedMaxWidth: maxWidth - l.config.Truncator.Advance,
 	}
+	if config.truncating && l.config.TruncatePosition != TruncateEnd {
+		return WrappedLine{Line: l.elidedLine(maxWidth)}, true
+	}
 	done = l.wrapNextLine(config)
 	finalLine := l.scratch.finalizeBest()
+	l.reshapeLineEdges(finalLine)
 	return WrappedLine{Line: finalLine}, done
 }
 
@@ -1094,19 +1563,19 @@ func (l *LineWrapper) wrapNextLine(config lineConfig) (done bool) {
 			l.restore()
 			continue
 		case fits:
-			l.scratch.markCandidateBest(candidateRun)
+			l.markCandidateBest(option, candidateRun)
 			if option.required {
 				return false
 			}
 			continue
 		case endLine:
 			// Found a valid line ending the text, append the candidateRun and use it.
-			l.scratch.markCandidateBest(candidateRun)
+			l.markCandidateBest(option, candidateRun)
 			return true
 		case truncated:
 			// The candidateRun does not fit.
 			if !l.scratch.hasBest() {
-				l.scratch.markCandidateBest()
+				l.markBareCandidateBest()
 			}
 			if l.config.BreakPolicy == Never {
 				return true
@@ -1126,7 +1595,7 @@ func (l *LineWrapper) wrapNextLine(config lineConfig) (done bool) {
 				if config.truncating {
 					return true
 				}
-				l.scratch.markCandidateBest(candidateRun)
+				l.markCandidateBest(option, candidateRun)
 				return false
 			}
 			// Fall through to try grapheme breaking.
@@ -1149,15 +1618,15 @@ func (l *LineWrapper) wrapNextLine(config lineConfig) (done bool) {
 				continue
 			case fits:
 				// If we found at least one viable line candidate, we aren't using the word break option.
-				l.scratch.markCandidateBest(candidateRun)
+				l.markCandidateBest(option, candidateRun)
 				l.breaker.markWordOptionUnused()
 				continue
 			case endLine:
-				l.scratch.markCandidateBest(candidateRun)
+				l.markCandidateBest(option, candidateRun)
 				return true
 			case truncated:
 				if !l.scratch.hasBest() {
-					l.scratch.markCandidateBest()
+					l.markBareCandidateBest()
 				}
 				return true
 			case newLineBeforeBreak:
@@ -1174,7 +1643,7 @@ func (l *LineWrapper) wrapNextLine(config lineConfig) (done bool) {
 				}
 				// If no graphemes fit, we should still use one so that the line contains something. Maybe
 				// the next grapheme will fit on the next line.
-				l.scratch.markCandidateBest(candidateRun)
+				l.markCandidateBest(option, candidateRun)
 				l.breaker.markWordOptionUnused()
 				return false
 			}
@@ -1219,6 +1688,7 @@ func (l *LineWrapper) processBreakOption(option breakOption, config lineConfig)
 	l.fillUntil(l.glyphRuns, option)
 
 	currRunIndex, run, _ := l.glyphRuns.Peek()
+	l.recordRun(currRunIndex, run)
 	l.mapper.mapRun(currRunIndex, run)
 	if !option.isValid(l.mapper.mapping, run) {
 		// Reject invalid line break candidate and acquire a new one.
@@ -1226,7 +1696,37 @@ func (l *LineWrapper) processBreakOption(option breakOption, config lineConfig)
 	}
 	isFirstInLine := l.scratch.candidateLen() == 0
 	candidateRun := cutRun(run, l.mapper.mapping, l.lineStartRune, option.breakAtRune, isFirstInLine)
+	l.candidateEdge = lineEdge{index: currRunIndex, run: run, valid: true}
 	candidateLineWidth := candidateRun.advanceSpaceAware(l.config.Direction) + l.scratch.candidateAdvance()
+	if l.config.BreakAfterSpaces {
+		// trailing spaces do not hang
+		candidateLineWidth = candidateRun.Advance + l.scratch.candidateAdvance()
+	}
+	if l.hasTabs {
+		candidateLineWidth += l.tabber.candidateExtra(l.scratch.alt, candidateRun)
+	}
+	if l.config.SpacingTrim != SpacingTrimSpaceAll {
+		candidateLineWidth -= l.trimmer.candidateTrim(l.lineStartRune, option.breakAtRune, l.scratch.alt, candidateRun)
+	}
+	if l.config.HangingPunctuation != 0 {
+		candidateLineWidth -= l.hanger.candidateHang(l.lineStartRune, option.breakAtRune, l.scratch.alt, candidateRun, config.truncating)
+	}
+	if option.hyphen {
+		l.candidateHyphen = l.hyphenFor(currRunIndex, candidateRun)
+		// when truncating, the hyphen is replaced by the truncator
+		if !config.truncating {
+			candidateLineWidth += l.candidateHyphen.Advance
+		}
+	}
+	if candidateLineWidth <= config.maxWidth {
+		// The edges of the candidate which are not safe to break are re-shaped,
+		// so that the chosen line is measured with its final glyphs and does not overflow.
+		// Safe edges, such as the usual breaks after spaces, are reused without re-shaping.
+		reshaped := l.reshapeCut(l.candidateEdge, candidateRun)
+		candidateLineWidth += reshaped.Advance - candidateRun.Advance
+		candidateRun = reshaped
+		l.candidateEdge.valid = false
+	}
 	if candidateLineWidth > config.maxWidth {
 		// The run doesn't fit on the line.
 		if !l.scratch.hasBest() {
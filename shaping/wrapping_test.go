@@ -3623,3 +3623,120 @@ func TestWrapping_oneLine_overflow_bug(t *testing.T) {
 	_, done := l.WrapNextLine(maxWidth)
 	tu.Assert(t, done)
 }
+
+func TestLineWrapperReshape(t *testing.T) {
+	type glyphKey struct {
+		id      font.GID
+		advance fixed.Int26_6
+	}
+	glyphKeys := func(glyphs []Glyph) []glyphKey {
+		var out []glyphKey
+		for _, g := range glyphs {
+			out = append(out, glyphKey{g.GlyphID, g.Advance})
+		}
+		return out
+	}
+	var shaper HarfbuzzShaper
+	// matchesShaping returns true if all the lines are
+	// identical to the result of shaping them separately.
+	matchesShaping := func(input Input, lines []Line) bool {
+		for _, line := range lines {
+			tu.Assert(t, len(line) == 1)
+			run := line[0]
+			lineInput := input
+			lineInput.RunStart, lineInput.RunEnd = run.Runes.Offset, run.Runes.Offset+run.Runes.Count
+			expected := shaper.Shape(lineInput)
+			if !reflect.DeepEqual(glyphKeys(run.Glyphs), glyphKeys(expected.Glyphs)) || run.Advance != expected.Advance {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, input := range []Input{
+		{
+			Text: []rune("مرحبا بالعالم"), Direction: di.DirectionRTL,
+			Face: benchArFace, Script: language.Arabic, Language: language.NewLanguage("ar"),
+		},
+		{
+			Text: []rune("AVAVAV AVAVAV"), Direction: di.DirectionLTR,
+			Face: loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf"), Script: language.Latin, Language: language.NewLanguage("en"),
+		},
+	} {
+		input.RunEnd, input.Size = len(input.Text), fixed.I(16)
+		out := shaper.Shape(input)
+
+		hasUnsafe := false
+		for _, g := range out.Glyphs {
+			hasUnsafe = hasUnsafe || g.Flags&GlyphUnsafeToBreak != 0
+		}
+		tu.Assert(t, hasUnsafe)
+
+		for _, maxWidth := range []int{20, 30, 40} {
+			var w LineWrapper
+			config := WrapConfig{BreakPolicy: Always, DisableTrailingWhitespaceTrim: true}
+			lines, _ := w.WrapParagraph(config, maxWidth, input.Text, NewReshapingIterator(&shaper, []Input{input}, []Output{out.copy()}))
+			tu.Assert(t, len(lines) > 1)
+			tu.Assert(t, matchesShaping(input, lines))
+			for _, line := range lines { // trailing spaces may hang
+				tu.Assert(t, line[0].advanceSpaceAware(input.Direction) <= fixed.I(maxWidth))
+			}
+		}
+	}
+
+	// without re-shaping, the kerning is wrong at the end of the lines
+	input := Input{
+		Text: []rune("AVAVAV AVAVAV"), RunEnd: 13, Direction: di.DirectionLTR, Size: fixed.I(16),
+		Face: loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf"), Script: language.Latin, Language: language.NewLanguage("en"),
+	}
+	var w LineWrapper
+	lines, _ := w.WrapParagraph(WrapConfig{BreakPolicy: Always}, 20, input.Text, NewSliceIterator([]Output{shaper.Shape(input)}))
+	tu.Assert(t, !matchesShaping(input, lines))
+
+	// glyphs are reused at safe-to-break positions
+	text := []rune("hello world")
+	input = Input{
+		Text: text, RunStart: 0, RunEnd: len(text), Direction: di.DirectionLTR,
+		Face: benchEnFace, Size: fixed.I(16), Script: language.Latin, Language: language.NewLanguage("en"),
+	}
+	out := shaper.Shape(input)
+	lines, _ = w.WrapParagraph(WrapConfig{}, out.Advance.Ceil()*2/3, text, NewReshapingIterator(&shaper, []Input{input}, []Output{out}))
+	tu.Assert(t, len(lines) == 2)
+	tu.Assert(t, &lines[0][0].Glyphs[0] == &out.Glyphs[0])
+	tu.Assert(t, &lines[1][0].Glyphs[0] == &out.Glyphs[6])
+}
+
+// countingReshaper counts the calls to [Reshaper.Reshape]
+type countingReshaper struct {
+	RunIterator
+	calls int
+}
+
+func (c *countingReshaper) Reshape(index int, runes Range, features ...FontFeature) Output {
+	c.calls++
+	return c.RunIterator.(Reshaper).Reshape(index, runes, features...)
+}
+
+func TestLineWrapperReshapeFit(t *testing.T) {
+	var shaper HarfbuzzShaper
+	text := []rune("مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم")
+	input := Input{
+		Text: text, RunEnd: len(text), Direction: di.DirectionRTL, Size: fixed.I(16),
+		Face: benchArFace, Script: language.Arabic, Language: language.NewLanguage("ar"),
+	}
+	out := shaper.Shape(input)
+
+	// only the line starts and the fitting candidates whose end is not
+	// safe to break are re-shaped, so that the lines do not overflow
+	for _, policy := range []LineBreakPolicy{WhenNecessary, Always} {
+		runs := &countingReshaper{RunIterator: NewReshapingIterator(&shaper, []Input{input}, []Output{out.copy()})}
+		var w LineWrapper
+		lines, _ := w.WrapParagraph(WrapConfig{BreakPolicy: policy, DisableTrailingWhitespaceTrim: true}, 30, text, runs)
+		tu.Assert(t, len(lines) > 1)
+		tu.Assert(t, runs.calls > 0)
+		tu.Assert(t, runs.calls <= 4*len(lines))
+		for _, line := range lines { // trailing spaces may hang
+			tu.Assert(t, line[0].advanceSpaceAware(input.Direction) <= fixed.I(30))
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package stroke
+
+import "math"
+
+// this file implements the geometric primitives used by the stroker
+
+type vec struct{ x, y float32 }
+
+func (v vec) add(u vec) vec             { return vec{v.x + u.x, v.y + u.y} }
+func (v vec) sub(u vec) vec             { return vec{v.x - u.x, v.y - u.y} }
+func (v vec) scale(s float32) vec       { return vec{v.x * s, v.y * s} }
+func (v vec) dot(u vec) float32         { return v.x*u.x + v.y*u.y }
+func (v vec) cross(u vec) float32       { return v.x*u.y - v.y*u.x }
+func (v vec) length() float32           { return float32(math.Hypot(float64(v.x), float64(v.y))) }
+func (v vec) lerp(u vec, t float32) vec { return v.add(u.sub(v).scale(t)) }
+
+// normalized returns the unit vector, or the zero vector
+func (v vec) normalized() vec {
+	l := v.length()
+	if l == 0 {
+		return vec{}
+	}
+	return vec{v.x / l, v.y / l}
+}
+
+// rightNormal returns the unit normal pointing to the right
+// of the direction [v], with the Y axis pointing up
+func (v vec) rightNormal() vec {
+	n := v.normalized()
+	return vec{n.y, -n.x}
+}
+
+// rotate applies a counter-clockwise rotation of [angle] radians
+func (v vec) rotate(angle float64) vec {
+	sin, cos := math.Sincos(angle)
+	s, c := float32(sin), float32(cos)
+	return vec{v.x*c - v.y*s, v.x*s + v.y*c}
+}
+
+// isSmall returns true for vectors considered as null,
+// expressed in font units
+func (v vec) isSmall() bool { return v.x*v.x+v.y*v.y < 1e-6 }
+
+// piece is either a line, or a quadratic Bézier curve.
+type piece struct {
+	start, ctrl, end vec // ctrl is only used by quadratic curves
+	isQuad           bool
+}
+
+func line(start, end vec) piece       { return piece{start: start, end: end} }
+func quad(start, ctrl, end vec) piece { return piece{start: start, ctrl: ctrl, end: end, isQuad: true} }
+func (p piece) reversed() piece {
+	return piece{start: p.end, ctrl: p.ctrl, end: p.start, isQuad: p.isQuad}
+}
+func (p piece) isDegenerate() bool {
+	return p.end.sub(p.start).isSmall() && (!p.isQuad || p.ctrl.sub(p.start).isSmall())
+}
+
+// startTangent returns the (non normalized) direction at the start of the piece
+func (p piece) startTangent() vec {
+	if p.isQuad {
+		if t := p.ctrl.sub(p.start); !t.isSmall() {
+			return t
+		}
+	}
+	return p.end.sub(p.start)
+}
+
+// endTangent returns the (non normalized) direction at the end of the piece
+func (p piece) endTangent() vec {
+	if p.isQuad {
+		if t := p.end.sub(p.ctrl); !t.isSmall() {
+			return t
+		}
+	}
+	return p.end.sub(p.start)
+}
+
+// eval returns the point at parameter [t]
+func (p piece) eval(t float32) vec {
+	if !p.isQuad {
+		return p.start.lerp(p.end, t)
+	}
+	a, b := p.start.lerp(p.ctrl, t), p.ctrl.lerp(p.end, t)
+	return a.lerp(b, t)
+}
+
+// tangent returns the (non normalized) derivative at parameter [t]
+func (p piece) tangent(t float32) vec {
+	if !p.isQuad {
+		return p.end.sub(p.start)
+	}
+	a, b := p.start.lerp(p.ctrl, t), p.ctrl.lerp(p.end, t)
+	if d := b.sub(a); !d.isSmall() {
+		return d
+	}
+	return p.end.sub(p.start)
+}
+
+// split cuts the piece at parameter [t], using de Casteljau algorithm
+func (p piece) split(t float32) (piece, piece) {
+	if !p.isQuad {
+		m := p.start.lerp(p.end, t)
+		return line(p.start, m), line(m, p.end)
+	}
+	a, b := p.start.lerp(p.ctrl, t), p.ctrl.lerp(p.end, t)
+	m := a.lerp(b, t)
+	return quad(p.start, a, m), quad(m, b, p.end)
+}
+
+// lineIntersection returns the intersection of the lines (p, p + u) and (q, q + v),
+// or false if they are (almost) parallel
+func lineIntersection(p, u, q, v vec) (vec, bool) {
+	den := u.cross(v)
+	if math.Abs(float64(den)) < 1e-6*float64(u.length()*v.length()) {
+		return vec{}, false
+	}
+	s := q.sub(p).cross(v) / den
+	return p.add(u.scale(s)), true
+}
+
+// segmentIntersection returns the parameters of the intersection
+// of the segments [p0, p1] and [q0, q1], if any
+func segmentIntersection(p0, p1, q0, q1 vec) (s, u float32, ok bool) {
+	dp, dq := p1.sub(p0), q1.sub(q0)
+	den := dp.cross(dq)
+	if den == 0 {
+		return 0, 0, false
+	}
+	w := q0.sub(p0)
+	s = w.cross(dq) / den
+	u = w.cross(dp) / den
+	return s, u, s >= 0 && s <= 1 && u >= 0 && u <= 1
+}
+
+// the maximum number of subdivisions when approximating curves
+const maxDepth = 10
+
+// cubicToQuads approximates the cubic Bézier curve with quadratic curves,
+// appending them to [out].
+func cubicToQuads(p0, p1, p2, p3 vec, tolerance float32, depth int, out []piece) []piece {
+	// the distance between the cubic and the quadratic curve with control point
+	// (3(p1 + p2) - p0 - p3) / 4 is bounded by √3/36 |p3 - 3p2 + 3p1 - p0|
+	err := p3.sub(p2.scale(3)).add(p1.scale(3)).sub(p0).length() * float32(math.Sqrt(3)) / 36
+	if err <= tolerance || depth >= maxDepth {
+		ctrl := p1.add(p2).scale(3).sub(p0).sub(p3).scale(0.25)
+		return append(out, quad(p0, ctrl, p3))
+	}
+	// split at 0.5
+	p01, p12, p23 := p0.lerp(p1, 0.5), p1.lerp(p2, 0.5), p2.lerp(p3, 0.5)
+	p012, p123 := p01.lerp(p12, 0.5), p12.lerp(p23, 0.5)
+	m := p012.lerp(p123, 0.5)
+	out = cubicToQuads(p0, p01, p012, m, tolerance, depth+1, out)
+	return cubicToQuads(m, p123, p23, p3, tolerance, depth+1, out)
+}
+
+// offsetPiece appends to [out] the approximation of the curve at (signed) distance [d]
+// from [p], on its right side.
+func offsetPiece(p piece, d, tolerance float32, depth int, out []piece) []piece {
+	if !p.isQuad {
+		n := p.end.sub(p.start).rightNormal().scale(d)
+		return append(out, line(p.start.add(n), p.end.add(n)))
+	}
+
+	t0, t2 := p.startTangent(), p.endTangent()
+	q0 := p.start.add(t0.rightNormal().scale(d))
+	q2 := p.end.add(t2.rightNormal().scale(d))
+	ctrl, ok := lineIntersection(q0, t0, q2, t2)
+	if !ok {
+		ctrl = q0.lerp(q2, 0.5)
+	}
+	approx := quad(q0, ctrl, q2)
+
+	if depth < maxDepth {
+		// split the curves with large turns, and check the error at the middle
+		needSplit := t0.dot(t2) <= 0.5*t0.length()*t2.length() // more than 60°
+		if !needSplit {
+			mid := p.eval(0.5).add(p.tangent(0.5).rightNormal().scale(d))
+			needSplit = approx.eval(0.5).sub(mid).length() > tolerance
+		}
+		if needSplit {
+			a, b := p.split(0.5)
+			out = offsetPiece(a, d, tolerance, depth+1, out)
+			return offsetPiece(b, d, tolerance, depth+1, out)
+		}
+	}
+	return append(out, approx)
+}
+
+// arc appends quadratic curves approximating the arc with [center],
+// starting at center + u and rotating by [angle] radians (counter-clockwise if positive).
+// [end] is the expected end point, used to avoid rounding errors.
+func arc(center, u vec, angle float64, end vec, out []piece) []piece {
+	n := int(math.Ceil(math.Abs(angle) / (math.Pi / 4)))
+	if n == 0 {
+		return out
+	}
+	step := angle / float64(n)
+	ctrlScale := float32(1 / math.Cos(step/2))
+	start := center.add(u)
+	for k := 0; k < n; k++ {
+		ctrl := center.add(u.rotate((float64(k) + 0.5) * step).scale(ctrlScale))
+		next := end
+		if k != n-1 {
+			next = center.add(u.rotate(float64(k+1) * step))
+		}
+		out = append(out, quad(start, ctrl, next))
+		start = next
+	}
+	return out
+}
+
+// signedArea returns the signed area of the polygon formed by the
+// points of the contours (including control points), which is positive
+// for counter-clockwise contours.
+func signedArea(contours [][]piece) float32 {
+	var a float32
+	for _, contour := range contours {
+		for _, p := range contour {
+			if p.isQuad {
+				a += p.start.cross(p.ctrl) + p.ctrl.cross(p.end)
+			} else {
+				a += p.start.cross(p.end)
+			}
+		}
+	}
+	return a / 2
+}
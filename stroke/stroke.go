@@ -0,0 +1,330 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package stroke implements outline effects on glyph outlines,
+// as returned by [font.Face.GlyphDataOutline] : stroking (see [Stroke])
+// and offsetting (see [Offset]).
+//
+// The input and output segments are expressed in font units,
+// with the Y axis pointing up.
+// Cubic Bézier curves are approximated by quadratic ones, so that
+// the output only contains MoveTo, LineTo and QuadTo segments.
+//
+// The returned contours may overlap or self-intersect and are meant
+// to be filled with the non-zero winding rule.
+package stroke
+
+import (
+	"math"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// Join specifies how the corners of a path are rendered.
+type Join uint8
+
+const (
+	// MiterJoin extends the outer edges up to their intersection,
+	// falling back to BevelJoin when the miter length exceeds [Options.MiterLimit].
+	MiterJoin Join = iota
+	// RoundJoin uses a circular arc.
+	RoundJoin
+	// BevelJoin connects the outer edges with a straight line.
+	BevelJoin
+)
+
+// Cap specifies how the ends of open contours are rendered.
+type Cap uint8
+
+const (
+	// ButtCap ends the stroke exactly at the end point.
+	ButtCap Cap = iota
+	// RoundCap adds a half circle.
+	RoundCap
+	// SquareCap extends the stroke by half its width.
+	SquareCap
+)
+
+// Options provides settings for the stroker.
+type Options struct {
+	// Width is the stroke width, in font units.
+	Width float32
+
+	Join Join
+	Cap  Cap
+
+	// MiterLimit is the maximum ratio between the miter length
+	// and half the stroke width.
+	// It defaults to 4.
+	MiterLimit float32
+
+	// Tolerance is the maximum distance, in font units,
+	// between the exact curves and their approximations.
+	// It defaults to 0.5.
+	Tolerance float32
+
+	// OpenContours should be set to treat the input contours as open paths,
+	// so that caps are added at their ends.
+	// By default, contours are closed, as for font outlines.
+	OpenContours bool
+}
+
+func (opts *Options) setDefaults() {
+	if opts.MiterLimit <= 0 {
+		opts.MiterLimit = 4
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 0.5
+	}
+}
+
+// Stroke returns the outline of the stroke of [segments], using the given [opts].
+// The contours of the result are always oriented counter-clockwise,
+// and an empty slice is returned for a non positive width.
+func Stroke(segments []font.Segment, opts Options) []font.Segment {
+	if opts.Width <= 0 {
+		return nil
+	}
+	opts.setDefaults()
+	h := opts.Width / 2
+
+	var out []font.Segment
+	for _, contour := range parseContours(segments, !opts.OpenContours, opts.Tolerance) {
+		if opts.OpenContours {
+			left := offsetContour(contour, h, false, opts)
+			right := reverseContour(offsetContour(contour, -h, false, opts))
+			last := contour[len(contour)-1]
+			left = addCap(left, last.end, last.endTangent(), h, opts.Cap)
+			right = addCap(right, contour[0].start, contour[0].startTangent().scale(-1), h, opts.Cap)
+			path := append(left, right...)
+			if signedArea([][]piece{path}) < 0 {
+				path = reverseContour(path)
+			}
+			out = appendContour(out, path)
+		} else {
+			left := offsetContour(contour, h, true, opts)
+			right := reverseContour(offsetContour(contour, -h, true, opts))
+			// make sure the stroke is filled with a positive winding number,
+			// so that overlapping strokes do not cancel each other
+			if signedArea([][]piece{left, right}) < 0 {
+				left, right = reverseContour(left), reverseContour(right)
+			}
+			out = appendContour(out, left)
+			out = appendContour(out, right)
+		}
+	}
+	return out
+}
+
+// Offset moves the contours of [segments] by [distance] font units,
+// along their normals. Positive values make the shape larger (dilatation),
+// negative values thinner (erosion), whatever the orientation of the contours.
+//
+// Only the Join, MiterLimit and Tolerance fields of [opts] are used, and the contours are always closed.
+func Offset(segments []font.Segment, distance float32, opts Options) []font.Segment {
+	opts.setDefaults()
+	contours := parseContours(segments, true, opts.Tolerance)
+
+	// for counter-clockwise outlines, the right side is the outside
+	if signedArea(contours) < 0 {
+		distance = -distance
+	}
+
+	var out []font.Segment
+	for _, contour := range contours {
+		out = appendContour(out, offsetContour(contour, distance, true, opts))
+	}
+	return out
+}
+
+func toVec(p font.SegmentPoint) vec { return vec{p.X, p.Y} }
+
+// parseContours splits the segments into contours, dropping the empty pieces,
+// and approximating cubic curves.
+// If [closed] is true, the contours are explicitly closed with a line.
+func parseContours(segments []font.Segment, closed bool, tolerance float32) (out [][]piece) {
+	var (
+		current    []piece
+		start, pos vec
+	)
+	flush := func() {
+		if closed && !pos.sub(start).isSmall() {
+			current = append(current, line(pos, start))
+		}
+		if len(current) != 0 {
+			out = append(out, current)
+		}
+		current = nil
+	}
+	for _, seg := range segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo:
+			flush()
+			start = toVec(seg.Args[0])
+			pos = start
+		case ot.SegmentOpLineTo:
+			p := line(pos, toVec(seg.Args[0]))
+			if !p.isDegenerate() {
+				current = append(current, p)
+			}
+			pos = p.end
+		case ot.SegmentOpQuadTo:
+			p := quad(pos, toVec(seg.Args[0]), toVec(seg.Args[1]))
+			if !p.isDegenerate() {
+				current = append(current, p)
+			}
+			pos = p.end
+		case ot.SegmentOpCubeTo:
+			p1, p2, p3 := toVec(seg.Args[0]), toVec(seg.Args[1]), toVec(seg.Args[2])
+			if !(p1.sub(pos).isSmall() && p2.sub(pos).isSmall() && p3.sub(pos).isSmall()) {
+				current = cubicToQuads(pos, p1, p2, p3, tolerance, 0, current)
+			}
+			pos = p3
+		}
+	}
+	flush()
+	return out
+}
+
+// appendContour converts [contour] to segments
+func appendContour(out []font.Segment, contour []piece) []font.Segment {
+	if len(contour) == 0 {
+		return out
+	}
+	start := contour[0].start
+	out = append(out, font.Segment{Op: ot.SegmentOpMoveTo, Args: [3]font.SegmentPoint{{X: start.x, Y: start.y}}})
+	for _, p := range contour {
+		end := font.SegmentPoint{X: p.end.x, Y: p.end.y}
+		if p.isQuad {
+			out = append(out, font.Segment{Op: ot.SegmentOpQuadTo, Args: [3]font.SegmentPoint{{X: p.ctrl.x, Y: p.ctrl.y}, end}})
+		} else {
+			out = append(out, font.Segment{Op: ot.SegmentOpLineTo, Args: [3]font.SegmentPoint{end}})
+		}
+	}
+	if last := contour[len(contour)-1].end; last != start {
+		out = append(out, font.Segment{Op: ot.SegmentOpLineTo, Args: [3]font.SegmentPoint{{X: start.x, Y: start.y}}})
+	}
+	return out
+}
+
+func reverseContour(contour []piece) []piece {
+	out := make([]piece, len(contour))
+	for i, p := range contour {
+		out[len(contour)-1-i] = p.reversed()
+	}
+	return out
+}
+
+// offsetContour returns the curve at distance [d] on the right of [contour],
+// with joins between its pieces.
+func offsetContour(contour []piece, d float32, closed bool, opts Options) []piece {
+	var out []piece
+	for i, p := range contour {
+		offset := offsetPiece(p, d, opts.Tolerance, 0, nil)
+		if i == 0 {
+			out = offset
+			continue
+		}
+		prev := contour[i-1]
+		a, join, b := joinPieces(out[len(out)-1], offset[0], p.start, prev.endTangent(), p.startTangent(), d, opts)
+		out[len(out)-1] = a
+		out = append(out, join...)
+		offset[0] = b
+		out = append(out, offset...)
+	}
+
+	if closed && len(contour) > 1 {
+		prev, next := contour[len(contour)-1], contour[0]
+		a, join, b := joinPieces(out[len(out)-1], out[0], next.start, prev.endTangent(), next.startTangent(), d, opts)
+		out[len(out)-1] = a
+		out[0] = b
+		out = append(out, join...)
+	}
+	return out
+}
+
+// joinPieces connects [a], ending near [vertex] + d * rightNormal(ta),
+// and [b], starting near [vertex] + d * rightNormal(tb).
+// It returns the (possibly trimmed) pieces and the connecting pieces.
+func joinPieces(a, b piece, vertex, ta, tb vec, d float32, opts Options) (piece, []piece, piece) {
+	ta, tb = ta.normalized(), tb.normalized()
+	cross, dot := ta.cross(tb), ta.dot(tb)
+
+	const eps = 1e-4
+	if math.Abs(float64(cross)) < eps && dot > 0 { // no turn
+		if a.end.sub(b.start).isSmall() {
+			return a, nil, b
+		}
+		return a, []piece{line(a.end, b.start)}, b
+	}
+
+	isReversal := math.Abs(float64(cross)) < eps
+	if !isReversal && cross*d < 0 { // inner join
+		if a, b, ok := trimInner(a, b); ok {
+			return a, nil, b
+		}
+		return a, []piece{line(a.end, vertex), line(vertex, b.start)}, b
+	}
+
+	// outer join
+	switch opts.Join {
+	case RoundJoin:
+		angle := math.Atan2(float64(cross), float64(dot))
+		if isReversal {
+			angle = math.Copysign(math.Pi, float64(d))
+		}
+		return a, arc(vertex, a.end.sub(vertex), angle, b.start, nil), b
+	case MiterJoin:
+		if !isReversal && 1/math.Sqrt(float64(1+dot)/2) <= float64(opts.MiterLimit) {
+			na, nb := ta.rightNormal(), tb.rightNormal()
+			miter := vertex.add(na.add(nb).scale(d / (1 + dot)))
+			return a, []piece{line(a.end, miter), line(miter, b.start)}, b
+		}
+	}
+	// bevel
+	return a, []piece{line(a.end, b.start)}, b
+}
+
+// trimInner looks for an intersection between [a] and [b],
+// looking for the closest one from the end of [a], and returns
+// the pieces cut at this intersection.
+func trimInner(a, b piece) (piece, piece, bool) {
+	flatten := func(p piece) []vec {
+		n := 1
+		if p.isQuad {
+			n = 8
+		}
+		out := make([]vec, n+1)
+		for k := range out {
+			out[k] = p.eval(float32(k) / float32(n))
+		}
+		return out
+	}
+	pa, pb := flatten(a), flatten(b)
+	na, nb := float32(len(pa)-1), float32(len(pb)-1)
+	for i := len(pa) - 2; i >= 0; i-- {
+		for j := 0; j < len(pb)-1; j++ {
+			if s, u, ok := segmentIntersection(pa[i], pa[i+1], pb[j], pb[j+1]); ok {
+				a, _ = a.split((float32(i) + s) / na)
+				_, b = b.split((float32(j) + u) / nb)
+				return a, b, true
+			}
+		}
+	}
+	return a, b, false
+}
+
+// addCap appends the cap at [end], for a path with direction [tangent],
+// from end + h * rightNormal(tangent) to end - h * rightNormal(tangent).
+func addCap(path []piece, end, tangent vec, h float32, style Cap) []piece {
+	n, t := tangent.rightNormal().scale(h), tangent.normalized().scale(h)
+	from, to := end.add(n), end.sub(n)
+	switch style {
+	case RoundCap:
+		return arc(end, n, math.Pi, to, path)
+	case SquareCap:
+		return append(path, line(from, from.add(t)), line(from.add(t), to.add(t)), line(to.add(t), to))
+	default:
+		return append(path, line(from, to))
+	}
+}
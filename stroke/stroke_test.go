@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package stroke
+
+import (
+	"bytes"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func moveTo(x, y float32) font.Segment {
+	return font.Segment{Op: ot.SegmentOpMoveTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+}
+
+func lineTo(x, y float32) font.Segment {
+	return font.Segment{Op: ot.SegmentOpLineTo, Args: [3]font.SegmentPoint{{X: x, Y: y}}}
+}
+
+// square returns a counter-clockwise square
+func square() []font.Segment {
+	return []font.Segment{moveTo(0, 0), lineTo(100, 0), lineTo(100, 100), lineTo(0, 100), lineTo(0, 0)}
+}
+
+// points returns the end points of the segments
+func points(segments []font.Segment) (out []vec) {
+	for _, seg := range segments {
+		args := seg.ArgsSlice()
+		out = append(out, toVec(args[len(args)-1]))
+	}
+	return out
+}
+
+func approxEqual(got, exp []vec) bool {
+	if len(got) != len(exp) {
+		return false
+	}
+	for i := range got {
+		if got[i].sub(exp[i]).length() > 1e-3 {
+			return false
+		}
+	}
+	return true
+}
+
+func bounds(segments []font.Segment) (min, max vec) {
+	min, max = vec{1e9, 1e9}, vec{-1e9, -1e9}
+	for i := range segments {
+		for _, p := range segments[i].ArgsSlice() {
+			min = vec{min32(min.x, p.X), min32(min.y, p.Y)}
+			max = vec{max32(max.x, p.X), max32(max.y, p.Y)}
+		}
+	}
+	return
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func reversed(segments []font.Segment) []font.Segment {
+	out := []font.Segment{moveTo(segments[len(segments)-1].Args[0].X, segments[len(segments)-1].Args[0].Y)}
+	for i := len(segments) - 2; i >= 0; i-- {
+		out = append(out, lineTo(segments[i].Args[0].X, segments[i].Args[0].Y))
+	}
+	return out
+}
+
+func TestOffsetSquare(t *testing.T) {
+	for _, segments := range [][]font.Segment{square(), reversed(square())} {
+		dilated := Offset(segments, 10, Options{})
+		min, max := bounds(dilated)
+		tu.Assert(t, min == vec{-10, -10} && max == vec{110, 110})
+
+		eroded := Offset(segments, -10, Options{})
+		min, max = bounds(eroded)
+		tu.Assert(t, min.sub(vec{10, 10}).isSmall() && max.sub(vec{90, 90}).isSmall())
+		tu.Assert(t, len(eroded) == 5)
+	}
+
+	got := points(Offset(square(), 10, Options{}))
+	tu.Assert(t, approxEqual(got, []vec{
+		{0, -10}, {100, -10}, {110, -10}, {110, 0}, {110, 100}, {110, 110},
+		{100, 110}, {0, 110}, {-10, 110}, {-10, 100}, {-10, 0}, {-10, -10}, {0, -10},
+	}))
+
+	got = points(Offset(square(), 10, Options{Join: BevelJoin}))
+	tu.Assert(t, approxEqual(got, []vec{
+		{0, -10}, {100, -10}, {110, 0}, {110, 100},
+		{100, 110}, {0, 110}, {-10, 100}, {-10, 0}, {0, -10},
+	}))
+
+	// the miter limit is exceeded for right angles (ratio √2)
+	got = points(Offset(square(), 10, Options{MiterLimit: 1.2}))
+	tu.Assert(t, len(got) == 9)
+
+	// each corner is approximated by two quadratic curves
+	rounded := Offset(square(), 10, Options{Join: RoundJoin})
+	tu.Assert(t, len(rounded) == 1+4+4*2)
+	for _, corner := range []vec{{100, 0}, {100, 100}, {0, 100}, {0, 0}} {
+		var onArc int
+		for _, p := range points(rounded) {
+			if absf(p.sub(corner).length()-10) < 1e-3 {
+				onArc++
+			}
+		}
+		tu.Assert(t, onArc >= 3)
+	}
+}
+
+func TestStrokeSquare(t *testing.T) {
+	for _, segments := range [][]font.Segment{square(), reversed(square())} {
+		stroked := Stroke(segments, Options{Width: 20})
+		// two contours
+		tu.Assert(t, stroked[0].Op == ot.SegmentOpMoveTo)
+		var nbContours int
+		for _, seg := range stroked {
+			if seg.Op == ot.SegmentOpMoveTo {
+				nbContours++
+			}
+		}
+		tu.Assert(t, nbContours == 2)
+		min, max := bounds(stroked)
+		tu.Assert(t, min == vec{-10, -10} && max == vec{110, 110})
+
+		// the stroke has a positive winding
+		contours := parseContours(stroked, true, 0.5)
+		tu.Assert(t, len(contours) == 2)
+		tu.Assert(t, signedArea(contours) > 0)
+		tu.Assert(t, absf(signedArea(contours)-(120*120-80*80)) < 1)
+	}
+
+	tu.Assert(t, Stroke(square(), Options{}) == nil)
+}
+
+func absf(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestStrokeCaps(t *testing.T) {
+	segments := []font.Segment{moveTo(0, 0), lineTo(100, 0)}
+
+	got := points(Stroke(segments, Options{Width: 20, OpenContours: true}))
+	tu.Assert(t, approxEqual(got, []vec{{0, -10}, {100, -10}, {100, 10}, {0, 10}, {0, -10}}))
+
+	got = points(Stroke(segments, Options{Width: 20, OpenContours: true, Cap: SquareCap}))
+	tu.Assert(t, approxEqual(got, []vec{
+		{0, -10}, {100, -10}, {110, -10}, {110, 10}, {100, 10}, {0, 10}, {-10, 10}, {-10, -10}, {0, -10},
+	}))
+
+	stroked := Stroke(segments, Options{Width: 20, OpenContours: true, Cap: RoundCap})
+	tu.Assert(t, len(stroked) == 1+1+4+1+4) // move, line, arc, line, arc
+	min, max := bounds(stroked)
+	tu.Assert(t, min.sub(vec{-10, -10}).isSmall() && max.sub(vec{110, 10}).isSmall())
+
+	// a polyline with an inner and outer join
+	segments = []font.Segment{moveTo(0, 0), lineTo(100, 0), lineTo(100, 100)}
+	stroked = Stroke(segments, Options{Width: 20, OpenContours: true})
+	min, max = bounds(stroked)
+	tu.Assert(t, min == vec{0, -10} && max == vec{110, 100})
+	tu.Assert(t, approxEqual(points(stroked), []vec{
+		{0, -10}, {100, -10}, {110, -10}, {110, 0}, {110, 100}, {90, 100}, {90, 10}, {0, 10}, {0, -10},
+	}))
+}
+
+func loadFace(t *testing.T, filename string) *font.Face {
+	file, err := td.Files.ReadFile(filename)
+	tu.AssertNoErr(t, err)
+	ft, err := font.ParseTTF(bytes.NewReader(file))
+	tu.AssertNoErr(t, err)
+	return ft
+}
+
+func TestGlyphs(t *testing.T) {
+	for _, filename := range []string{
+		"common/DejaVuSans.ttf",            // quadratic curves
+		"common/Raleway-v4020-Regular.otf", // cubic curves
+	} {
+		face := loadFace(t, filename)
+		for _, r := range "oHa&" {
+			gid, ok := face.NominalGlyph(r)
+			tu.Assert(t, ok)
+			outline, _ := face.GlyphData(gid).(font.GlyphOutline)
+			tu.Assert(t, len(outline.Segments) != 0)
+			min, max := bounds(outline.Segments)
+
+			for _, join := range []Join{MiterJoin, RoundJoin, BevelJoin} {
+				stroked := Stroke(outline.Segments, Options{Width: 40, Join: join})
+				tu.Assert(t, len(stroked) != 0)
+				strokeMin, strokeMax := bounds(stroked)
+				tu.Assert(t, strokeMin.x < min.x && strokeMin.y < min.y && strokeMax.x > max.x && strokeMax.y > max.y)
+				for _, seg := range stroked {
+					tu.Assert(t, seg.Op != ot.SegmentOpCubeTo)
+				}
+
+				dilated := Offset(outline.Segments, 20, Options{Join: join})
+				dilatedMin, dilatedMax := bounds(dilated)
+				tu.Assert(t, dilatedMin.x < min.x && dilatedMax.x > max.x)
+				tu.Assert(t, absf(dilatedMin.x-(min.x-20)) < 5 && absf(dilatedMax.y-(max.y+20)) < 5)
+
+				eroded := Offset(outline.Segments, -10, Options{Join: join})
+				erodedMin, erodedMax := bounds(eroded)
+				tu.Assert(t, erodedMin.x > min.x && erodedMax.x < max.x)
+			}
+		}
+	}
+}
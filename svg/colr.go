@@ -0,0 +1,357 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
+
+// this file translates the COLR paint graphs to SVG elements
+//
+// Paints are expressed in font units, and the variable paints are
+// rendered using their default values (no variation deltas are applied).
+
+// protect against malicious fonts with cyclic paint graphs
+const maxPaintDepth = 64
+
+// foregroundIndex is the palette index used for the text color
+const foregroundIndex = 0xFFFF
+
+// fillAll is used to fill the current clip path with a paint
+const fillAll = `<rect x="-32768" y="-32768" width="65536" height="65536"`
+
+// colorStop is a resolved stop, common to
+// variable and non variable color lines
+type colorStop struct {
+	offset       float32
+	paletteIndex uint16
+	alpha        float32
+}
+
+func f2dot14(v tables.Fixed214) float32 { return float32(v) / (1 << 14) }
+
+func colorLineStops(cl tables.ColorLine) []colorStop {
+	out := make([]colorStop, len(cl.ColorStops))
+	for i, stop := range cl.ColorStops {
+		out[i] = colorStop{f2dot14(stop.StopOffset), stop.PaletteIndex, f2dot14(stop.Alpha)}
+	}
+	return out
+}
+
+func varColorLineStops(cl tables.VarColorLine) []colorStop {
+	out := make([]colorStop, len(cl.ColorStops))
+	for i, stop := range cl.ColorStops {
+		out[i] = colorStop{f2dot14(stop.StopOffset), stop.PaletteIndex, f2dot14(stop.Alpha)}
+	}
+	return out
+}
+
+// paletteColor resolves the color at [index] in the selected palette,
+// returning the SVG color and the opacity.
+func (ex *exporter) paletteColor(face *font.Face, index uint16, alpha float32) (string, float32) {
+	if index == foregroundIndex {
+		_, fgAlpha := formatColor(ex.opts.Foreground)
+		return "currentColor", alpha * fgAlpha
+	}
+	palette := ex.opts.Palette
+	if palette < 0 || palette >= len(face.CPAL) {
+		palette = 0
+	}
+	if palette >= len(face.CPAL) || int(index) >= len(face.CPAL[palette]) {
+		return "#000000", alpha
+	}
+	c := face.CPAL[palette][index]
+	return fmt.Sprintf("#%02x%02x%02x", c.Red, c.Green, c.Blue), alpha * float32(c.Alpha) / 0xFF
+}
+
+// fillAttributes returns the SVG fill attributes for the given palette entry.
+func (ex *exporter) fillAttributes(face *font.Face, index uint16, alpha float32) string {
+	fill, opacity := ex.paletteColor(face, index, alpha)
+	if opacity == 1 {
+		return fmt.Sprintf(` fill="%s"`, fill)
+	}
+	return fmt.Sprintf(` fill="%s" fill-opacity="%s"`, fill, formatFloat(opacity))
+}
+
+// clipDef returns the id of a clip path made of the glyph outline,
+// or an empty string for empty glyphs.
+func (ex *exporter) clipDef(face *font.Face, gid font.GID) string {
+	outline, _ := face.GlyphDataOutline(gid)
+	outlineID := ex.outlineDef(face, gid, outline)
+	if outlineID == "" {
+		return ""
+	}
+	clipID := "c" + outlineID
+	if _, ok := ex.clips[outlineID]; !ok {
+		ex.clips[outlineID] = struct{}{}
+		fmt.Fprintf(&ex.defs, `<clipPath id="%s"><use href="#%s"/></clipPath>`+"\n", clipID, outlineID)
+	}
+	return clipID
+}
+
+// writeGradientStops writes the <stop> elements, which must be
+// sorted and clamped to [0;1] in SVG.
+func (ex *exporter) writeGradientStops(face *font.Face, stops []colorStop) {
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].offset < stops[j].offset })
+	for _, stop := range stops {
+		fill, opacity := ex.paletteColor(face, stop.paletteIndex, stop.alpha)
+		offset := maxF(0, minF(1, stop.offset))
+		fmt.Fprintf(&ex.defs, `<stop offset="%s" stop-color="%s" stop-opacity="%s"/>`, formatFloat(offset), fill, formatFloat(opacity))
+	}
+}
+
+func spreadMethod(extend tables.Extend) string {
+	switch extend {
+	case tables.ExtendRepeat:
+		return "repeat"
+	case tables.ExtendReflect:
+		return "reflect"
+	default:
+		return "pad"
+	}
+}
+
+// linearGradientDef writes a gradient definition and returns its id.
+func (ex *exporter) linearGradientDef(face *font.Face, stops []colorStop, extend tables.Extend, x0, y0, x1, y1, x2, y2 float32) string {
+	// SVG gradients do not support the rotation point p2:
+	// project p1 on the line orthogonal to p0p2
+	x3, y3 := x1, y1
+	if nx, ny := -(y2 - y0), x2-x0; nx != 0 || ny != 0 {
+		dot := ((x1-x0)*nx + (y1-y0)*ny) / (nx*nx + ny*ny)
+		x3, y3 = x0+dot*nx, y0+dot*ny
+	}
+	id := ex.newID("lg")
+	fmt.Fprintf(&ex.defs, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s" spreadMethod="%s">`,
+		id, formatFloat(x0), formatFloat(y0), formatFloat(x3), formatFloat(y3), spreadMethod(extend))
+	ex.writeGradientStops(face, stops)
+	ex.defs.WriteString("</linearGradient>\n")
+	return id
+}
+
+// radialGradientDef writes a gradient definition and returns its id.
+func (ex *exporter) radialGradientDef(face *font.Face, stops []colorStop, extend tables.Extend, x0, y0, r0, x1, y1, r1 float32) string {
+	id := ex.newID("rg")
+	fmt.Fprintf(&ex.defs, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" fx="%s" fy="%s" fr="%s" cx="%s" cy="%s" r="%s" spreadMethod="%s">`,
+		id, formatFloat(x0), formatFloat(y0), formatFloat(r0), formatFloat(x1), formatFloat(y1), formatFloat(r1), spreadMethod(extend))
+	ex.writeGradientStops(face, stops)
+	ex.defs.WriteString("</radialGradient>\n")
+	return id
+}
+
+// writeSweepGradient approximates the sweep gradient, which is not supported by SVG,
+// with a solid fill using the first color stop.
+func (ex *exporter) writeSweepGradient(w *bytes.Buffer, face *font.Face, stops []colorStop) {
+	if len(stops) == 0 {
+		return
+	}
+	fmt.Fprintf(w, fillAll+"%s/>\n", ex.fillAttributes(face, stops[0].paletteIndex, stops[0].alpha))
+}
+
+func (ex *exporter) writeTransformed(w *bytes.Buffer, face *font.Face, transform string, paint tables.PaintTable, depth int) {
+	fmt.Fprintf(w, `<g transform="%s">`+"\n", transform)
+	ex.writePaint(w, face, paint, depth+1)
+	w.WriteString("</g>\n")
+}
+
+func aroundCenter(transform string, centerX, centerY int16) string {
+	return fmt.Sprintf("translate(%d %d) %s translate(%d %d)", centerX, centerY, transform, -centerX, -centerY)
+}
+
+// angles are expressed as multiple of 180°, counter-clockwise
+func rotate(angle tables.Fixed214) string {
+	return fmt.Sprintf("rotate(%s)", formatFloat(f2dot14(angle)*180))
+}
+
+func skew(xAngle, yAngle tables.Fixed214) string {
+	tanX := math.Tan(float64(f2dot14(xAngle)) * math.Pi)
+	tanY := math.Tan(float64(f2dot14(yAngle)) * math.Pi)
+	return formatMatrix([6]float32{1, float32(tanY), float32(-tanX), 1, 0, 0})
+}
+
+func scale(sx, sy tables.Fixed214) string {
+	return fmt.Sprintf("scale(%s %s)", formatFloat(f2dot14(sx)), formatFloat(f2dot14(sy)))
+}
+
+// writePaint translates [paint] to SVG elements, written in [w].
+// Definitions required by the elements (gradients, clip paths) are directly written
+// in the document definitions.
+func (ex *exporter) writePaint(w *bytes.Buffer, face *font.Face, paint tables.PaintTable, depth int) {
+	if depth > maxPaintDepth {
+		return
+	}
+	switch p := paint.(type) {
+	case tables.PaintColrLayersResolved: // COLR version 0
+		for _, layer := range p {
+			outline, _ := face.GlyphDataOutline(font.GID(layer.GlyphID))
+			id := ex.outlineDef(face, font.GID(layer.GlyphID), outline)
+			if id == "" {
+				continue
+			}
+			fmt.Fprintf(w, `<use href="#%s"%s/>`+"\n", id, ex.fillAttributes(face, layer.PaletteIndex, 1))
+		}
+	case tables.PaintColrLayers:
+		layers, err := face.COLR.LayerList.Resolve(p)
+		if err != nil {
+			return
+		}
+		for _, layer := range layers {
+			ex.writePaint(w, face, layer, depth+1)
+		}
+	case tables.PaintSolid:
+		fmt.Fprintf(w, fillAll+"%s/>\n", ex.fillAttributes(face, p.PaletteIndex, f2dot14(p.Alpha)))
+	case tables.PaintVarSolid:
+		fmt.Fprintf(w, fillAll+"%s/>\n", ex.fillAttributes(face, p.PaletteIndex, f2dot14(p.Alpha)))
+	case tables.PaintLinearGradient:
+		id := ex.linearGradientDef(face, colorLineStops(p.ColorLine), p.ColorLine.Extend,
+			float32(p.X0), float32(p.Y0), float32(p.X1), float32(p.Y1), float32(p.X2), float32(p.Y2))
+		fmt.Fprintf(w, fillAll+` fill="url(#%s)"/>`+"\n", id)
+	case tables.PaintVarLinearGradient:
+		id := ex.linearGradientDef(face, varColorLineStops(p.ColorLine), p.ColorLine.Extend,
+			float32(p.X0), float32(p.Y0), float32(p.X1), float32(p.Y1), float32(p.X2), float32(p.Y2))
+		fmt.Fprintf(w, fillAll+` fill="url(#%s)"/>`+"\n", id)
+	case tables.PaintRadialGradient:
+		id := ex.radialGradientDef(face, colorLineStops(p.ColorLine), p.ColorLine.Extend,
+			float32(p.X0), float32(p.Y0), float32(p.Radius0), float32(p.X1), float32(p.Y1), float32(p.Radius1))
+		fmt.Fprintf(w, fillAll+` fill="url(#%s)"/>`+"\n", id)
+	case tables.PaintVarRadialGradient:
+		id := ex.radialGradientDef(face, varColorLineStops(p.ColorLine), p.ColorLine.Extend,
+			float32(p.X0), float32(p.Y0), float32(p.Radius0), float32(p.X1), float32(p.Y1), float32(p.Radius1))
+		fmt.Fprintf(w, fillAll+` fill="url(#%s)"/>`+"\n", id)
+	case tables.PaintSweepGradient:
+		ex.writeSweepGradient(w, face, colorLineStops(p.ColorLine))
+	case tables.PaintVarSweepGradient:
+		ex.writeSweepGradient(w, face, varColorLineStops(p.ColorLine))
+	case tables.PaintGlyph:
+		gid := font.GID(p.GlyphID)
+		// shortcut for the common case of a solid fill
+		if solid, ok := p.Paint.(tables.PaintSolid); ok {
+			outline, _ := face.GlyphDataOutline(gid)
+			if id := ex.outlineDef(face, gid, outline); id != "" {
+				fmt.Fprintf(w, `<use href="#%s"%s/>`+"\n", id, ex.fillAttributes(face, solid.PaletteIndex, f2dot14(solid.Alpha)))
+			}
+			return
+		}
+		clipID := ex.clipDef(face, gid)
+		if clipID == "" {
+			return
+		}
+		fmt.Fprintf(w, `<g clip-path="url(#%s)">`+"\n", clipID)
+		ex.writePaint(w, face, p.Paint, depth+1)
+		w.WriteString("</g>\n")
+	case tables.PaintColrGlyph:
+		if child, ok := face.COLR.Search(tables.GlyphID(p.GlyphID)); ok {
+			ex.writePaint(w, face, child, depth+1)
+		}
+	case tables.PaintTransform:
+		t := p.Transform
+		ex.writeTransformed(w, face, formatMatrix([6]float32{t.Xx, t.Yx, t.Xy, t.Yy, t.Dx, t.Dy}), p.Paint, depth)
+	case tables.PaintVarTransform:
+		t := p.Transform
+		ex.writeTransformed(w, face, formatMatrix([6]float32{t.Xx, t.Yx, t.Xy, t.Yy, t.Dx, t.Dy}), p.Paint, depth)
+	case tables.PaintTranslate:
+		ex.writeTransformed(w, face, fmt.Sprintf("translate(%d %d)", p.Dx, p.Dy), p.Paint, depth)
+	case tables.PaintVarTranslate:
+		ex.writeTransformed(w, face, fmt.Sprintf("translate(%d %d)", p.Dx, p.Dy), p.Paint, depth)
+	case tables.PaintScale:
+		ex.writeTransformed(w, face, scale(p.ScaleX, p.ScaleY), p.Paint, depth)
+	case tables.PaintVarScale:
+		ex.writeTransformed(w, face, scale(p.ScaleX, p.ScaleY), p.Paint, depth)
+	case tables.PaintScaleAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(scale(p.ScaleX, p.ScaleY), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintVarScaleAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(scale(p.ScaleX, p.ScaleY), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintScaleUniform:
+		ex.writeTransformed(w, face, scale(p.Scale, p.Scale), p.Paint, depth)
+	case tables.PaintVarScaleUniform:
+		ex.writeTransformed(w, face, scale(p.Scale, p.Scale), p.Paint, depth)
+	case tables.PaintScaleUniformAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(scale(p.Scale, p.Scale), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintVarScaleUniformAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(scale(p.Scale, p.Scale), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintRotate:
+		ex.writeTransformed(w, face, rotate(p.Angle), p.Paint, depth)
+	case tables.PaintVarRotate:
+		ex.writeTransformed(w, face, rotate(p.Angle), p.Paint, depth)
+	case tables.PaintRotateAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(rotate(p.Angle), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintVarRotateAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(rotate(p.Angle), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintSkew:
+		ex.writeTransformed(w, face, skew(p.XSkewAngle, p.YSkewAngle), p.Paint, depth)
+	case tables.PaintVarSkew:
+		ex.writeTransformed(w, face, skew(p.XSkewAngle, p.YSkewAngle), p.Paint, depth)
+	case tables.PaintSkewAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(skew(p.XSkewAngle, p.YSkewAngle), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintVarSkewAroundCenter:
+		ex.writeTransformed(w, face, aroundCenter(skew(p.XSkewAngle, p.YSkewAngle), p.CenterX, p.CenterY), p.Paint, depth)
+	case tables.PaintComposite:
+		ex.writeComposite(w, face, p, depth)
+	}
+}
+
+// writeComposite supports the blend modes using the CSS 'mix-blend-mode' property.
+// Porter-Duff modes other than Clear, Src, Dest, SrcOver and DestOver are approximated
+// by SrcOver.
+func (ex *exporter) writeComposite(w *bytes.Buffer, face *font.Face, p tables.PaintComposite, depth int) {
+	var blendMode string
+	switch p.CompositeMode {
+	case tables.CompositeClear:
+		return
+	case tables.CompositeSrc:
+		ex.writePaint(w, face, p.SourcePaint, depth+1)
+		return
+	case tables.CompositeDest:
+		ex.writePaint(w, face, p.BackdropPaint, depth+1)
+		return
+	case tables.CompositeDestOver:
+		ex.writePaint(w, face, p.SourcePaint, depth+1)
+		ex.writePaint(w, face, p.BackdropPaint, depth+1)
+		return
+	case tables.CompositeScreen:
+		blendMode = "screen"
+	case tables.CompositeOverlay:
+		blendMode = "overlay"
+	case tables.CompositeDarken:
+		blendMode = "darken"
+	case tables.CompositeLighten:
+		blendMode = "lighten"
+	case tables.CompositeColorDodge:
+		blendMode = "color-dodge"
+	case tables.CompositeColorBurn:
+		blendMode = "color-burn"
+	case tables.CompositeHardLight:
+		blendMode = "hard-light"
+	case tables.CompositeSoftLight:
+		blendMode = "soft-light"
+	case tables.CompositeDifference:
+		blendMode = "difference"
+	case tables.CompositeExclusion:
+		blendMode = "exclusion"
+	case tables.CompositeMultiply:
+		blendMode = "multiply"
+	case tables.CompositeHslHue:
+		blendMode = "hue"
+	case tables.CompositeHslSaturation:
+		blendMode = "saturation"
+	case tables.CompositeHslColor:
+		blendMode = "color"
+	case tables.CompositeHslLuminosity:
+		blendMode = "luminosity"
+	}
+
+	w.WriteString(`<g style="isolation:isolate">` + "\n")
+	ex.writePaint(w, face, p.BackdropPaint, depth+1)
+	if blendMode != "" {
+		fmt.Fprintf(w, `<g style="mix-blend-mode:%s">`+"\n", blendMode)
+	} else {
+		w.WriteString("<g>\n")
+	}
+	ex.writePaint(w, face, p.SourcePaint, depth+1)
+	w.WriteString("</g>\n</g>\n")
+}
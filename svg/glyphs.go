@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"regexp"
+
+	"github.com/go-text/typesetting/font"
+)
+
+// glyphDef returns the id of the definition for the given glyph,
+// writing it if needed.
+// An empty string is returned for glyphs with no content.
+func (ex *exporter) glyphDef(face *font.Face, gid font.GID) string {
+	key := glyphKey{face, gid}
+	if id, ok := ex.glyphs[key]; ok {
+		return id
+	}
+
+	var id string
+	if gid != font.EmptyGlyph {
+		id = ex.writeGlyphDef(face, gid)
+	}
+	ex.glyphs[key] = id
+	return id
+}
+
+// writeGlyphDef writes the definition of the glyph and returns its id,
+// or returns an empty string if it has no content.
+// The definitions are expressed in font units, with the Y axis pointing up.
+func (ex *exporter) writeGlyphDef(face *font.Face, gid font.GID) string {
+	id := fmt.Sprintf("g%d-%d", ex.faceIndex(face), gid)
+	switch data := face.GlyphData(gid).(type) {
+	case font.GlyphColor:
+		var content bytes.Buffer
+		ex.writePaint(&content, face, data.Paint, 0)
+		// the paint may have added definitions (gradients, clip paths)
+		fmt.Fprintf(&ex.defs, `<g id="%s">`+"\n", id)
+		ex.defs.Write(content.Bytes())
+		ex.defs.WriteString("</g>\n")
+	case font.GlyphBitmap:
+		if !ex.writeBitmapDef(face, gid, data, id) {
+			return ""
+		}
+	case font.GlyphSVG:
+		ex.writeSVGDef(face, gid, data, id)
+	case font.GlyphOutline:
+		return ex.outlineDef(face, gid, data)
+	default:
+		return ""
+	}
+	return id
+}
+
+// outlineDef returns the id of a <path> element
+// with the outline of the glyph, writing it if needed,
+// or an empty string for empty glyphs.
+func (ex *exporter) outlineDef(face *font.Face, gid font.GID, outline font.GlyphOutline) string {
+	key := glyphKey{face, gid}
+	if id, ok := ex.outlines[key]; ok {
+		return id
+	}
+	var id string
+	if len(outline.Segments) != 0 {
+		id = fmt.Sprintf("o%d-%d", ex.faceIndex(face), gid)
+		fmt.Fprintf(&ex.defs, `<path id="%s" d="%s"/>`+"\n", id, pathData(outline.Segments))
+	}
+	ex.outlines[key] = id
+	return id
+}
+
+func (ex *exporter) writeBitmapDef(face *font.Face, gid font.GID, data font.GlyphBitmap, id string) bool {
+	extents, ok := face.GlyphExtents(gid)
+	if !ok || extents.Width == 0 || extents.Height == 0 {
+		return false
+	}
+
+	var mimeType string
+	content := data.Data
+	switch data.Format {
+	case font.PNG:
+		mimeType = "image/png"
+	case font.JPG:
+		mimeType = "image/jpeg"
+	case font.TIFF:
+		mimeType = "image/tiff"
+	case font.BlackAndWhite, font.BlackAndWhiteByteAligned:
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, monochromeImage(data)); err != nil {
+			return false
+		}
+		mimeType, content = "image/png", buf.Bytes()
+	default:
+		return false
+	}
+
+	// the image uses a Y axis pointing down
+	fmt.Fprintf(&ex.defs, `<g id="%s" transform="scale(1 -1)"><image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" href="data:%s;base64,%s"/></g>`+"\n",
+		id, formatFloat(extents.XBearing), formatFloat(-extents.YBearing), formatFloat(extents.Width), formatFloat(-extents.Height),
+		mimeType, base64.StdEncoding.EncodeToString(content))
+	return true
+}
+
+// monochromeImage converts a black and white bitmap,
+// using transparent pixels for the background.
+func monochromeImage(data font.GlyphBitmap) *image.Alpha {
+	img := image.NewAlpha(image.Rect(0, 0, data.Width, data.Height))
+	rowLength := data.Width // in bits
+	if data.Format == font.BlackAndWhiteByteAligned {
+		rowLength = (data.Width + 7) / 8 * 8
+	}
+	for y := 0; y < data.Height; y++ {
+		for x := 0; x < data.Width; x++ {
+			bit := y*rowLength + x
+			if bit/8 >= len(data.Data) {
+				return img
+			}
+			if data.Data[bit/8]&(0x80>>(bit%8)) != 0 {
+				img.Pix[y*img.Stride+x] = 0xFF
+			}
+		}
+	}
+	return img
+}
+
+var (
+	// the XML declaration and doctype are not valid
+	// in an embedded document
+	reSVGProlog = regexp.MustCompile(`(?s)^\s*(<\?xml.*?\?>)?\s*(<!DOCTYPE[^>]*>)?`)
+	reSVGID     = regexp.MustCompile(`(\s)id=("|')`)
+	reSVGHref   = regexp.MustCompile(`href=("|')#`)
+	reSVGURL    = regexp.MustCompile(`url\(\s*#`)
+)
+
+// writeSVGDef embeds the SVG document containing the glyph, if not already done,
+// and writes a definition referencing the glyph element.
+//
+// To avoid collisions, the identifiers of the document elements are prefixed.
+// Note that only the glyph element is displayed, so that transforms applied on its
+// ancestors in the original document are ignored.
+func (ex *exporter) writeSVGDef(face *font.Face, gid font.GID, data font.GlyphSVG, id string) {
+	key := svgSourceKey{face, string(data.Source)}
+	prefix, ok := ex.svgSources[key]
+	if !ok {
+		prefix = ex.newID("s") + "-"
+		ex.svgSources[key] = prefix
+
+		source := reSVGProlog.ReplaceAll(data.Source, nil)
+		source = reSVGID.ReplaceAll(source, []byte("${1}id=${2}"+prefix))
+		source = reSVGHref.ReplaceAll(source, []byte("href=${1}#"+prefix))
+		source = reSVGURL.ReplaceAll(source, []byte("url(#"+prefix))
+		ex.defs.Write(source)
+		ex.defs.WriteByte('\n')
+	}
+
+	// SVG glyphs use a Y axis pointing down
+	fmt.Fprintf(&ex.defs, `<g id="%s" transform="scale(1 -1)"><use href="#%sglyph%d"/></g>`+"\n", id, prefix, gid)
+}
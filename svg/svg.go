@@ -0,0 +1,334 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package svg exports shaped text as standalone SVG documents.
+//
+// Glyphs are written once per (face, glyph) pair in the <defs> section of the document,
+// and referenced with <use> elements, so that the output stays compact for long texts.
+// Plain outlines are written as <path>, COLR glyphs are translated to SVG paints (including
+// gradients), bitmap glyphs are embedded as <image> elements and SVG glyphs (from the 'SVG ' table)
+// are inlined.
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// Options provides settings for the SVG output.
+type Options struct {
+	// Foreground is the color used to fill regular glyphs,
+	// and the parts of color glyphs using the text color.
+	// It defaults to opaque black.
+	Foreground color.Color
+
+	// Background, if not nil, is used to fill the whole document.
+	Background color.Color
+
+	// Palette is the index of the CPAL palette used for color glyphs.
+	// An invalid index is replaced by the default palette (0).
+	Palette int
+}
+
+// WriteRuns writes an SVG document displaying [runs] as a single line.
+// See [WriteLines] for more details.
+func WriteRuns(w io.Writer, runs []shaping.Output, opts Options) error {
+	return WriteLines(w, []shaping.Line{runs}, opts)
+}
+
+// WriteLines writes an SVG document displaying [lines], as returned
+// by [shaping.LineWrapper.WrapParagraph].
+//
+// Horizontal lines are stacked from top to bottom, vertical lines
+// from right to left. In each line, the runs are drawn according to their [shaping.Output.VisualIndex].
+// The document size is adjusted to fit the lines, using the font metrics.
+//
+// Sizes in the document are expressed in the unit used by the [shaping.Input.Size] field
+// (typically pixels).
+func WriteLines(w io.Writer, lines []shaping.Line, opts Options) error {
+	ex := newExporter(opts)
+
+	var width, height float32
+	layouts := make([]lineLayout, len(lines))
+	for i, line := range lines {
+		layouts[i] = newLineLayout(line)
+	}
+	for _, layout := range layouts {
+		if layout.vertical {
+			width += layout.thickness()
+			height = maxF(height, layout.advance)
+		} else {
+			width = maxF(width, layout.advance)
+			height += layout.thickness()
+		}
+	}
+
+	var pos float32 // top of the line (horizontal), or right of the column (vertical)
+	if len(layouts) != 0 && layouts[0].vertical {
+		pos = width
+	}
+	for i, layout := range layouts {
+		if layout.vertical {
+			baseline := pos - layout.gap - layout.ascent
+			ex.drawLine(lines[i], baseline)
+			pos = baseline + layout.descent
+		} else {
+			baseline := pos + layout.ascent
+			ex.drawLine(lines[i], baseline)
+			pos = baseline - layout.descent + layout.gap
+		}
+	}
+
+	return ex.writeDocument(w, width, height)
+}
+
+// lineLayout stores the dimensions of one line,
+// merged from its runs.
+type lineLayout struct {
+	ascent, descent, gap float32
+	advance              float32 // always positive
+	vertical             bool
+}
+
+func newLineLayout(line shaping.Line) lineLayout {
+	var out lineLayout
+	for _, run := range line {
+		out.vertical = run.Direction.IsVertical()
+		out.ascent = maxF(out.ascent, toFloat(run.LineBounds.Ascent))
+		out.descent = minF(out.descent, toFloat(run.LineBounds.Descent))
+		out.gap = maxF(out.gap, toFloat(run.LineBounds.Gap))
+		out.advance += absF(toFloat(run.Advance))
+	}
+	return out
+}
+
+func (l lineLayout) thickness() float32 { return l.ascent - l.descent + l.gap }
+
+// exporter accumulates the content of the document,
+// so that the glyphs definitions may be written before the body.
+type exporter struct {
+	opts Options
+
+	faces      map[*font.Face]int
+	glyphs     map[glyphKey]string // id of the definition, or "" for empty glyphs
+	outlines   map[glyphKey]string // id of the plain outlines, or "" for empty glyphs
+	svgSources map[svgSourceKey]string
+	clips      map[string]struct{} // outline ids already used as clip path
+	idCounter  int
+
+	defs, body bytes.Buffer
+}
+
+type glyphKey struct {
+	face *font.Face
+	gid  font.GID
+}
+
+type svgSourceKey struct {
+	face   *font.Face
+	source string
+}
+
+func newExporter(opts Options) *exporter {
+	if opts.Foreground == nil {
+		opts.Foreground = color.Black
+	}
+	return &exporter{
+		opts:       opts,
+		faces:      make(map[*font.Face]int),
+		glyphs:     make(map[glyphKey]string),
+		outlines:   make(map[glyphKey]string),
+		svgSources: make(map[svgSourceKey]string),
+		clips:      make(map[string]struct{}),
+	}
+}
+
+// newID returns a unique identifier with the given prefix.
+func (ex *exporter) newID(prefix string) string {
+	ex.idCounter++
+	return prefix + strconv.Itoa(ex.idCounter)
+}
+
+func (ex *exporter) faceIndex(face *font.Face) int {
+	index, ok := ex.faces[face]
+	if !ok {
+		index = len(ex.faces)
+		ex.faces[face] = index
+	}
+	return index
+}
+
+// drawLine writes the glyphs of [line] into the body,
+// using [baseline] as y coordinate for horizontal text and
+// x coordinate for vertical text.
+func (ex *exporter) drawLine(line shaping.Line, baseline float32) {
+	// sort by visual order, without mutating the input
+	runs := append(shaping.Line(nil), line...)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].VisualIndex < runs[j].VisualIndex })
+
+	// the opacity is not inherited by the color glyphs,
+	// which use their own fill attributes
+	var fgOpacity string
+	if _, alpha := formatColor(ex.opts.Foreground); alpha != 1 {
+		fgOpacity = fmt.Sprintf(` fill-opacity="%s"`, formatFloat(alpha))
+	}
+
+	var dot float32
+	for _, run := range runs {
+		if run.Face == nil || run.Size == 0 {
+			continue
+		}
+		isVertical := run.Direction.IsVertical()
+		scale := toFloat(run.Size) / float32(run.Face.Upem())
+		for _, g := range run.Glyphs {
+			// the current position, in SVG coordinates
+			var x, y float32
+			if isVertical {
+				x, y = baseline, dot
+				dot -= toFloat(g.Advance)
+			} else {
+				x, y = dot, baseline
+				dot += toFloat(g.Advance)
+			}
+
+			id := ex.glyphDef(run.Face, g.GlyphID)
+			if id == "" {
+				continue
+			}
+			// glyph definitions use font units, with Y axis pointing up
+			matrix := [6]float32{scale, 0, 0, -scale, x + toFloat(g.XOffset), y - toFloat(g.YOffset)}
+			if run.Direction.IsSideways() {
+				// as in [font.GlyphOutline.Sideways], the glyph is rotated 90° clockwise
+				// and lifted up by -YOffset, before being placed as upright glyphs are
+				matrix = sidewaysMatrix(matrix, -toFloat(g.YOffset)/scale)
+			}
+			var opacity string
+			if id == ex.outlines[glyphKey{run.Face, g.GlyphID}] {
+				opacity = fgOpacity
+			}
+			fmt.Fprintf(&ex.body, `<use href="#%s" transform="%s"%s/>`+"\n", id, formatMatrix(matrix), opacity)
+		}
+	}
+}
+
+func (ex *exporter) writeDocument(w io.Writer, width, height float32) error {
+	var header bytes.Buffer
+	fmt.Fprintf(&header, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		formatFloat(width), formatFloat(height), formatFloat(width), formatFloat(height))
+	if ex.opts.Background != nil {
+		fill, alpha := formatColor(ex.opts.Background)
+		fmt.Fprintf(&header, `<rect width="100%%" height="100%%" fill="%s" fill-opacity="%s"/>`+"\n", fill, formatFloat(alpha))
+	}
+	if ex.defs.Len() != 0 {
+		header.WriteString("<defs>\n")
+	}
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	if ex.defs.Len() != 0 {
+		if _, err := w.Write(ex.defs.Bytes()); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "</defs>\n"); err != nil {
+			return err
+		}
+	}
+
+	fill, _ := formatColor(ex.opts.Foreground)
+	if _, err := fmt.Fprintf(w, `<g fill="%s" color="%s">`+"\n", fill, fill); err != nil {
+		return err
+	}
+	if _, err := w.Write(ex.body.Bytes()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "</g>\n</svg>\n")
+	return err
+}
+
+// ------------------------------- formatting helpers -------------------------------
+
+func toFloat(v fixed.Int26_6) float32 { return float32(v) / 64 }
+
+func formatFloat(v float32) string {
+	// limit the precision to keep the output compact
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+func formatMatrix(m [6]float32) string {
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)", formatFloat(m[0]), formatFloat(m[1]),
+		formatFloat(m[2]), formatFloat(m[3]), formatFloat(m[4]), formatFloat(m[5]))
+}
+
+// sidewaysMatrix returns the scaling and translation [m] applied after the rotation
+// done by [font.GlyphOutline.Sideways] with [yOffset], in font units.
+func sidewaysMatrix(m [6]float32, yOffset float32) [6]float32 {
+	// the rotation maps (x, y) to (y, -x + yOffset)
+	return [6]float32{0, -m[3], m[0], 0, m[4], m[3]*yOffset + m[5]}
+}
+
+// formatColor returns the SVG color and its opacity, in [0;1].
+func formatColor(c color.Color) (string, float32) {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B), float32(nrgba.A) / 0xFF
+}
+
+// pathData returns the SVG path description of the given segments.
+func pathData(segments []font.Segment) string {
+	var out bytes.Buffer
+	for i, seg := range segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo:
+			if i != 0 {
+				out.WriteString("Z")
+			}
+			out.WriteString("M")
+		case ot.SegmentOpLineTo:
+			out.WriteString("L")
+		case ot.SegmentOpQuadTo:
+			out.WriteString("Q")
+		case ot.SegmentOpCubeTo:
+			out.WriteString("C")
+		}
+		for j, pt := range seg.ArgsSlice() {
+			if j != 0 {
+				out.WriteByte(' ')
+			}
+			out.WriteString(formatFloat(pt.X))
+			out.WriteByte(' ')
+			out.WriteString(formatFloat(pt.Y))
+		}
+	}
+	if len(segments) != 0 {
+		out.WriteString("Z")
+	}
+	return out.String()
+}
+
+func minF(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxF(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absF(a float32) float32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+
+	td "github.com/go-text/typesetting-utils/opentype"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func loadFace(t testing.TB, filename string) *font.Face {
+	t.Helper()
+	file, err := td.Files.ReadFile(filename)
+	tu.AssertNoErr(t, err)
+	face, err := font.ParseTTF(bytes.NewReader(file))
+	tu.AssertNoErr(t, err)
+	return face
+}
+
+func shape(face *font.Face, text string, dir di.Direction) shaping.Output {
+	runes := []rune(text)
+	return (&shaping.HarfbuzzShaper{}).Shape(shaping.Input{
+		Text:      runes,
+		RunEnd:    len(runes),
+		Direction: dir,
+		Face:      face,
+		Size:      fixed.I(32),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("en"),
+	})
+}
+
+// glyphsOutput returns an output displaying the given glyphs
+func glyphsOutput(face *font.Face, gids ...font.GID) shaping.Output {
+	out := shaping.Output{Face: face, Size: fixed.I(32), Direction: di.DirectionLTR}
+	for _, gid := range gids {
+		out.Glyphs = append(out.Glyphs, shaping.Glyph{GlyphID: gid, Advance: fixed.I(32)})
+	}
+	out.RecalculateAll()
+	return out
+}
+
+// assertWellFormed checks the document is valid XML
+func assertWellFormed(t *testing.T, doc []byte) {
+	t.Helper()
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		tu.AssertNoErr(t, err)
+	}
+}
+
+func TestWriteOutlines(t *testing.T) {
+	face := loadFace(t, "common/Roboto-BoldItalic.ttf")
+	run := shape(face, "Hello world", di.DirectionLTR)
+
+	var buf bytes.Buffer
+	err := WriteRuns(&buf, []shaping.Output{run}, Options{Foreground: color.NRGBA{R: 0xFF, A: 0x80}})
+	tu.AssertNoErr(t, err)
+	doc := buf.Bytes()
+	assertWellFormed(t, doc)
+
+	// 'l' and 'o' are defined only once
+	tu.Assert(t, bytes.Count(doc, []byte("<path ")) == 7) // H e l o w r d
+	tu.Assert(t, bytes.Count(doc, []byte("<use ")) == 10) // space has no outline
+	tu.Assert(t, bytes.Contains(doc, []byte(`fill="#ff0000"`)))
+	tu.Assert(t, bytes.Contains(doc, []byte(`fill-opacity="0.5019608"`)))
+}
+
+func TestWriteLines(t *testing.T) {
+	face := loadFace(t, "common/Roboto-BoldItalic.ttf")
+	line1 := shape(face, "abc", di.DirectionLTR)
+	line2 := shape(face, "abc", di.DirectionLTR)
+
+	var buf bytes.Buffer
+	err := WriteLines(&buf, []shaping.Line{{line1}, {line2}}, Options{Background: color.White})
+	tu.AssertNoErr(t, err)
+	doc := buf.Bytes()
+	assertWellFormed(t, doc)
+	tu.Assert(t, bytes.Count(doc, []byte("<path ")) == 3)
+	tu.Assert(t, bytes.Count(doc, []byte("<use ")) == 6)
+	tu.Assert(t, bytes.Contains(doc, []byte(`<rect width="100%" height="100%" fill="#ffffff"`)))
+}
+
+func TestWriteVertical(t *testing.T) {
+	face := loadFace(t, "common/NotoSansCJKjp-VF.otf")
+	upright := shape(face, "漢字", di.DirectionTTB)
+
+	sidewaysDir := di.DirectionTTB
+	sidewaysDir.SetSideways(true)
+	sideways := shape(face, "abc", sidewaysDir)
+
+	var buf bytes.Buffer
+	err := WriteLines(&buf, []shaping.Line{{upright}, {sideways}}, Options{})
+	tu.AssertNoErr(t, err)
+	doc := buf.Bytes()
+	assertWellFormed(t, doc)
+	tu.Assert(t, bytes.Count(doc, []byte("<use ")) == 5)
+	// rotated glyphs
+	tu.Assert(t, bytes.Count(doc, []byte(`transform="matrix(0 `)) == 3)
+	tu.Assert(t, !bytes.Contains(doc, []byte("xmlns:xlink")))
+}
+
+func TestSidewaysMatrix(t *testing.T) {
+	apply := func(m [6]float32, p font.SegmentPoint) font.SegmentPoint {
+		return font.SegmentPoint{X: m[0]*p.X + m[2]*p.Y + m[4], Y: m[1]*p.X + m[3]*p.Y + m[5]}
+	}
+	upright := [6]float32{0.5, 0, 0, -0.5, 10, 20}
+	outline := font.GlyphOutline{Segments: []font.Segment{{Op: ot.SegmentOpLineTo, Args: [3]font.SegmentPoint{{X: 100, Y: 700}, {X: -30, Y: 4}}}}}
+	points := outline.Segments[0].Args
+
+	// the matrix matches the rotated outline, placed as upright glyphs are
+	outline.Sideways(-60)
+	m := sidewaysMatrix(upright, -60)
+	for i, p := range points[:2] {
+		tu.Assert(t, apply(m, p) == apply(upright, outline.Segments[0].Args[i]))
+	}
+}
+
+func TestWriteColorGlyphs(t *testing.T) {
+	for _, test := range []struct {
+		filename string
+		text     string
+		expected []string // markups expected in the output
+	}{
+		{"color/NotoColorEmoji-Regular.ttf", "😀🎉", []string{"<linearGradient ", "<radialGradient ", "<clipPath "}}, // COLR v1
+		{"color/CoralPixels-Regular.ttf", "A", []string{"<path ", "fill=\"#"}},                                     // COLR v0
+		{"bitmap/NotoColorEmoji.ttf", "😀", []string{"<image ", "data:image/png;base64,"}},                          // CBDT
+	} {
+		face := loadFace(t, test.filename)
+		run := shape(face, test.text, di.DirectionLTR)
+
+		var buf bytes.Buffer
+		err := WriteRuns(&buf, []shaping.Output{run}, Options{})
+		tu.AssertNoErr(t, err)
+		doc := buf.String()
+		assertWellFormed(t, buf.Bytes())
+		for _, exp := range test.expected {
+			tu.AssertC(t, strings.Contains(doc, exp), test.filename+": "+exp)
+		}
+	}
+}
+
+func TestWriteSVGGlyphs(t *testing.T) {
+	face := loadFace(t, "toys/chromacheck-svg.ttf")
+	var gids []font.GID
+	for gid := font.GID(0); gid < 100; gid++ {
+		if _, ok := face.GlyphData(gid).(font.GlyphSVG); ok {
+			gids = append(gids, gid, gid) // repeat to check deduplication
+		}
+	}
+	tu.Assert(t, len(gids) != 0)
+
+	var buf bytes.Buffer
+	err := WriteRuns(&buf, []shaping.Output{glyphsOutput(face, gids...)}, Options{})
+	tu.AssertNoErr(t, err)
+	doc := buf.String()
+	assertWellFormed(t, buf.Bytes())
+	tu.Assert(t, strings.Count(doc, `transform="scale(1 -1)"><use href="#s1-glyph`) == len(gids)/2)
+	tu.Assert(t, !strings.Contains(doc, "<?xml"))
+}
+
+func TestMonochromeImage(t *testing.T) {
+	data := font.GlyphBitmap{
+		Width: 3, Height: 2, Format: font.BlackAndWhiteByteAligned,
+		Data: []byte{0b10100000, 0b01000000},
+	}
+	img := monochromeImage(data)
+	tu.Assert(t, bytes.Equal(img.Pix, []byte{0xFF, 0, 0xFF, 0, 0xFF, 0}))
+
+	data.Format = font.BlackAndWhite
+	data.Data = []byte{0b10101000}
+	img = monochromeImage(data)
+	tu.Assert(t, bytes.Equal(img.Pix, []byte{0xFF, 0, 0xFF, 0, 0xFF, 0}))
+}